@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/clicks"
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/url"
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	keyStrategyRandom  = "random"
+	keyStrategyCounter = "counter"
+	keyStrategyHash    = "hash"
+)
+
+// config holds the application settings that can be overridden through the environment
+type config struct {
+	// keyStrategy selects the key generator used for tiny urls: random, counter or hash
+	keyStrategy string
+	urlService  types.URLServiceConfig
+	handler     types.HandlerConfig
+	clicks      types.ClickPipelineConfig
+	clickCount  types.ClickCounterConfig
+	// adminKey is an optional admin API key used to create the first API keys
+	adminKey string
+}
+
+func loadConfig() (config, error) {
+	cfg := config{
+		keyStrategy: getEnv("TINY_URL_KEY_STRATEGY", keyStrategyRandom),
+		urlService:  url.DefaultConfig(),
+		clicks:      clicks.DefaultPipelineConfig(),
+		clickCount:  clicks.DefaultCounterConfig(),
+	}
+	var err error
+	if cfg.urlService.MinExpiry, err = getDurationEnv("TINY_URL_MIN_EXPIRY", cfg.urlService.MinExpiry); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.MaxExpiry, err = getDurationEnv("TINY_URL_MAX_EXPIRY", cfg.urlService.MaxExpiry); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.MinExpiry > cfg.urlService.MaxExpiry {
+		return config{}, fmt.Errorf("min expiry %s is greater than max expiry %s",
+			cfg.urlService.MinExpiry, cfg.urlService.MaxExpiry)
+	}
+	if cfg.urlService.MaxBatchSize, err = getIntEnv("TINY_URL_MAX_BATCH_SIZE", cfg.urlService.MaxBatchSize); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.Dedupe, err = getBoolEnv("TINY_URL_DEDUPE", cfg.urlService.Dedupe); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.PasswordAttempts, err = getIntEnv("TINY_URL_PASSWORD_ATTEMPTS",
+		cfg.urlService.PasswordAttempts); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.PasswordAttemptWindow, err = getDurationEnv("TINY_URL_PASSWORD_ATTEMPT_WINDOW",
+		cfg.urlService.PasswordAttemptWindow); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.TrashRetention, err = getDurationEnv("TINY_URL_TRASH_RETENTION",
+		cfg.urlService.TrashRetention); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.PurgeInterval, err = getDurationEnv("TINY_URL_PURGE_INTERVAL",
+		cfg.urlService.PurgeInterval); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.TrashRetention <= 0 || cfg.urlService.PurgeInterval <= 0 {
+		return config{}, fmt.Errorf("trash retention %s and purge interval %s must be positive",
+			cfg.urlService.TrashRetention, cfg.urlService.PurgeInterval)
+	}
+	if cfg.urlService.ExpiredRetention, err = getDurationEnv("TINY_URL_EXPIRED_RETENTION",
+		cfg.urlService.ExpiredRetention); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.ExpiredRetention < 0 {
+		return config{}, fmt.Errorf("expired retention %s must not be negative", cfg.urlService.ExpiredRetention)
+	}
+	redirect := &cfg.urlService.Redirect
+	if redirect.Status, err = getIntEnv("TINY_URL_REDIRECT_STATUS", redirect.Status); err != nil {
+		return config{}, err
+	}
+	redirect.CacheControl = getEnv("TINY_URL_CACHE_CONTROL", redirect.CacheControl)
+	redirect.ReferrerPolicy = getEnv("TINY_URL_REFERRER_POLICY", redirect.ReferrerPolicy)
+	if err = url.ValidateRedirect(*redirect); err != nil {
+		return config{}, err
+	}
+	cfg.urlService.TrustedDomains = getListEnv("TINY_URL_TRUSTED_DOMAINS")
+	if err = url.ValidateTrustedDomains(cfg.urlService.TrustedDomains); err != nil {
+		return config{}, err
+	}
+	if cfg.clicks, err = getClickPipelineConfig(cfg.clicks); err != nil {
+		return config{}, err
+	}
+	if cfg.clickCount.FlushInterval, err = getDurationEnv("TINY_URL_CLICK_COUNT_FLUSH_INTERVAL",
+		cfg.clickCount.FlushInterval); err != nil {
+		return config{}, err
+	}
+	if cfg.clickCount.FlushInterval <= 0 {
+		return config{}, fmt.Errorf("click count flush interval %s must be positive", cfg.clickCount.FlushInterval)
+	}
+	cfg.adminKey = getEnv("TINY_URL_ADMIN_KEY", "")
+	cfg.handler.NotYetActiveURL = getEnv("TINY_URL_NOT_YET_ACTIVE_URL", "")
+	if path := getEnv("TINY_URL_HOLDING_PAGE", ""); path != "" {
+		page, err := os.ReadFile(path)
+		if err != nil {
+			return config{}, fmt.Errorf("failed to read holding page: %w", err)
+		}
+		cfg.handler.HoldingPage = string(page)
+	}
+	cfg.handler.CountryHeader = getEnv("TINY_URL_COUNTRY_HEADER", "")
+	cfg.handler.IPHashKey = getEnv("TINY_URL_IP_HASH_KEY", "")
+	if cfg.handler.Tenants, err = getTenantsEnv("TINY_URL_TENANTS"); err != nil {
+		return config{}, err
+	}
+	cfg.urlService.FallbackURL = getEnv("TINY_URL_FALLBACK_URL", "")
+	if err = url.ValidateFallbackURL(cfg.urlService.FallbackURL); err != nil {
+		return config{}, err
+	}
+	if cfg.urlService.TenantFallbackURLs, err = getTenantFallbacksEnv("TINY_URL_TENANT_FALLBACK_URLS",
+		cfg.handler.Tenants); err != nil {
+		return config{}, err
+	}
+	return cfg, nil
+}
+
+// getClickPipelineConfig overrides the settings of the click event pipeline from the environment. They must all be
+// positive.
+func getClickPipelineConfig(cfg types.ClickPipelineConfig) (types.ClickPipelineConfig, error) {
+	var err error
+	if cfg.QueueSize, err = getIntEnv("TINY_URL_CLICK_QUEUE_SIZE", cfg.QueueSize); err != nil {
+		return types.ClickPipelineConfig{}, err
+	}
+	if cfg.Workers, err = getIntEnv("TINY_URL_CLICK_WORKERS", cfg.Workers); err != nil {
+		return types.ClickPipelineConfig{}, err
+	}
+	if cfg.BatchSize, err = getIntEnv("TINY_URL_CLICK_BATCH_SIZE", cfg.BatchSize); err != nil {
+		return types.ClickPipelineConfig{}, err
+	}
+	if cfg.FlushInterval, err = getDurationEnv("TINY_URL_CLICK_FLUSH_INTERVAL", cfg.FlushInterval); err != nil {
+		return types.ClickPipelineConfig{}, err
+	}
+	if cfg.QueueSize <= 0 || cfg.Workers <= 0 || cfg.BatchSize <= 0 || cfg.FlushInterval <= 0 {
+		return types.ClickPipelineConfig{}, fmt.Errorf("click queue size %d, workers %d, batch size %d and flush "+
+			"interval %s must be positive", cfg.QueueSize, cfg.Workers, cfg.BatchSize, cfg.FlushInterval)
+	}
+	return cfg, nil
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getIntEnv(key string, fallback int) (int, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return fallback, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer for %s: %w", key, err)
+	}
+	return i, nil
+}
+
+func getBoolEnv(key string, fallback bool) (bool, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean for %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// getListEnv parses a comma separated list of lower cased values, skipping empty ones
+func getListEnv(key string) []string {
+	var values []string
+	for _, v := range strings.Split(getEnv(key, ""), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getTenantsEnv parses a comma separated list of tenants given as id=domain. Ids and domains must be unique.
+func getTenantsEnv(key string) ([]types.Tenant, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return nil, nil
+	}
+	tenants := make([]types.Tenant, 0)
+	ids, domains := make(map[string]struct{}), make(map[string]struct{})
+	for _, entry := range strings.Split(v, ",") {
+		id, domain, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tenant %q for %s, expected id=domain", entry, key)
+		}
+		t := types.Tenant{ID: strings.TrimSpace(id), Domain: strings.ToLower(strings.TrimSpace(domain))}
+		if err := url.ValidateTenant(t); err != nil {
+			return nil, fmt.Errorf("invalid tenant for %s: %w", key, err)
+		}
+		if _, ok = ids[t.ID]; ok {
+			return nil, fmt.Errorf("duplicate tenant id %q for %s", t.ID, key)
+		}
+		if _, ok = domains[t.Domain]; ok {
+			return nil, fmt.Errorf("duplicate tenant domain %q for %s", t.Domain, key)
+		}
+		ids[t.ID], domains[t.Domain] = struct{}{}, struct{}{}
+		tenants = append(tenants, t)
+	}
+	return tenants, nil
+}
+
+// getTenantFallbacksEnv parses a comma separated list of default fallback urls given as id=url for the tenants
+func getTenantFallbacksEnv(key string, tenants []types.Tenant) (map[string]string, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return nil, nil
+	}
+	ids := make(map[string]struct{}, len(tenants))
+	for _, t := range tenants {
+		ids[t.ID] = struct{}{}
+	}
+	fallbacks := make(map[string]string)
+	for _, entry := range strings.Split(v, ",") {
+		id, fallback, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid fallback url %q for %s, expected id=url", entry, key)
+		}
+		id, fallback = strings.TrimSpace(id), strings.TrimSpace(fallback)
+		if _, ok = ids[id]; !ok {
+			return nil, fmt.Errorf("unknown tenant id %q for %s", id, key)
+		}
+		if err := url.ValidateFallbackURL(fallback); err != nil {
+			return nil, fmt.Errorf("invalid fallback url for %s: %w", key, err)
+		}
+		fallbacks[id] = fallback
+	}
+	return fallbacks, nil
+}
+
+func getDurationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	v := getEnv(key, "")
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for %s: %w", key, err)
+	}
+	return d, nil
+}
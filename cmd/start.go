@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,7 +17,9 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/vaishakdinesh/tiny-url-svc/pkg/apis/rest_v0"
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/auth"
 	"github.com/vaishakdinesh/tiny-url-svc/pkg/cache"
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/clicks"
 	"github.com/vaishakdinesh/tiny-url-svc/pkg/db"
 	"github.com/vaishakdinesh/tiny-url-svc/pkg/url"
 	"github.com/vaishakdinesh/tiny-url-svc/types"
@@ -33,6 +36,10 @@ func Run() {
 	if err != nil {
 		os.Exit(1)
 	}
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.Fatal("failed to load config", zap.Error(err))
+	}
 
 	dbClient, err := initDatastore(ctx, logger)
 	if err != nil {
@@ -61,7 +68,7 @@ func Run() {
 		logger.Fatal("failed to create a new server", zap.Error(err))
 	}
 
-	apis, err := initHandlers(logger, dbClient, redisClient)
+	apis, workers, err := initHandlers(ctx, cfg, logger, dbClient, redisClient)
 	if err != nil {
 		logger.Fatal("failed to init rest handlers", zap.Error(err))
 	}
@@ -72,6 +79,10 @@ func Run() {
 	wg := new(sync.WaitGroup)
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
 	defer cancel()
+	for _, w := range workers {
+		wg.Add(1)
+		go w.Run(ctx, wg)
+	}
 	wg.Add(1)
 	server.Run(ctx, wg)
 	wg.Wait()
@@ -88,17 +99,54 @@ func newServer() (*types.Server, error) {
 	return s, nil
 }
 
-func initHandlers(l *zap.Logger, c *mongo.Client, r *redis.Client) ([]types.Registerer, error) {
+func initHandlers(ctx context.Context, cfg config, l *zap.Logger, c *mongo.Client,
+	r *redis.Client) ([]types.Registerer, []types.Worker, error) {
 	cacheSvc := cache.NewCacheService(r)
-	urlSvc := url.NewTinyURLService(l, db.NewURLRepo(c), cacheSvc)
+	urlRepo, err := db.NewURLRepo(ctx, c, cfg.urlService.ExpiredRetention)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyGen, err := newKeyGenerator(cfg, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	clickCounter := clicks.NewCounter(l, urlRepo, cacheSvc, cfg.clickCount)
+	urlSvc := url.NewTinyURLService(l, urlRepo, cacheSvc, keyGen, clickCounter, cfg.urlService)
 	if err := urlSvc.RegisterProm(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	tinyURLV0, err := rest_v0.NewHandler(l, urlSvc)
+	keyRepo, err := db.NewAPIKeyRepo(ctx, c)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	keySvc := auth.NewKeyService(l, keyRepo, cfg.adminKey)
+	clickRepo, err := db.NewClickRepo(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	clickPipeline := clicks.NewPipeline(l, clickRepo, cfg.clicks)
+	if err := clickPipeline.RegisterProm(); err != nil {
+		return nil, nil, err
+	}
+	tinyURLV0, err := rest_v0.NewHandler(l, urlSvc, keySvc, clickPipeline, cfg.handler)
+	if err != nil {
+		return nil, nil, err
+	}
+	purger := url.NewPurger(l, urlRepo, cfg.urlService)
+	return []types.Registerer{tinyURLV0}, []types.Worker{purger, clickPipeline, clickCounter}, nil
+}
+
+func newKeyGenerator(cfg config, c *mongo.Client) (types.KeyGenerator, error) {
+	switch cfg.keyStrategy {
+	case keyStrategyRandom:
+		return url.NewRandomKeyGenerator(), nil
+	case keyStrategyCounter:
+		return url.NewCounterKeyGenerator(db.NewSequenceRepo(c), 0), nil
+	case keyStrategyHash:
+		return url.NewHashKeyGenerator(), nil
+	default:
+		return nil, fmt.Errorf("unknown key strategy %q", cfg.keyStrategy)
 	}
-	return []types.Registerer{tinyURLV0}, nil
 }
 
 func initDatastore(ctx context.Context, logger *zap.Logger) (*mongo.Client, error) {
@@ -1,11 +1,21 @@
 package rest_v0
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"html/template"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -18,10 +28,23 @@ var apiURL = "/tinyurlsvc"
 type handler struct {
 	schema types.OpenAPISchema
 	svc    types.URLService
+	keys   types.KeyService
+	clicks types.ClickRecorder
 	l      *zap.Logger
+	// notYetActiveURL and holdingPage answer visits of tiny urls that are not active yet
+	notYetActiveURL string
+	holdingPage     *template.Template
+	// tenants maps the domains of the tenants to their ids, and domains the ids of the tenants to their domains
+	tenants map[string]string
+	domains map[string]string
+	// countryHeader carries the country of the client for targeting rules
+	countryHeader string
+	// ipHashKey keys the hashes of the IPs of clients
+	ipHashKey []byte
 }
 
-func NewHandler(logger *zap.Logger, s types.URLService) (types.Handler, error) {
+func NewHandler(logger *zap.Logger, s types.URLService, k types.KeyService, c types.ClickRecorder,
+	cfg types.HandlerConfig) (types.Handler, error) {
 	swagger, err := v0.GetSwagger()
 	if err != nil {
 		logger.Error("failed to get swagger", zap.Error(err))
@@ -32,17 +55,133 @@ func NewHandler(logger *zap.Logger, s types.URLService) (types.Handler, error) {
 		logger.Error("failed to load schema from openapi spec", zap.Error(err))
 		return nil, err
 	}
+	holdingPage, err := parseHoldingPage(cfg.HoldingPage)
+	if err != nil {
+		logger.Error("failed to parse holding page", zap.Error(err))
+		return nil, err
+	}
+	tenants := make(map[string]string, len(cfg.Tenants))
+	domains := make(map[string]string, len(cfg.Tenants))
+	for _, t := range cfg.Tenants {
+		domain := strings.TrimSuffix(strings.ToLower(t.Domain), ".")
+		tenants[domain] = t.ID
+		domains[t.ID] = domain
+	}
+	ipHashKey := []byte(cfg.IPHashKey)
+	if len(ipHashKey) == 0 {
+		logger.Warn("no ip hash key configured, using a random key so hashes of client IPs change on restart")
+		ipHashKey = make([]byte, 32)
+		if _, err = rand.Read(ipHashKey); err != nil {
+			logger.Error("failed to generate ip hash key", zap.Error(err))
+			return nil, err
+		}
+	}
 	return &handler{
-		l:      logger,
-		schema: schema,
-		svc:    s,
+		l:               logger,
+		schema:          schema,
+		svc:             s,
+		keys:            k,
+		clicks:          c,
+		notYetActiveURL: cfg.NotYetActiveURL,
+		holdingPage:     holdingPage,
+		tenants:         tenants,
+		domains:         domains,
+		countryHeader:   cfg.CountryHeader,
+		ipHashKey:       ipHashKey,
 	}, nil
 }
 
 func (h *handler) Register(s *types.Server) {
+	middleware := []echo.MiddlewareFunc{h.schema.AuthenticationMiddleware(h.keys), h.schema.ValidationMiddleware()}
 	sg := s.Group(apiURL)
-	sg.Use(h.schema.ValidationMiddleware())
+	sg.Use(h.resolveTenant)
+	sg.Use(middleware...)
 	v0.RegisterHandlers(sg, h)
+	w := &v0.ServerInterfaceWrapper{Handler: h}
+	// passthrough tiny urls take a trailing path, which the spec cannot describe, so the routes of visits outside the
+	// group go through its middleware as GetURL
+	asGetURL := routedAsGetURL(middleware...)
+	s.GET(apiURL+"/:urlKey/*", w.GetURL, h.resolveTenant, asGetURL)
+	if len(h.tenants) > 0 {
+		s.GET("/:urlKey", h.getTenantURL(w), h.resolveTenant, asGetURL)
+		s.GET("/:urlKey/*", h.getTenantURL(w), h.resolveTenant, asGetURL)
+	}
+}
+
+// routedAsGetURL runs the middleware on visits as if they were sent to the GetURL route of their key, so that they
+// are authenticated and validated against that operation. The handler gets the request with its own path.
+func routedAsGetURL(middleware ...echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			visit := ctx.Request()
+			routed := visit.Clone(visit.Context())
+			routed.URL.Path = apiURL + "/" + ctx.Param("urlKey")
+			routed.URL.RawPath = ""
+			handle := func(ctx echo.Context) error {
+				// keep the context the middleware added, such as the principal
+				ctx.SetRequest(visit.WithContext(ctx.Request().Context()))
+				return next(ctx)
+			}
+			for i := len(middleware) - 1; i >= 0; i-- {
+				handle = middleware[i](handle)
+			}
+			ctx.SetRequest(routed)
+			return handle(ctx)
+		}
+	}
+}
+
+// resolveTenant scopes the request to the tenant whose domain is the host of the request. Requests for other hosts
+// are left to the default tenant.
+func (h *handler) resolveTenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		r := ctx.Request()
+		if tenantID, ok := h.tenants[requestHost(r)]; ok {
+			ctx.SetRequest(r.WithContext(types.WithTenant(r.Context(), tenantID)))
+		}
+		return next(ctx)
+	}
+}
+
+// getTenantURL serves GetURL from the root of the domains of the tenants, which is where their tiny urls point. The
+// tiny urls of the default tenant are only served under apiURL.
+func (h *handler) getTenantURL(w *v0.ServerInterfaceWrapper) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if types.TenantFromContext(ctx.Request().Context()) == "" {
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NoRouteError,
+				Message: types.ErrNoPath.Error(),
+			})
+		}
+		return w.GetURL(ctx)
+	}
+}
+
+// trailingPath returns the unescaped path following the key of a visit, which is empty unless the visit is routed to
+// a passthrough route
+func trailingPath(ctx echo.Context) string {
+	path := ctx.Param("*")
+	// echo routes on the escaped path when it differs from the default escaping
+	if ctx.Request().URL.RawPath != "" {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+	}
+	return path
+}
+
+// tinyURL returns the tiny url of the document. Tiny urls of tenants point to the configured domain of their tenant.
+func (h *handler) tinyURL(ctx echo.Context, urlDoc types.URLDocument) string {
+	return urlDoc.ToURL(ctx, h.domains[urlDoc.Tenant])
+}
+
+// requestHost returns the lower cased host of the request without its port
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
 }
 
 // GenerateURL Generate a tiny url
@@ -56,47 +195,517 @@ func (h *handler) GenerateURL(ctx echo.Context) error {
 		})
 	}
 
-	tinyURL, err := h.svc.GenerateTinyURL(ctx.Request().Context(), genURLReq.Url, genURLReq.LiveForever)
+	tinyURL, existing, err := h.svc.GenerateTinyURL(ctx.Request().Context(),
+		toGenerateRequest(genURLReq, principal(ctx).KeyID))
+	if err != nil {
+		status, apiErr := generateError(err)
+		return ctx.JSON(status, apiErr)
+	}
+	response := &v0.GenerateURLResponse{GeneratedTinyURL: h.tinyURL(ctx, tinyURL)}
+	if !tinyURL.ExpireTime.IsZero() {
+		response.ExpireTime = timePtr(tinyURL.ExpireTime.UTC())
+	}
+	if existing {
+		return ctx.JSON(http.StatusOK, response)
+	}
+	return ctx.JSON(http.StatusCreated, response)
+}
+
+// GenerateURLBatch Generate tiny urls in a batch
+// (POST /tinyurlsvc/generate/batch)
+func (h *handler) GenerateURLBatch(ctx echo.Context) error {
+	batchReq := new(v0.GenerateURLBatchRequest)
+	if err := json.NewDecoder(ctx.Request().Body).Decode(batchReq); err != nil {
+		return ctx.JSON(http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		})
+	}
+	results := make([]v0.GenerateURLBatchResult, len(batchReq.Items))
+	owner := principal(ctx).KeyID
+	reqs := make([]types.GenerateRequest, 0, len(batchReq.Items))
+	// indexes maps the requests sent to the service to their index in the batch
+	indexes := make([]int, 0, len(batchReq.Items))
+	for i := range batchReq.Items {
+		results[i].Index = i
+		if err := validateLongURL(batchReq.Items[i].Url); err != nil {
+			results[i].Error = &v0.APIError{Code: types.InputError, Message: err.Error()}
+			continue
+		}
+		reqs = append(reqs, toGenerateRequest(&batchReq.Items[i], owner))
+		indexes = append(indexes, i)
+	}
+	generated, err := h.svc.GenerateTinyURLs(ctx.Request().Context(), reqs)
 	if err != nil {
+		if errors.Is(err, types.ErrBatchTooLarge) {
+			return ctx.JSON(http.StatusBadRequest, &types.APIError{
+				Code:    types.InputError,
+				Message: err.Error(),
+			})
+		}
 		return ctx.JSON(http.StatusInternalServerError, &types.APIError{
 			Code:    types.InternalServerError,
 			Message: err.Error(),
 		})
 	}
-	response := &v0.GenerateURLResponse{GeneratedTinyURL: tinyURL.ToURL(ctx)}
-	if !tinyURL.ExpireTime.IsZero() {
-		response.ExpireTime = stringPtr(tinyURL.ExpireTime.String())
+	for j, i := range indexes {
+		if generated[j].Err != nil {
+			_, apiErr := generateError(generated[j].Err)
+			results[i].Error = &v0.APIError{Code: apiErr.Code, Message: apiErr.Message}
+			continue
+		}
+		tinyURL := generated[j].Document
+		results[i].GeneratedTinyURL = stringPtr(h.tinyURL(ctx, tinyURL))
+		if !tinyURL.ExpireTime.IsZero() {
+			results[i].ExpireTime = timePtr(tinyURL.ExpireTime.UTC())
+		}
+		if generated[j].Existing {
+			results[i].Existing = boolPtr(true)
+		}
 	}
-	return ctx.JSON(http.StatusCreated, response)
+	return ctx.JSON(http.StatusOK, &v0.GenerateURLBatchResponse{Results: results})
+}
+
+// ListURLs Lists tiny urls
+// (GET /tinyurlsvc/urls)
+func (h *handler) ListURLs(ctx echo.Context, params v0.ListURLsParams) error {
+	page, err := h.svc.ListTinyURLs(ctx.Request().Context(), toListRequest(params), principal(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
+		case errors.Is(err, types.ErrInvalidCursor), errors.Is(err, types.ErrInvalidListLimit),
+			errors.Is(err, types.ErrInvalidInput):
+			return ctx.JSON(http.StatusBadRequest, &types.APIError{
+				Code:    types.InputError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	list := &v0.URLList{Items: make([]v0.URLInfo, len(page.Documents))}
+	for i, urlDoc := range page.Documents {
+		list.Items[i] = *h.toURLInfo(ctx, urlDoc)
+	}
+	if page.NextCursor != "" {
+		list.NextCursor = stringPtr(page.NextCursor)
+	}
+	return ctx.JSON(http.StatusOK, list)
 }
 
 // GetURL redirects to long url.
 // (GET /tinyurlsvc/{urlKey})
-func (h *handler) GetURL(ctx echo.Context, urlKey string) error {
-	urlDoc, err := h.svc.GetTinyURL(ctx.Request().Context(), urlKey)
+func (h *handler) GetURL(ctx echo.Context, urlKey string, params v0.GetURLParams) error {
+	var password string
+	if params.XLinkPassword != nil {
+		password = *params.XLinkPassword
+	}
+	visit := h.newVisit(ctx, password, trailingPath(ctx))
+	urlDoc, err := h.svc.GetTinyURL(ctx.Request().Context(), urlKey, visit)
+	if err != nil {
+		return h.getURLError(ctx, urlKey, visit.Path, err)
+	}
+	keepVisitor(ctx, urlDoc, visit)
+	urlDoc.LongURL = urlDoc.Destination(visit.Path, ctx.QueryParams())
+	h.recordClick(ctx, urlDoc)
+	status := urlDoc.RedirectStatus
+	if status == 0 {
+		status = http.StatusFound
+	}
+	return follow(ctx, urlDoc, status)
+}
+
+// UnlockURL Unlocks a password protected tiny url
+// (POST /tinyurlsvc/{urlKey}/unlock)
+func (h *handler) UnlockURL(ctx echo.Context, urlKey string) error {
+	// the password form carries the trailing path of the visit in its body and the query in its action
+	visit := h.newVisit(ctx, ctx.FormValue("password"), ctx.Request().PostFormValue("path"))
+	urlDoc, err := h.svc.GetTinyURL(ctx.Request().Context(), urlKey, visit)
 	if err != nil {
+		return h.getURLError(ctx, urlKey, visit.Path, err)
+	}
+	keepVisitor(ctx, urlDoc, visit)
+	urlDoc.LongURL = urlDoc.Destination(visit.Path, ctx.QueryParams())
+	h.recordClick(ctx, urlDoc)
+	// the form is posted, so the browser has to follow the redirect with a GET
+	return follow(ctx, urlDoc, http.StatusSeeOther)
+}
+
+// getURLError writes the response for an error resolving a tiny url visited with the trailing path. Browsers asked
+// for a password get the password form.
+func (h *handler) getURLError(ctx echo.Context, urlKey, path string, err error) error {
+	switch {
+	case errors.Is(err, types.ErrPasswordRequired), errors.Is(err, types.ErrWrongPassword):
+		if acceptsHTML(ctx.Request()) {
+			return renderPasswordPage(ctx, urlKey, path, errors.Is(err, types.ErrWrongPassword))
+		}
+		return ctx.JSON(http.StatusUnauthorized, &types.APIError{
+			Code:    types.UnauthorizedError,
+			Message: err.Error(),
+		})
+	case errors.Is(err, types.ErrTooManyAttempts):
+		return ctx.JSON(http.StatusTooManyRequests, &types.APIError{
+			Code:    types.TooManyRequestsError,
+			Message: err.Error(),
+		})
+	case errors.Is(err, types.ErrLinkExpired), errors.Is(err, types.ErrLinkSuspended),
+		errors.Is(err, types.ErrClicksExhausted):
+		return gone(ctx, err)
+	case errors.Is(err, types.ErrLinkNotYetActive):
+		return h.notYetActive(ctx, err)
+	default:
 		return ctx.JSON(http.StatusNotFound, &types.APIError{
 			Code:    types.NotFoundError,
 			Message: err.Error(),
 		})
 	}
-	http.Redirect(ctx.Response().Unwrap(), ctx.Request(), urlDoc.LongURL, http.StatusMovedPermanently)
+}
+
+// gone answers a visit of a tiny url that exists but no longer redirects with a 302 to its fallback url, or else a
+// 410 carrying the reason, so that clients can tell it from a tiny url that never existed
+func gone(ctx echo.Context, err error) error {
+	reason, _ := types.GoneReasonOf(err)
+	// tiny urls can be unsuspended or given a new expiry, so the response is not reused
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	var linkGone *types.LinkGoneError
+	if errors.As(err, &linkGone) {
+		http.Redirect(ctx.Response().Unwrap(), ctx.Request(), linkGone.FallbackURL, http.StatusFound)
+		return nil
+	}
+	return ctx.JSON(http.StatusGone, &types.GoneResponse{
+		Code:    types.GoneError,
+		Message: err.Error(),
+		Reason:  reason,
+	})
+}
+
+// notYetActive answers a visit of a tiny url that is not active yet with a redirect to the configured url, the
+// holding page for browsers or an error
+func (h *handler) notYetActive(ctx echo.Context, err error) error {
+	var activeFrom time.Time
+	var notYetActive *types.LinkNotYetActiveError
+	if errors.As(err, &notYetActive) {
+		activeFrom = notYetActive.ActiveFrom
+	}
+	// the response changes once the tiny url becomes active
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	if h.notYetActiveURL != "" {
+		http.Redirect(ctx.Response().Unwrap(), ctx.Request(), h.notYetActiveURL, http.StatusFound)
+		return nil
+	}
+	if wait := time.Until(activeFrom); wait > 0 {
+		ctx.Response().Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+	}
+	if acceptsHTML(ctx.Request()) {
+		return renderHoldingPage(ctx, h.holdingPage, activeFrom)
+	}
+	return ctx.JSON(http.StatusServiceUnavailable, &types.APIError{
+		Code:    types.NotYetActiveError,
+		Message: err.Error(),
+	})
+}
+
+// follow sends the visitor on to the long url, through the interstitial when the tiny url has one
+func follow(ctx echo.Context, urlDoc types.URLDocument, status int) error {
+	if urlDoc.Interstitial {
+		return interstitial(ctx, urlDoc)
+	}
+	return redirect(ctx, urlDoc, status)
+}
+
+// interstitial shows the long url instead of redirecting to it. Browsers get a page linking to the long url and API
+// clients get it in the body.
+func interstitial(ctx echo.Context, urlDoc types.URLDocument) error {
+	// every visit is counted as a click, so the response is never reused
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	if urlDoc.ReferrerPolicy != "" {
+		ctx.Response().Header().Set("Referrer-Policy", urlDoc.ReferrerPolicy)
+	}
+	if acceptsHTML(ctx.Request()) {
+		return renderInterstitialPage(ctx, urlDoc.LongURL)
+	}
+	return ctx.JSON(http.StatusOK, &types.InterstitialResponse{
+		Code:        types.InterstitialError,
+		Message:     types.ErrInterstitial.Error(),
+		Destination: urlDoc.LongURL,
+	})
+}
+
+// redirect redirects to the long url with the status and the headers of the tiny url
+func redirect(ctx echo.Context, urlDoc types.URLDocument, status int) error {
+	if urlDoc.CacheControl != "" {
+		ctx.Response().Header().Set("Cache-Control", urlDoc.CacheControl)
+	}
+	if urlDoc.ReferrerPolicy != "" {
+		ctx.Response().Header().Set("Referrer-Policy", urlDoc.ReferrerPolicy)
+	}
+	http.Redirect(ctx.Response().Unwrap(), ctx.Request(), urlDoc.LongURL, status)
 	return nil
 }
 
+// GetURLInfo Returns the details of a tiny url
+// (GET /tinyurlsvc/{urlKey}/info)
+func (h *handler) GetURLInfo(ctx echo.Context, urlKey string, params v0.GetURLInfoParams) error {
+	var password string
+	if params.XLinkPassword != nil {
+		password = *params.XLinkPassword
+	}
+	urlDoc, err := h.svc.GetTinyURLInfo(ctx.Request().Context(), urlKey, password)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrDocumentNotFound):
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NotFoundError,
+				Message: err.Error(),
+			})
+		case errors.Is(err, types.ErrLinkExpired), errors.Is(err, types.ErrLinkSuspended):
+			return gone(ctx, err)
+		case errors.Is(err, types.ErrPasswordRequired), errors.Is(err, types.ErrWrongPassword):
+			return ctx.JSON(http.StatusUnauthorized, &types.APIError{
+				Code:    types.UnauthorizedError,
+				Message: err.Error(),
+			})
+		case errors.Is(err, types.ErrTooManyAttempts):
+			return ctx.JSON(http.StatusTooManyRequests, &types.APIError{
+				Code:    types.TooManyRequestsError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	body, err := json.Marshal(h.toURLInfo(ctx, urlDoc))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+			Code:    types.InternalServerError,
+			Message: err.Error(),
+		})
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	ctx.Response().Header().Set("ETag", etag)
+	if params.IfNoneMatch != nil && etagMatches(*params.IfNoneMatch, etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	return ctx.JSONBlob(http.StatusOK, body)
+}
+
+// GetURLStats Returns the click stats of a tiny url
+// (GET /tinyurlsvc/{urlKey}/stats)
+func (h *handler) GetURLStats(ctx echo.Context, urlKey string) error {
+	urlDoc, err := h.svc.GetTinyURLStats(ctx.Request().Context(), urlKey, principal(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
+		case errors.Is(err, types.ErrDocumentNotFound):
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NotFoundError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	stats := &v0.URLStats{UrlKey: urlDoc.URLKey, Clicks: urlDoc.Clicks}
+	if len(urlDoc.Variants) > 0 {
+		variants := make([]v0.VariantStats, len(urlDoc.Variants))
+		for i, v := range urlDoc.Variants {
+			variants[i] = v0.VariantStats{Url: v.URL, Weight: v.Weight, Clicks: v.Clicks}
+		}
+		stats.Variants = &variants
+	}
+	return ctx.JSON(http.StatusOK, stats)
+}
+
 // DeleteURL Deletes a tiny url
 // (DELETE /tinyurlsvc/{urlKey})
-func (h *handler) DeleteURL(ctx echo.Context, urlKey string) error {
-	err := h.svc.DeleteTinyURL(ctx.Request().Context(), urlKey)
+func (h *handler) DeleteURL(ctx echo.Context, urlKey string, params v0.DeleteURLParams) error {
+	var err error
+	if params.Permanent != nil && *params.Permanent {
+		err = h.svc.PurgeTinyURL(ctx.Request().Context(), urlKey, principal(ctx))
+	} else {
+		err = h.svc.DeleteTinyURL(ctx.Request().Context(), urlKey, principal(ctx))
+	}
 	if err != nil {
 		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
 		case errors.Is(err, types.ErrCacheNotFound):
-			return ctx.JSON(http.StatusNoContent, nil)
+			return ctx.NoContent(http.StatusNoContent)
+		case errors.Is(err, types.ErrDocumentNotFound):
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NotFoundError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// RestoreURL Restores a tiny url from the trash
+// (POST /tinyurlsvc/{urlKey}/restore)
+func (h *handler) RestoreURL(ctx echo.Context, urlKey string) error {
+	urlDoc, err := h.svc.RestoreTinyURL(ctx.Request().Context(), urlKey, principal(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
+		case errors.Is(err, types.ErrDocumentNotFound):
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NotFoundError,
+				Message: err.Error(),
+			})
+		case errors.Is(err, types.ErrNotInTrash):
+			return ctx.JSON(http.StatusConflict, &types.APIError{
+				Code:    types.ConflictError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	return ctx.JSON(http.StatusOK, h.toURLInfo(ctx, urlDoc))
+}
+
+// SuspendURL Suspends a tiny url
+// (POST /tinyurlsvc/{urlKey}/suspend)
+func (h *handler) SuspendURL(ctx echo.Context, urlKey string) error {
+	suspendReq := new(v0.SuspendURLRequest)
+	// the body is optional
+	if err := json.NewDecoder(ctx.Request().Body).Decode(suspendReq); err != nil && !errors.Is(err, io.EOF) {
+		return ctx.JSON(http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		})
+	}
+	var reason string
+	if suspendReq.Reason != nil {
+		reason = *suspendReq.Reason
+	}
+	urlDoc, err := h.svc.SuspendTinyURL(ctx.Request().Context(), urlKey, reason, principal(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
+		case errors.Is(err, types.ErrDocumentNotFound):
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NotFoundError,
+				Message: err.Error(),
+			})
+		case errors.Is(err, types.ErrInvalidReason):
+			return ctx.JSON(http.StatusBadRequest, &types.APIError{
+				Code:    types.InputError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	return ctx.JSON(http.StatusOK, h.toURLInfo(ctx, urlDoc))
+}
+
+// UnsuspendURL Unsuspends a tiny url
+// (POST /tinyurlsvc/{urlKey}/unsuspend)
+func (h *handler) UnsuspendURL(ctx echo.Context, urlKey string) error {
+	urlDoc, err := h.svc.UnsuspendTinyURL(ctx.Request().Context(), urlKey, principal(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
+		case errors.Is(err, types.ErrDocumentNotFound):
+			return ctx.JSON(http.StatusNotFound, &types.APIError{
+				Code:    types.NotFoundError,
+				Message: err.Error(),
+			})
+		case errors.Is(err, types.ErrNotSuspended):
+			return ctx.JSON(http.StatusConflict, &types.APIError{
+				Code:    types.ConflictError,
+				Message: err.Error(),
+			})
+		default:
+			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+				Code:    types.InternalServerError,
+				Message: err.Error(),
+			})
+		}
+	}
+	return ctx.JSON(http.StatusOK, h.toURLInfo(ctx, urlDoc))
+}
+
+// UpdateURL Updates a tiny url
+// (PATCH /tinyurlsvc/{urlKey})
+func (h *handler) UpdateURL(ctx echo.Context, urlKey string) error {
+	updateReq, err := decodeUpdateRequest(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		})
+	}
+	update := types.URLUpdate{
+		LongURL:      updateReq.Url,
+		ExpireAt:     updateReq.ExpireAt,
+		LiveForever:  updateReq.LiveForever,
+		ActiveFrom:   updateReq.ActiveFrom,
+		ActiveUntil:  updateReq.ActiveUntil,
+		Interstitial: updateReq.Interstitial,
+		Passthrough:  updateReq.Passthrough,
+		Title:        updateReq.Title,
+		Notes:        updateReq.Notes,
+		Tags:         updateReq.Tags,
+		CreatedBy:    updateReq.CreatedBy,
+		ExternalID:   updateReq.ExternalID,
+		FallbackURL:  updateReq.FallbackURL,
+	}
+	if updateReq.Rules != nil {
+		rules := toTargetingRules(*updateReq.Rules)
+		update.Rules = &rules
+	}
+	if updateReq.Variants != nil {
+		variants := toVariants(*updateReq.Variants)
+		update.Variants = &variants
+	}
+	tinyURL, err := h.svc.UpdateTinyURL(ctx.Request().Context(), urlKey, update, principal(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return forbidden(ctx, err)
 		case errors.Is(err, types.ErrDocumentNotFound):
 			return ctx.JSON(http.StatusNotFound, &types.APIError{
 				Code:    types.NotFoundError,
 				Message: err.Error(),
 			})
+		case errors.Is(err, types.ErrEmptyUpdate), errors.Is(err, types.ErrConflictExpiry),
+			errors.Is(err, types.ErrMissingExpiry), errors.Is(err, types.ErrExpiryOutOfRange),
+			errors.Is(err, types.ErrInvalidWindow),
+			errors.Is(err, types.ErrInvalidTemplate), errors.Is(err, types.ErrInvalidRules),
+			errors.Is(err, types.ErrInvalidVariants), errors.Is(err, types.ErrInvalidMetadata),
+			errors.Is(err, types.ErrInvalidFallback):
+			return ctx.JSON(http.StatusBadRequest, &types.APIError{
+				Code:    types.InputError,
+				Message: err.Error(),
+			})
 		default:
 			return ctx.JSON(http.StatusInternalServerError, &types.APIError{
 				Code:    types.InternalServerError,
@@ -104,7 +713,246 @@ func (h *handler) DeleteURL(ctx echo.Context, urlKey string) error {
 			})
 		}
 	}
-	return ctx.JSON(http.StatusNoContent, nil)
+	response := &v0.GenerateURLResponse{GeneratedTinyURL: h.tinyURL(ctx, tinyURL)}
+	if !tinyURL.ExpireTime.IsZero() {
+		response.ExpireTime = timePtr(tinyURL.ExpireTime.UTC())
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// toURLInfo converts the document to the details of the tiny url. The clicks and the metadata about the people
+// managing the tiny url are only filled in for its owner and admins.
+func (h *handler) toURLInfo(ctx echo.Context, urlDoc types.URLDocument) *v0.URLInfo {
+	info := &v0.URLInfo{
+		UrlKey:      urlDoc.URLKey,
+		TinyURL:     h.tinyURL(ctx, urlDoc),
+		Url:         urlDoc.LongURL,
+		LiveForever: urlDoc.LiveForever,
+	}
+	if !urlDoc.ExpireTime.IsZero() {
+		info.ExpireTime = timePtr(urlDoc.ExpireTime.UTC())
+	}
+	if !urlDoc.CreatedAt.IsZero() {
+		info.CreatedAt = timePtr(urlDoc.CreatedAt.UTC())
+	}
+	if !urlDoc.UpdatedAt.IsZero() {
+		info.UpdatedAt = timePtr(urlDoc.UpdatedAt.UTC())
+	}
+	if !urlDoc.DeletedAt.IsZero() {
+		info.DeletedAt = timePtr(urlDoc.DeletedAt.UTC())
+	}
+	if urlDoc.Suspension != nil {
+		info.SuspendedAt = timePtr(urlDoc.Suspension.At.UTC())
+		if urlDoc.Suspension.Reason != "" {
+			info.SuspensionReason = stringPtr(urlDoc.Suspension.Reason)
+		}
+	}
+	if urlDoc.Title != "" {
+		info.Title = stringPtr(urlDoc.Title)
+	}
+	if principal(ctx).Manages(urlDoc) {
+		info.Clicks = int64Ptr(urlDoc.Clicks)
+		if urlDoc.Notes != "" {
+			info.Notes = stringPtr(urlDoc.Notes)
+		}
+		if len(urlDoc.Tags) > 0 {
+			info.Tags = &urlDoc.Tags
+		}
+		if urlDoc.CreatedBy != "" {
+			info.CreatedBy = stringPtr(urlDoc.CreatedBy)
+		}
+		if urlDoc.ExternalID != "" {
+			info.ExternalID = stringPtr(urlDoc.ExternalID)
+		}
+	}
+	if urlDoc.PasswordHash != "" {
+		info.PasswordProtected = boolPtr(true)
+	}
+	if urlDoc.MaxClicks > 0 {
+		info.MaxClicks = int64Ptr(urlDoc.MaxClicks)
+	}
+	if !urlDoc.ActiveFrom.IsZero() {
+		info.ActiveFrom = timePtr(urlDoc.ActiveFrom.UTC())
+	}
+	if !urlDoc.ActiveUntil.IsZero() {
+		info.ActiveUntil = timePtr(urlDoc.ActiveUntil.UTC())
+	}
+	if urlDoc.Interstitial {
+		info.Interstitial = boolPtr(true)
+	}
+	if urlDoc.Passthrough {
+		info.Passthrough = boolPtr(true)
+	}
+	if urlDoc.FallbackURL != "" {
+		info.FallbackURL = stringPtr(urlDoc.FallbackURL)
+	}
+	if len(urlDoc.Rules) > 0 {
+		rules := fromTargetingRules(urlDoc.Rules)
+		info.Rules = &rules
+	}
+	if len(urlDoc.Variants) > 0 {
+		variants := fromVariants(urlDoc.Variants)
+		info.Variants = &variants
+	}
+	return info
+}
+
+// etagMatches reports whether the If-None-Match header value matches the etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func toListRequest(params v0.ListURLsParams) types.ListRequest {
+	req := types.ListRequest{}
+	if params.Limit != nil {
+		req.Limit = *params.Limit
+	}
+	if params.Cursor != nil {
+		req.Cursor = *params.Cursor
+	}
+	if params.Sort != nil {
+		req.Sort = types.ListSort(*params.Sort)
+	}
+	if params.Order != nil {
+		req.Ascending = *params.Order == v0.Asc
+	}
+	if params.Owner != nil {
+		req.Filter.Owner = *params.Owner
+	}
+	if params.Domain != nil {
+		req.Filter.Domain = *params.Domain
+	}
+	if params.CreatedAfter != nil {
+		req.Filter.CreatedAfter = *params.CreatedAfter
+	}
+	if params.CreatedBefore != nil {
+		req.Filter.CreatedBefore = *params.CreatedBefore
+	}
+	if params.ExpiresAfter != nil {
+		req.Filter.ExpiresAfter = *params.ExpiresAfter
+	}
+	if params.ExpiresBefore != nil {
+		req.Filter.ExpiresBefore = *params.ExpiresBefore
+	}
+	if params.State != nil {
+		req.Filter.State = types.URLState(*params.State)
+	}
+	if params.Tag != nil {
+		req.Filter.Tags = *params.Tag
+	}
+	if params.CreatedBy != nil {
+		req.Filter.CreatedBy = *params.CreatedBy
+	}
+	if params.ExternalID != nil {
+		req.Filter.ExternalID = *params.ExternalID
+	}
+	if params.Q != nil {
+		req.Filter.Search = *params.Q
+	}
+	return req
+}
+
+func toGenerateRequest(genURLReq *v0.GenerateURLRequest, owner string) types.GenerateRequest {
+	req := types.GenerateRequest{
+		LongURL:     genURLReq.Url,
+		LiveForever: genURLReq.LiveForever,
+		Owner:       owner,
+	}
+	if genURLReq.Alias != nil {
+		req.Alias = *genURLReq.Alias
+	}
+	if genURLReq.TtlSeconds != nil {
+		req.TTL = time.Duration(*genURLReq.TtlSeconds) * time.Second
+	}
+	if genURLReq.ExpireAt != nil {
+		req.ExpireAt = *genURLReq.ExpireAt
+	}
+	if genURLReq.RedirectType != nil {
+		req.RedirectStatus = int(*genURLReq.RedirectType)
+	}
+	if genURLReq.CacheControl != nil {
+		req.CacheControl = *genURLReq.CacheControl
+	}
+	if genURLReq.ReferrerPolicy != nil {
+		req.ReferrerPolicy = string(*genURLReq.ReferrerPolicy)
+	}
+	req.Dedupe = genURLReq.Dedupe
+	if genURLReq.Password != nil {
+		req.Password = *genURLReq.Password
+	}
+	if genURLReq.MaxClicks != nil {
+		req.MaxClicks = *genURLReq.MaxClicks
+	}
+	if genURLReq.ActiveFrom != nil {
+		req.ActiveFrom = *genURLReq.ActiveFrom
+	}
+	if genURLReq.ActiveUntil != nil {
+		req.ActiveUntil = *genURLReq.ActiveUntil
+	}
+	if genURLReq.Interstitial != nil {
+		req.Interstitial = *genURLReq.Interstitial
+	}
+	if genURLReq.Passthrough != nil {
+		req.Passthrough = *genURLReq.Passthrough
+	}
+	if genURLReq.FallbackURL != nil {
+		req.FallbackURL = *genURLReq.FallbackURL
+	}
+	if genURLReq.Rules != nil {
+		req.Rules = toTargetingRules(*genURLReq.Rules)
+	}
+	if genURLReq.Variants != nil {
+		req.Variants = toVariants(*genURLReq.Variants)
+	}
+	if genURLReq.Title != nil {
+		req.Title = *genURLReq.Title
+	}
+	if genURLReq.Notes != nil {
+		req.Notes = *genURLReq.Notes
+	}
+	if genURLReq.Tags != nil {
+		req.Tags = *genURLReq.Tags
+	}
+	if genURLReq.CreatedBy != nil {
+		req.CreatedBy = *genURLReq.CreatedBy
+	}
+	if genURLReq.ExternalID != nil {
+		req.ExternalID = *genURLReq.ExternalID
+	}
+	return req
+}
+
+// generateError maps an error generating a tiny url to the response status and error
+func generateError(err error) (int, *types.APIError) {
+	switch {
+	case errors.Is(err, types.ErrInvalidAlias), errors.Is(err, types.ErrReservedAlias),
+		errors.Is(err, types.ErrConflictExpiry), errors.Is(err, types.ErrExpiryOutOfRange),
+		errors.Is(err, types.ErrInvalidRedirect), errors.Is(err, types.ErrInvalidPassword),
+		errors.Is(err, types.ErrInvalidMaxClicks), errors.Is(err, types.ErrInvalidWindow),
+		errors.Is(err, types.ErrInvalidTemplate), errors.Is(err, types.ErrInvalidRules),
+		errors.Is(err, types.ErrInvalidVariants), errors.Is(err, types.ErrInvalidMetadata),
+		errors.Is(err, types.ErrInvalidFallback):
+		return http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		}
+	case errors.Is(err, types.ErrAliasTaken):
+		return http.StatusConflict, &types.APIError{
+			Code:    types.ConflictError,
+			Message: err.Error(),
+		}
+	default:
+		return http.StatusInternalServerError, &types.APIError{
+			Code:    types.InternalServerError,
+			Message: err.Error(),
+		}
+	}
 }
 
 func decodeRequest(ctx echo.Context) (*v0.GenerateURLRequest, error) {
@@ -113,19 +961,52 @@ func decodeRequest(ctx echo.Context) (*v0.GenerateURLRequest, error) {
 	if err != nil {
 		return nil, err
 	}
-	if genURLReq.Url == "" {
-		return nil, types.ErrInvalidInput
+	if err = validateLongURL(genURLReq.Url); err != nil {
+		return nil, err
 	}
-	parsedURL, err := url.Parse(genURLReq.Url)
+	return genURLReq, nil
+}
+
+func decodeUpdateRequest(ctx echo.Context) (*v0.UpdateURLRequest, error) {
+	updateReq := new(v0.UpdateURLRequest)
+	err := json.NewDecoder(ctx.Request().Body).Decode(updateReq)
 	if err != nil {
 		return nil, err
 	}
+	if updateReq.Url != nil {
+		if err = validateLongURL(*updateReq.Url); err != nil {
+			return nil, err
+		}
+	}
+	return updateReq, nil
+}
+
+func validateLongURL(longURL string) error {
+	if longURL == "" {
+		return types.ErrInvalidInput
+	}
+	parsedURL, err := url.Parse(longURL)
+	if err != nil {
+		return err
+	}
 	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return nil, types.ErrInvalidScheme
+		return types.ErrInvalidScheme
 	}
-	return genURLReq, nil
+	return nil
 }
 
 func stringPtr(s string) *string {
 	return &s
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -1,18 +1,28 @@
 package rest_v0
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/auth"
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/clicks"
 	"github.com/vaishakdinesh/tiny-url-svc/pkg/url"
 	"github.com/vaishakdinesh/tiny-url-svc/types"
 	v0 "github.com/vaishakdinesh/tiny-url-svc/types/api/rest/v0"
@@ -23,8 +33,8 @@ func TestGenerateURL(t *testing.T) {
 	l := zap.NewNop()
 	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
 	c := &types.MockCache{Data: make(map[string]string)}
-	svc := url.NewTinyURLService(l, r, c)
-	h, err := NewHandler(l, svc)
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
 	a.NotNil(h)
 	a.Nil(err)
 
@@ -100,6 +110,35 @@ func TestGenerateURL(t *testing.T) {
 				a.Equal(tinyURLRes.Code, types.InputError)
 			},
 		},
+		"tiny url invalid password": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com", Password: stringPtr("abc")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+			},
+		},
+		"tiny url invalid activation window": {
+			req: &v0.GenerateURLRequest{
+				Url:         "https://foo.com",
+				ActiveFrom:  timePtr(time.Now().Add(time.Hour)),
+				ActiveUntil: timePtr(time.Now()),
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				tinyURLRes := &v0.APIError{}
+				err = json.Unmarshal(body, &tinyURLRes)
+				a.Nil(err)
+				a.Equal(types.InputError, tinyURLRes.Code)
+			},
+		},
 		"tiny url invalid url": {
 			req: &v0.GenerateURLRequest{Url: "?skhasdpasp"},
 			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
@@ -133,9 +172,122 @@ func TestGenerateURL(t *testing.T) {
 				a.Equal(tinyURLRes.Message, types.ErrInvalidScheme.Error())
 			},
 		},
+		"successfully create tiny url with alias": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com/sale", Alias: stringPtr("spring-sale")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusCreated, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				tinyURLRes := &v0.GenerateURLResponse{}
+				err = json.Unmarshal(body, &tinyURLRes)
+				a.Nil(err)
+				a.True(strings.HasSuffix(tinyURLRes.GeneratedTinyURL, "/spring-sale"))
+			},
+		},
+		"alias taken": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com/sale", Alias: stringPtr("taken-alias")},
+			pre: func() {
+				r.Data["taken-alias"] = types.URLDocument{URLKey: "taken-alias"}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusConflict, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				tinyURLRes := &v0.APIError{}
+				err = json.Unmarshal(body, &tinyURLRes)
+				a.Nil(err)
+				a.Equal(types.ConflictError, tinyURLRes.Code)
+			},
+		},
+		"successfully create tiny url with ttl": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com", TtlSeconds: int64Ptr(3600)},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusCreated, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				raw := map[string]string{}
+				err = json.Unmarshal(body, &raw)
+				a.Nil(err)
+				expireTime, err := time.Parse(time.RFC3339, raw["expireTime"])
+				a.Nil(err)
+				a.WithinDuration(time.Now().Add(time.Hour), expireTime, time.Second*5)
+			},
+		},
+		"expiry out of range": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com", TtlSeconds: int64Ptr(1)},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				tinyURLRes := &v0.APIError{}
+				err = json.Unmarshal(body, &tinyURLRes)
+				a.Nil(err)
+				a.Equal(types.InputError, tinyURLRes.Code)
+			},
+		},
+		"reserved alias": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com/sale", Alias: stringPtr("generate")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				tinyURLRes := &v0.APIError{}
+				err = json.Unmarshal(body, &tinyURLRes)
+				a.Nil(err)
+				a.Equal(types.InputError, tinyURLRes.Code)
+				a.Equal(types.ErrReservedAlias.Error(), tinyURLRes.Message)
+			},
+		},
+		"dedupe returns existing tiny url": {
+			req: &v0.GenerateURLRequest{Url: "https://foo.com/dedupe", Dedupe: boolPtr(true)},
+			pre: func() {
+				_, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+					LongURL: "https://FOO.com/dedupe",
+					Dedupe:  boolPtr(true),
+					Owner:   "admin",
+				})
+				a.Nil(err)
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusOK, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				tinyURLRes := &v0.GenerateURLResponse{}
+				err = json.Unmarshal(body, &tinyURLRes)
+				a.Nil(err)
+				a.NotEmpty(tinyURLRes.GeneratedTinyURL)
+			},
+		},
 	}
 	for name, testCase := range testCases {
 		t.Run(name, func(t *testing.T) {
+			if testCase.pre != nil {
+				testCase.pre()
+			}
 			bytes, err := json.Marshal(testCase.req)
 			a.Nil(err)
 
@@ -148,18 +300,80 @@ func TestGenerateURL(t *testing.T) {
 	}
 }
 
+func TestGenerateURLBatch(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	r.Data["taken-alias"] = types.URLDocument{URLKey: "taken-alias"}
+
+	bytes, err := json.Marshal(&v0.GenerateURLBatchRequest{Items: []v0.GenerateURLRequest{
+		{Url: "https://foo.com"},
+		{Url: "grpc://service:19081/FooExample/GrpcHello"},
+		{Url: "https://foo.com/sale", Alias: stringPtr("taken-alias")},
+		{Url: "https://foo.com/sale", Alias: stringPtr("batch-alias")},
+		{Url: "https://foo.com/sale", Dedupe: boolPtr(true)},
+		{Url: "https://foo.com/sale", Dedupe: boolPtr(true)},
+	}})
+	a.Nil(err)
+	req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(string(bytes)))
+	a.Nil(err)
+	ctx, rec := getCTX(req)
+	a.Nil(h.GenerateURLBatch(ctx))
+
+	res := rec.Result()
+	defer res.Body.Close()
+	a.Equal(http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	a.Nil(err)
+	batchRes := &v0.GenerateURLBatchResponse{}
+	a.Nil(json.Unmarshal(body, batchRes))
+	a.Len(batchRes.Results, 6)
+	for i, result := range batchRes.Results {
+		a.Equal(i, result.Index)
+	}
+
+	a.Nil(batchRes.Results[0].Error)
+	a.NotNil(batchRes.Results[0].GeneratedTinyURL)
+	a.NotNil(batchRes.Results[0].ExpireTime)
+	a.Nil(batchRes.Results[1].GeneratedTinyURL)
+	a.Equal(types.InputError, batchRes.Results[1].Error.Code)
+	a.Equal(types.ConflictError, batchRes.Results[2].Error.Code)
+	a.Nil(batchRes.Results[3].Error)
+	a.True(strings.HasSuffix(*batchRes.Results[3].GeneratedTinyURL, "/batch-alias"))
+	a.Nil(batchRes.Results[4].Existing)
+	a.True(*batchRes.Results[5].Existing)
+	a.Equal(*batchRes.Results[4].GeneratedTinyURL, *batchRes.Results[5].GeneratedTinyURL)
+}
+
 func TestGetURL(t *testing.T) {
 	a := assert.New(t)
 	l := zap.NewNop()
 	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
 	c := &types.MockCache{Data: make(map[string]string)}
-	svc := url.NewTinyURLService(l, r, c)
-	h, err := NewHandler(l, svc)
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
 	a.NotNil(h)
 	a.Nil(err)
+	protected, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL:  "https://foo.com/protected",
+		Password: "s3cret",
+	})
+	a.Nil(err)
+	upcoming, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL:    "https://foo.com/launch",
+		ActiveFrom: time.Now().Add(time.Hour),
+	})
+	a.Nil(err)
 
 	testCases := map[string]struct {
 		urlKey        string
+		params        v0.GetURLParams
+		accept        string
 		expectedError bool
 		pre           func()
 		validate      func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
@@ -167,13 +381,131 @@ func TestGetURL(t *testing.T) {
 		"successfully get tiny url": {
 			urlKey: "f56Cd",
 			pre: func() {
-				r.Data["f56Cd"] = types.URLDocument{}
+				r.Data["f56Cd"] = types.URLDocument{LiveForever: true}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusFound, res.StatusCode)
+				a.Empty(res.Header.Get("Cache-Control"))
+			},
+		},
+		"successfully get tiny url with redirect settings": {
+			urlKey: "Pq7Ws",
+			pre: func() {
+				r.Data["Pq7Ws"] = types.URLDocument{
+					URLKey:         "Pq7Ws",
+					LongURL:        "https://foo.com",
+					LiveForever:    true,
+					RedirectStatus: http.StatusMovedPermanently,
+					CacheControl:   "private, max-age=90",
+					ReferrerPolicy: "no-referrer",
+				}
 			},
 			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
 				a.Nil(err)
 				res := rec.Result()
 				defer res.Body.Close()
 				a.Equal(http.StatusMovedPermanently, res.StatusCode)
+				a.Equal("https://foo.com", res.Header.Get("Location"))
+				a.Equal("private, max-age=90", res.Header.Get("Cache-Control"))
+				a.Equal("no-referrer", res.Header.Get("Referrer-Policy"))
+			},
+		},
+		"password required": {
+			urlKey: protected.URLKey,
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusUnauthorized, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				apiErr := &v0.APIError{}
+				a.Nil(json.Unmarshal(body, apiErr))
+				a.Equal(types.UnauthorizedError, apiErr.Code)
+			},
+		},
+		"password form for browsers": {
+			urlKey: protected.URLKey,
+			accept: "text/html,application/xhtml+xml",
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusUnauthorized, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+				a.Contains(string(body), `action="/tinyurlsvc/`+protected.URLKey+`/unlock"`)
+			},
+		},
+		"password in header": {
+			urlKey: protected.URLKey,
+			params: v0.GetURLParams{XLinkPassword: stringPtr("s3cret")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusFound, res.StatusCode)
+				a.Equal("https://foo.com/protected", res.Header.Get("Location"))
+			},
+		},
+		"click limit reached": {
+			urlKey: "Xk4Rt",
+			pre: func() {
+				r.Data["Xk4Rt"] = types.URLDocument{
+					URLKey:     "Xk4Rt",
+					LongURL:    "https://foo.com/invite",
+					ExpireTime: time.Now().Add(time.Hour),
+					MaxClicks:  1,
+					Clicks:     1,
+				}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusGone, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				goneErr := &v0.GoneError{}
+				a.Nil(json.Unmarshal(body, goneErr))
+				a.Equal(types.GoneError, goneErr.Code)
+				a.Equal(v0.GoneErrorReasonExhausted, goneErr.Reason)
+			},
+		},
+		"not active yet": {
+			urlKey: upcoming.URLKey,
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusServiceUnavailable, res.StatusCode)
+				a.NotEmpty(res.Header.Get("Retry-After"))
+				a.Equal("no-store", res.Header.Get("Cache-Control"))
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				apiErr := &v0.APIError{}
+				a.Nil(json.Unmarshal(body, apiErr))
+				a.Equal(types.NotYetActiveError, apiErr.Code)
+			},
+		},
+		"holding page for browsers": {
+			urlKey: upcoming.URLKey,
+			accept: "text/html,application/xhtml+xml",
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusServiceUnavailable, res.StatusCode)
+				a.NotEmpty(res.Header.Get("Retry-After"))
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+				a.Contains(string(body), "not active yet")
 			},
 		},
 		"tiny url not found": {
@@ -193,66 +525,1691 @@ func TestGetURL(t *testing.T) {
 			}
 			req, err := http.NewRequest(http.MethodDelete, apiURL, nil)
 			a.Nil(err)
+			if testCase.accept != "" {
+				req.Header.Set(echo.HeaderAccept, testCase.accept)
+			}
 
 			ctx, rec := getCTX(req)
-			testCase.validate(a, rec, h.GetURL(ctx, testCase.urlKey))
+			testCase.validate(a, rec, h.GetURL(ctx, testCase.urlKey, testCase.params))
 		})
 	}
+
+	t.Run("redirect to the not yet active url", func(t *testing.T) {
+		h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{},
+			types.HandlerConfig{NotYetActiveURL: "https://foo.com/soon"})
+		a.Nil(err)
+		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		a.Nil(err)
+
+		ctx, rec := getCTX(req)
+		a.Nil(h.GetURL(ctx, upcoming.URLKey, v0.GetURLParams{}))
+		res := rec.Result()
+		defer res.Body.Close()
+		a.Equal(http.StatusFound, res.StatusCode)
+		a.Equal("https://foo.com/soon", res.Header.Get("Location"))
+		a.Equal("no-store", res.Header.Get("Cache-Control"))
+	})
 }
 
-func TestDeleteURL(t *testing.T) {
+func TestGetURLInterstitial(t *testing.T) {
 	a := assert.New(t)
 	l := zap.NewNop()
 	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
 	c := &types.MockCache{Data: make(map[string]string)}
-	svc := url.NewTinyURLService(l, r, c)
-	h, err := NewHandler(l, svc)
+	cfg := url.DefaultConfig()
+	cfg.TrustedDomains = []string{"foo.com"}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), cfg)
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
 	a.NotNil(h)
 	a.Nil(err)
+	untrusted, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL: "https://bar.com/sale?id=1&ref=<x>",
+	})
+	a.Nil(err)
+	asked, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL:      "https://foo.com/sale",
+		Interstitial: true,
+	})
+	a.Nil(err)
+	trusted, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL: "https://foo.com/sale",
+	})
+	a.Nil(err)
 
 	testCases := map[string]struct {
-		urlKey        string
-		expectedError bool
-		pre           func()
-		validate      func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
+		urlKey         string
+		accept         string
+		expectedStatus int
+		validate       func(a *assert.Assertions, res *http.Response)
 	}{
-		"successfully delete tiny url": {
-			urlKey: "f56Cd",
-			pre: func() {
-				r.Data["f56Cd"] = types.URLDocument{}
-			},
-			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+		"page for browsers": {
+			urlKey:         untrusted.URLKey,
+			accept:         "text/html,application/xhtml+xml",
+			expectedStatus: http.StatusOK,
+			validate: func(a *assert.Assertions, res *http.Response) {
+				a.Equal("no-store", res.Header.Get("Cache-Control"))
+				body, err := io.ReadAll(res.Body)
 				a.Nil(err)
-				res := rec.Result()
-				defer res.Body.Close()
-				a.Equal(http.StatusNoContent, res.StatusCode)
+				a.Contains(string(body), `<strong>bar.com</strong>`)
+				a.Contains(string(body), `href="https://bar.com/sale?id=1&amp;ref=%3cx%3e"`)
+				a.NotContains(string(body), "<x>")
 			},
 		},
-		"tiny url not found": {
-			urlKey: "6hgtEs",
-			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+		"destination for api clients": {
+			urlKey:         asked.URLKey,
+			expectedStatus: http.StatusOK,
+			validate: func(a *assert.Assertions, res *http.Response) {
+				body, err := io.ReadAll(res.Body)
 				a.Nil(err)
-				res := rec.Result()
-				defer res.Body.Close()
-				a.Equal(http.StatusNotFound, res.StatusCode)
+				interstitial := &v0.Interstitial{}
+				a.Nil(json.Unmarshal(body, interstitial))
+				a.Equal(types.InterstitialError, interstitial.Code)
+				a.Equal("https://foo.com/sale", interstitial.Destination)
+			},
+		},
+		"trusted domain redirects": {
+			urlKey:         trusted.URLKey,
+			accept:         "text/html",
+			expectedStatus: http.StatusFound,
+			validate: func(a *assert.Assertions, res *http.Response) {
+				a.Equal("https://foo.com/sale", res.Header.Get("Location"))
 			},
 		},
 	}
 	for name, testCase := range testCases {
 		t.Run(name, func(t *testing.T) {
-			if testCase.pre != nil {
-				testCase.pre()
-			}
-			req, err := http.NewRequest(http.MethodDelete, apiURL, nil)
+			req, err := http.NewRequest(http.MethodGet, apiURL, nil)
 			a.Nil(err)
+			req.Header.Set(echo.HeaderAccept, testCase.accept)
 
 			ctx, rec := getCTX(req)
-			testCase.validate(a, rec, h.DeleteURL(ctx, testCase.urlKey))
+			a.Nil(h.GetURL(ctx, testCase.urlKey, v0.GetURLParams{}))
+			res := rec.Result()
+			defer res.Body.Close()
+			a.Equal(testCase.expectedStatus, res.StatusCode)
+			testCase.validate(a, res)
 		})
 	}
 }
 
-func getCTX(r *http.Request) (echo.Context, *httptest.ResponseRecorder) {
+func TestUnlockURL(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	protected, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL:        "https://foo.com/protected",
+		Password:       "s3cret",
+		RedirectStatus: http.StatusPermanentRedirect,
+	})
+	a.Nil(err)
+
+	testCases := map[string]struct {
+		password       string
+		expectedStatus int
+	}{
+		"wrong password": {
+			password:       "wrong",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"right password": {
+			password:       "s3cret",
+			expectedStatus: http.StatusSeeOther,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			form := "password=" + testCase.password
+			req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(form))
+			a.Nil(err)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			req.Header.Set(echo.HeaderAccept, echo.MIMETextHTML)
+
+			ctx, rec := getCTX(req)
+			a.Nil(h.UnlockURL(ctx, protected.URLKey))
+			res := rec.Result()
+			defer res.Body.Close()
+			a.Equal(testCase.expectedStatus, res.StatusCode)
+			if testCase.expectedStatus == http.StatusSeeOther {
+				a.Equal("https://foo.com/protected", res.Header.Get("Location"))
+			}
+		})
+	}
+}
+
+func TestGetURLInfo(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	r.Data["f56Cd"] = types.URLDocument{
+		URLKey:     "f56Cd",
+		LongURL:    "https://foo.com",
+		ExpireTime: time.Now().Add(time.Hour),
+		CreatedAt:  time.Now(),
+		Clicks:     3,
+	}
+
+	// first request returns the details and the etag to poll with
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	a.Nil(err)
+	ctx, rec := getCTX(req)
+	a.Nil(h.GetURLInfo(ctx, "f56Cd", v0.GetURLInfoParams{}))
+	res := rec.Result()
+	defer res.Body.Close()
+	a.Equal(http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	a.NotEmpty(etag)
+	body, err := io.ReadAll(res.Body)
+	a.Nil(err)
+	info := &v0.URLInfo{}
+	a.Nil(json.Unmarshal(body, info))
+	a.Equal("https://foo.com", info.Url)
+	a.Equal(int64Ptr(3), info.Clicks)
+	a.NotNil(info.CreatedAt)
+
+	testCases := map[string]struct {
+		urlKey         string
+		ifNoneMatch    *string
+		pre            func()
+		expectedStatus int
+	}{
+		"not modified": {
+			urlKey:         "f56Cd",
+			ifNoneMatch:    &etag,
+			expectedStatus: http.StatusNotModified,
+		},
+		"weak etag not modified": {
+			urlKey:         "f56Cd",
+			ifNoneMatch:    stringPtr(`"other", W/` + etag),
+			expectedStatus: http.StatusNotModified,
+		},
+		"modified": {
+			urlKey:      "Gh6Tr",
+			ifNoneMatch: &etag,
+			pre: func() {
+				r.Data["Gh6Tr"] = types.URLDocument{
+					URLKey:     "Gh6Tr",
+					LongURL:    "https://foo.com",
+					ExpireTime: time.Now().Add(time.Hour),
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		"expired": {
+			urlKey: "Yt5Re",
+			pre: func() {
+				r.Data["Yt5Re"] = types.URLDocument{URLKey: "Yt5Re", ExpireTime: time.Now()}
+			},
+			expectedStatus: http.StatusGone,
+		},
+		"tiny url not found": {
+			urlKey:         "6hgtEs",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if testCase.pre != nil {
+				testCase.pre()
+			}
+			req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			err = h.GetURLInfo(ctx, testCase.urlKey, v0.GetURLInfoParams{IfNoneMatch: testCase.ifNoneMatch})
+			a.Nil(err)
+			res := rec.Result()
+			defer res.Body.Close()
+			a.Equal(testCase.expectedStatus, res.StatusCode)
+		})
+	}
+
+	r.Data["Kp9Lm"] = types.URLDocument{
+		URLKey:      "Kp9Lm",
+		LongURL:     "https://foo.com",
+		LiveForever: true,
+		Owner:       "owner",
+		Clicks:      4,
+		Metadata:    types.Metadata{Title: "Sale", Notes: "internal", Tags: []string{"q3"}, ExternalID: "crm-1"},
+	}
+	callers := map[string]struct {
+		caller   *types.Principal
+		expected bool
+	}{
+		"anonymous":    {},
+		"other caller": {caller: &types.Principal{KeyID: "other"}},
+		"owner":        {caller: &types.Principal{KeyID: "owner"}, expected: true},
+		"admin":        {caller: &admin, expected: true},
+	}
+	for name, testCase := range callers {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, apiURL, nil)
+			rec := httptest.NewRecorder()
+			ctx := echo.New().NewContext(req, rec)
+			if testCase.caller != nil {
+				ctx, rec = getCTXAs(req, *testCase.caller)
+			}
+			a.Nil(h.GetURLInfo(ctx, "Kp9Lm", v0.GetURLInfoParams{}))
+			a.Equal(http.StatusOK, rec.Code)
+			info := &v0.URLInfo{}
+			a.Nil(json.Unmarshal(rec.Body.Bytes(), info))
+			a.Equal(stringPtr("Sale"), info.Title)
+			if testCase.expected {
+				a.Equal(int64Ptr(4), info.Clicks)
+				a.Equal(stringPtr("internal"), info.Notes)
+				a.Equal(&[]string{"q3"}, info.Tags)
+				a.Equal(stringPtr("crm-1"), info.ExternalID)
+			} else {
+				a.Nil(info.Clicks)
+				a.Nil(info.Notes)
+				a.Nil(info.Tags)
+				a.Nil(info.ExternalID)
+			}
+		})
+	}
+}
+
+func TestListURLs(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	for i, key := range []string{"f56Cd", "Gh6Tr", "Yt5Re"} {
+		r.Data[key] = types.URLDocument{
+			URLKey:     key,
+			LongURL:    "https://foo.com",
+			Domain:     "foo.com",
+			ExpireTime: time.Now().Add(time.Hour),
+			CreatedAt:  time.Now().Add(time.Duration(i) * time.Second),
+		}
+	}
+	tagged := r.Data["Gh6Tr"]
+	tagged.Metadata = types.Metadata{Title: "Spring sale", Tags: []string{"sale", "email"}, ExternalID: "c-1"}
+	r.Data["Gh6Tr"] = tagged
+
+	testCases := map[string]struct {
+		params   v0.ListURLsParams
+		validate func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
+	}{
+		"first page": {
+			params: v0.ListURLsParams{Limit: intPtr(2)},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusOK, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				list := &v0.URLList{}
+				a.Nil(json.Unmarshal(body, list))
+				a.Len(list.Items, 2)
+				a.Equal("Yt5Re", list.Items[0].UrlKey)
+				a.NotNil(list.NextCursor)
+			},
+		},
+		"last page": {
+			params: v0.ListURLsParams{Domain: stringPtr("foo.com")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusOK, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				list := &v0.URLList{}
+				a.Nil(json.Unmarshal(body, list))
+				a.Len(list.Items, 3)
+				a.Nil(list.NextCursor)
+			},
+		},
+		"tags and search": {
+			params: v0.ListURLsParams{Tag: &[]string{"Sale", "email"}, Q: stringPtr("spring")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusOK, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				list := &v0.URLList{}
+				a.Nil(json.Unmarshal(body, list))
+				a.Len(list.Items, 1)
+				a.Equal("Gh6Tr", list.Items[0].UrlKey)
+				a.Equal("Spring sale", *list.Items[0].Title)
+				a.Equal([]string{"sale", "email"}, *list.Items[0].Tags)
+				a.Equal("c-1", *list.Items[0].ExternalID)
+			},
+		},
+		"invalid cursor": {
+			params: v0.ListURLsParams{Cursor: stringPtr("abc")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+				body, err := io.ReadAll(res.Body)
+				a.Nil(err)
+
+				apiErr := &v0.APIError{}
+				a.Nil(json.Unmarshal(body, apiErr))
+				a.Equal(types.InputError, apiErr.Code)
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			testCase.validate(a, rec, h.ListURLs(ctx, testCase.params))
+		})
+	}
+}
+
+func TestDeleteURL(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+
+	permanent := true
+	testCases := map[string]struct {
+		urlKey        string
+		caller        *types.Principal
+		params        v0.DeleteURLParams
+		expectedError bool
+		pre           func()
+		validate      func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
+	}{
+		"successfully delete tiny url": {
+			urlKey: "f56Cd",
+			pre: func() {
+				r.Data["f56Cd"] = types.URLDocument{}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusNoContent, res.StatusCode)
+			},
+		},
+		"tiny url not found": {
+			urlKey: "6hgtEs",
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusNotFound, res.StatusCode)
+			},
+		},
+		"not the owner": {
+			urlKey: "g67De",
+			caller: &types.Principal{KeyID: "other"},
+			pre: func() {
+				r.Data["g67De"] = types.URLDocument{URLKey: "g67De", Owner: "owner"}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusForbidden, res.StatusCode)
+				a.Contains(r.Data, "g67De")
+			},
+		},
+		"owner deletes tiny url": {
+			urlKey: "h78Ef",
+			caller: &types.Principal{KeyID: "owner"},
+			pre: func() {
+				r.Data["h78Ef"] = types.URLDocument{URLKey: "h78Ef", Owner: "owner"}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusNoContent, res.StatusCode)
+				a.False(r.Data["h78Ef"].DeletedAt.IsZero())
+			},
+		},
+		"owner cannot delete tiny url for good": {
+			urlKey: "i89Fg",
+			caller: &types.Principal{KeyID: "owner"},
+			params: v0.DeleteURLParams{Permanent: &permanent},
+			pre: func() {
+				r.Data["i89Fg"] = types.URLDocument{URLKey: "i89Fg", Owner: "owner"}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusForbidden, res.StatusCode)
+				a.True(r.Data["i89Fg"].DeletedAt.IsZero())
+			},
+		},
+		"admin deletes tiny url for good": {
+			urlKey: "j90Gh",
+			params: v0.DeleteURLParams{Permanent: &permanent},
+			pre: func() {
+				r.Data["j90Gh"] = types.URLDocument{URLKey: "j90Gh", DeletedAt: time.Now()}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusNoContent, res.StatusCode)
+				a.NotContains(r.Data, "j90Gh")
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if testCase.pre != nil {
+				testCase.pre()
+			}
+			req, err := http.NewRequest(http.MethodDelete, apiURL, nil)
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			if testCase.caller != nil {
+				ctx, rec = getCTXAs(req, *testCase.caller)
+			}
+			testCase.validate(a, rec, h.DeleteURL(ctx, testCase.urlKey, testCase.params))
+		})
+	}
+}
+
+func TestRestoreURL(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+
+	testCases := map[string]struct {
+		urlKey   string
+		caller   *types.Principal
+		pre      func()
+		validate func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
+	}{
+		"owner restores tiny url": {
+			urlKey: "k12Hi",
+			caller: &types.Principal{KeyID: "owner"},
+			pre: func() {
+				r.Data["k12Hi"] = types.URLDocument{URLKey: "k12Hi", LongURL: "https://google.com", LiveForever: true,
+					Owner: "owner", DeletedAt: time.Now()}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusOK, res.StatusCode)
+				info := &v0.URLInfo{}
+				a.Nil(json.NewDecoder(res.Body).Decode(info))
+				a.Equal("https://google.com", info.Url)
+				a.Nil(info.DeletedAt)
+				a.True(r.Data["k12Hi"].DeletedAt.IsZero())
+			},
+		},
+		"tiny url not in the trash": {
+			urlKey: "l23Ij",
+			pre: func() {
+				r.Data["l23Ij"] = types.URLDocument{URLKey: "l23Ij", LiveForever: true}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusConflict, res.StatusCode)
+			},
+		},
+		"not the owner": {
+			urlKey: "m34Jk",
+			caller: &types.Principal{KeyID: "other"},
+			pre: func() {
+				r.Data["m34Jk"] = types.URLDocument{URLKey: "m34Jk", Owner: "owner", DeletedAt: time.Now()}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusForbidden, res.StatusCode)
+				a.False(r.Data["m34Jk"].DeletedAt.IsZero())
+			},
+		},
+		"tiny url not found": {
+			urlKey: "n45Kl",
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusNotFound, res.StatusCode)
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if testCase.pre != nil {
+				testCase.pre()
+			}
+			req, err := http.NewRequest(http.MethodPost, apiURL, nil)
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			if testCase.caller != nil {
+				ctx, rec = getCTXAs(req, *testCase.caller)
+			}
+			testCase.validate(a, rec, h.RestoreURL(ctx, testCase.urlKey))
+		})
+	}
+}
+
+func TestSuspendURL(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	owner := types.Principal{KeyID: "owner"}
+	tURL, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL: "https://foo.com/reported",
+		Owner:   owner.KeyID,
+	})
+	a.Nil(err)
+	r.Data["Ex9Pd"] = types.URLDocument{URLKey: "Ex9Pd", LongURL: "https://foo.com", ExpireTime: time.Now()}
+
+	// visit returns the status and the gone reason of a visit of the tiny url
+	visit := func(urlKey string) (int, v0.GoneErrorReason) {
+		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		a.Nil(err)
+		ctx, rec := getCTX(req)
+		a.Nil(h.GetURL(ctx, urlKey, v0.GetURLParams{}))
+		res := rec.Result()
+		defer res.Body.Close()
+		goneErr := &v0.GoneError{}
+		if res.StatusCode == http.StatusGone {
+			a.Nil(json.NewDecoder(res.Body).Decode(goneErr))
+			a.Equal("no-store", res.Header.Get("Cache-Control"))
+		}
+		return res.StatusCode, goneErr.Reason
+	}
+	// post calls the handler as the caller with the body and returns the response
+	post := func(handle func(echo.Context, string) error, caller types.Principal, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(body))
+		a.Nil(err)
+		ctx, rec := getCTXAs(req, caller)
+		a.Nil(handle(ctx, tURL.URLKey))
+		return rec.Result()
+	}
+
+	status, reason := visit("Ex9Pd")
+	a.Equal(http.StatusGone, status)
+	a.Equal(v0.GoneErrorReasonExpired, reason)
+	status, _ = visit("Nv3Rx")
+	a.Equal(http.StatusNotFound, status)
+
+	res := post(h.SuspendURL, owner, "")
+	defer res.Body.Close()
+	a.Equal(http.StatusForbidden, res.StatusCode)
+	res = post(h.SuspendURL, admin, fmt.Sprintf(`{"reason": %q}`, strings.Repeat("x", 501)))
+	defer res.Body.Close()
+	a.Equal(http.StatusBadRequest, res.StatusCode)
+
+	res = post(h.SuspendURL, admin, `{"reason": "reported as phishing"}`)
+	defer res.Body.Close()
+	a.Equal(http.StatusOK, res.StatusCode)
+	info := &v0.URLInfo{}
+	a.Nil(json.NewDecoder(res.Body).Decode(info))
+	a.NotNil(info.SuspendedAt)
+	a.Equal("reported as phishing", *info.SuspensionReason)
+	status, reason = visit(tURL.URLKey)
+	a.Equal(http.StatusGone, status)
+	a.Equal(v0.GoneErrorReasonSuspended, reason)
+
+	res = post(h.UnsuspendURL, admin, "")
+	defer res.Body.Close()
+	a.Equal(http.StatusOK, res.StatusCode)
+	status, _ = visit(tURL.URLKey)
+	a.Equal(http.StatusFound, status)
+	res = post(h.UnsuspendURL, admin, "")
+	defer res.Body.Close()
+	a.Equal(http.StatusConflict, res.StatusCode)
+}
+
+func TestFallbackURL(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	r.Data["Ex9Pd"] = types.URLDocument{
+		URLKey:      "Ex9Pd",
+		LongURL:     "https://foo.com",
+		ExpireTime:  time.Now(),
+		FallbackURL: "https://foo.com/ended",
+	}
+
+	// generate asks for a tiny url with the fallback url and returns the response status
+	generate := func(fallbackURL string) int {
+		body, err := json.Marshal(v0.GenerateURLRequest{Url: "https://foo.com", FallbackURL: &fallbackURL})
+		a.Nil(err)
+		req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(body))
+		a.Nil(err)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		ctx, rec := getCTX(req)
+		a.Nil(h.GenerateURL(ctx))
+		res := rec.Result()
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+	a.Equal(http.StatusBadRequest, generate("javascript:alert(1)"))
+	a.Equal(http.StatusCreated, generate("https://foo.com/ended"))
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	a.Nil(err)
+	ctx, rec := getCTX(req)
+	a.Nil(h.GetURL(ctx, "Ex9Pd", v0.GetURLParams{}))
+	res := rec.Result()
+	defer res.Body.Close()
+	a.Equal(http.StatusFound, res.StatusCode)
+	a.Equal("https://foo.com/ended", res.Header.Get("Location"))
+	a.Equal("no-store", res.Header.Get("Cache-Control"))
+
+	// the info of the tiny url still answers with a 410
+	req, err = http.NewRequest(http.MethodGet, apiURL, nil)
+	a.Nil(err)
+	ctx, rec = getCTX(req)
+	a.Nil(h.GetURLInfo(ctx, "Ex9Pd", v0.GetURLInfoParams{}))
+	res = rec.Result()
+	defer res.Body.Close()
+	a.Equal(http.StatusGone, res.StatusCode)
+}
+
+func TestClickEvents(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	recorder := &types.MockClickRecorder{}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), recorder, types.HandlerConfig{IPHashKey: "ip-hash-key"})
+	a.NotNil(h)
+	a.Nil(err)
+	tenant := types.WithTenant(context.Background(), "brand-a")
+	tURL, _, err := svc.GenerateTinyURL(tenant, types.GenerateRequest{LongURL: "https://foo.com/offer"})
+	a.Nil(err)
+	r.Data["Ex9Pd"] = types.URLDocument{URLKey: "Ex9Pd", LongURL: "https://foo.com", ExpireTime: time.Now()}
+
+	// visit visits the tiny url from the client and returns the response status
+	visit := func(ctx context.Context, urlKey string) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+		a.Nil(err)
+		req.Header.Set("Referer", "https://news.example/post")
+		req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone)")
+		req.Header.Set(echo.HeaderXRealIP, "203.0.113.7")
+		echoCtx, rec := getCTX(req)
+		a.Nil(h.GetURL(echoCtx, urlKey, v0.GetURLParams{}))
+		res := rec.Result()
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+	a.Equal(http.StatusFound, visit(tenant, tURL.URLKey))
+	a.Equal(http.StatusGone, visit(context.Background(), "Ex9Pd"))
+	a.Equal(http.StatusNotFound, visit(context.Background(), "Nv3Rx"))
+
+	// only the redirect is recorded, and the IP of the client is not
+	a.Len(recorder.Events, 1)
+	event := recorder.Events[0]
+	a.Equal("brand-a", event.Tenant)
+	a.Equal(tURL.URLKey, event.URLKey)
+	a.WithinDuration(time.Now(), event.Time, time.Minute)
+	a.Equal("https://news.example/post", event.Referrer)
+	a.Equal("Mozilla/5.0 (iPhone)", event.UserAgent)
+	a.Equal("https://foo.com/offer", event.Destination)
+	a.NotEmpty(event.IPHash)
+	a.NotContains(event.IPHash, "203.0.113.7")
+	plain := sha256.Sum256([]byte("203.0.113.7"))
+	a.NotEqual(hex.EncodeToString(plain[:16]), event.IPHash)
+}
+
+func TestHashIP(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	// newHandler returns a handler hashing IPs with the key
+	newHandler := func(key string) *handler {
+		h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{IPHashKey: key})
+		a.Nil(err)
+		return h.(*handler)
+	}
+
+	h := newHandler("ip-hash-key")
+	hash := h.hashIP("203.0.113.7")
+	// hashes are stable for a key, and can stand in for the visitor cookie
+	a.Equal(hash, newHandler("ip-hash-key").hashIP("203.0.113.7"))
+	a.Regexp(visitorIDFormat, hash)
+	a.NotEqual(hash, h.hashIP("203.0.113.8"))
+	a.NotEqual(hash, newHandler("other-key").hashIP("203.0.113.7"))
+	// handlers without a key hash with a random one
+	a.NotEqual(newHandler("").hashIP("203.0.113.7"), newHandler("").hashIP("203.0.113.7"))
+}
+
+func TestUpdateURL(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+
+	testCases := map[string]struct {
+		urlKey   string
+		caller   *types.Principal
+		req      *v0.UpdateURLRequest
+		pre      func()
+		validate func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
+	}{
+		"successfully update tiny url": {
+			urlKey: "f56Cd",
+			req:    &v0.UpdateURLRequest{Url: stringPtr("https://bar.com")},
+			pre: func() {
+				r.Data["f56Cd"] = types.URLDocument{URLKey: "f56Cd", LongURL: "https://foo.com"}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusOK, res.StatusCode)
+				a.Equal("https://bar.com", r.Data["f56Cd"].LongURL)
+			},
+		},
+		"unsupported scheme": {
+			urlKey: "f56Cd",
+			req:    &v0.UpdateURLRequest{Url: stringPtr("ftp://bar.com")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+			},
+		},
+		"live forever turned off without expire at": {
+			urlKey: "f56Cd",
+			req:    &v0.UpdateURLRequest{LiveForever: boolPtr(false)},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusBadRequest, res.StatusCode)
+			},
+		},
+		"tiny url not found": {
+			urlKey: "6hgtEs",
+			req:    &v0.UpdateURLRequest{Url: stringPtr("https://bar.com")},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusNotFound, res.StatusCode)
+			},
+		},
+		"not the owner": {
+			urlKey: "g67De",
+			caller: &types.Principal{KeyID: "other"},
+			req:    &v0.UpdateURLRequest{Url: stringPtr("https://bar.com")},
+			pre: func() {
+				r.Data["g67De"] = types.URLDocument{URLKey: "g67De", LongURL: "https://foo.com", Owner: "owner"}
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				res := rec.Result()
+				defer res.Body.Close()
+				a.Equal(http.StatusForbidden, res.StatusCode)
+				a.Equal("https://foo.com", r.Data["g67De"].LongURL)
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if testCase.pre != nil {
+				testCase.pre()
+			}
+			bytes, err := json.Marshal(testCase.req)
+			a.Nil(err)
+
+			req, err := http.NewRequest(http.MethodPatch, apiURL, strings.NewReader(string(bytes)))
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			if testCase.caller != nil {
+				ctx, rec = getCTXAs(req, *testCase.caller)
+			}
+			testCase.validate(a, rec, h.UpdateURL(ctx, testCase.urlKey))
+		})
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	keys := newKeyService(l)
+	h, err := NewHandler(l, svc, keys, &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+
+	req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(`{"name":"marketing"}`))
+	a.Nil(err)
+	ctx, rec := getCTX(req)
+	a.Nil(h.CreateAPIKey(ctx))
+	a.Equal(http.StatusCreated, rec.Code)
+	a.Equal("no-store", rec.Header().Get("Cache-Control"))
+	created := &v0.APIKeySecret{}
+	a.Nil(json.NewDecoder(rec.Body).Decode(created))
+	a.Equal("marketing", created.Key.Name)
+	a.False(created.Key.Admin)
+	p, err := keys.Authenticate(context.Background(), created.Secret)
+	a.Nil(err)
+	a.Equal(created.Key.Id, p.KeyID)
+
+	testCases := map[string]struct {
+		caller   *types.Principal
+		call     func(ctx echo.Context) error
+		validate func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error)
+	}{
+		"list keys": {
+			call: h.ListAPIKeys,
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				a.Equal(http.StatusOK, rec.Code)
+				list := &v0.APIKeyList{}
+				a.Nil(json.NewDecoder(rec.Body).Decode(list))
+				a.Len(list.Items, 1)
+				a.Equal(created.Key.Id, list.Items[0].Id)
+				a.NotContains(rec.Body.String(), created.Secret)
+			},
+		},
+		"invalid key name": {
+			call: func(ctx echo.Context) error {
+				ctx.Request().Body = io.NopCloser(strings.NewReader(`{"name":" "}`))
+				return h.CreateAPIKey(ctx)
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				a.Equal(http.StatusBadRequest, rec.Code)
+			},
+		},
+		"not an admin": {
+			caller: &types.Principal{KeyID: created.Key.Id},
+			call:   h.ListAPIKeys,
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				a.Equal(http.StatusForbidden, rec.Code)
+			},
+		},
+		"rotate unknown key": {
+			call: func(ctx echo.Context) error {
+				return h.RotateAPIKey(ctx, "unknown")
+			},
+			validate: func(a *assert.Assertions, rec *httptest.ResponseRecorder, err error) {
+				a.Nil(err)
+				a.Equal(http.StatusNotFound, rec.Code)
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			if testCase.caller != nil {
+				ctx, rec = getCTXAs(req, *testCase.caller)
+			}
+			testCase.validate(a, rec, testCase.call(ctx))
+		})
+	}
+
+	req, err = http.NewRequest(http.MethodPost, apiURL, nil)
+	a.Nil(err)
+	ctx, rec = getCTX(req)
+	a.Nil(h.RotateAPIKey(ctx, created.Key.Id))
+	a.Equal(http.StatusOK, rec.Code)
+	rotated := &v0.APIKeySecret{}
+	a.Nil(json.NewDecoder(rec.Body).Decode(rotated))
+	a.NotNil(rotated.Key.RotatedAt)
+	_, err = keys.Authenticate(context.Background(), created.Secret)
+	a.ErrorIs(err, types.ErrUnauthenticated)
+
+	ctx, rec = getCTX(req)
+	a.Nil(h.RevokeAPIKey(ctx, created.Key.Id))
+	a.Equal(http.StatusNoContent, rec.Code)
+	_, err = keys.Authenticate(context.Background(), rotated.Secret)
+	a.ErrorIs(err, types.ErrUnauthenticated)
+
+	ctx, rec = getCTX(req)
+	a.Nil(h.RevokeAPIKey(ctx, created.Key.Id))
+	a.Equal(http.StatusNotFound, rec.Code)
+}
+
+func TestAuthentication(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	s := &types.Server{Echo: echo.New()}
+	h.Register(s)
+	r.Data["f56Cd"] = types.URLDocument{URLKey: "f56Cd", LongURL: "https://foo.com", LiveForever: true}
+
+	testCases := map[string]struct {
+		method         string
+		path           string
+		apiKey         string
+		expectedStatus int
+	}{
+		"missing api key": {
+			method:         http.MethodGet,
+			path:           apiURL + "/urls",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"generate without api key": {
+			method:         http.MethodPost,
+			path:           apiURL + "/generate",
+			expectedStatus: http.StatusCreated,
+		},
+		"unknown api key": {
+			method:         http.MethodPost,
+			path:           apiURL + "/generate",
+			apiKey:         "tus_unknown",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"bootstrap api key": {
+			method:         http.MethodPost,
+			path:           apiURL + "/generate",
+			apiKey:         bootstrapKey,
+			expectedStatus: http.StatusCreated,
+		},
+		"admin endpoint without api key": {
+			method:         http.MethodGet,
+			path:           apiURL + "/admin/keys",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"redirect is public": {
+			method:         http.MethodGet,
+			path:           apiURL + "/f56Cd",
+			expectedStatus: http.StatusFound,
+		},
+		"info is public": {
+			method:         http.MethodGet,
+			path:           apiURL + "/f56Cd/info",
+			expectedStatus: http.StatusOK,
+		},
+		"info with api key": {
+			method:         http.MethodGet,
+			path:           apiURL + "/f56Cd/info",
+			apiKey:         bootstrapKey,
+			expectedStatus: http.StatusOK,
+		},
+		"info with unknown api key": {
+			method:         http.MethodGet,
+			path:           apiURL + "/f56Cd/info",
+			apiKey:         "tus_unknown",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var body io.Reader
+			if testCase.method == http.MethodPost {
+				body = strings.NewReader(`{"url":"https://bar.com"}`)
+			}
+			req := httptest.NewRequest(testCase.method, testCase.path, body)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if testCase.apiKey != "" {
+				req.Header.Set(types.APIKeyHeader, testCase.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			a.Equal(testCase.expectedStatus, rec.Code, rec.Body.String())
+		})
+	}
+
+	// tiny urls generated without an API key have no owner
+	req := httptest.NewRequest(http.MethodPost, apiURL+"/generate/batch",
+		strings.NewReader(`{"items":[{"url":"https://bar.com/batch"}]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusOK, rec.Code, rec.Body.String())
+	var owners []string
+	for _, doc := range r.Data {
+		if doc.LongURL == "https://bar.com/batch" {
+			owners = append(owners, doc.Owner)
+		}
+	}
+	a.Equal([]string{""}, owners)
+}
+
+func TestTenants(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{Tenants: []types.Tenant{
+		{ID: "brand-a", Domain: "brand-a.link"},
+		{ID: "brand-b", Domain: "Brand-B.link"},
+	}})
+	a.NotNil(h)
+	a.Nil(err)
+	s := &types.Server{Echo: echo.New()}
+	h.Register(s)
+
+	for host, longURL := range map[string]string{"brand-a.link": "https://a.io", "brand-b.link:8000": "https://b.io"} {
+		req := httptest.NewRequest(http.MethodPost, apiURL+"/generate",
+			strings.NewReader(`{"url":"`+longURL+`","alias":"sale"}`))
+		req.Host = host
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(types.APIKeyHeader, bootstrapKey)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		a.Equal(http.StatusCreated, rec.Code, rec.Body.String())
+		res := &v0.GenerateURLResponse{}
+		a.Nil(json.NewDecoder(rec.Body).Decode(res))
+		// tiny urls of tenants point to the configured domain rather than the host of the request
+		a.Equal("http://"+strings.Split(strings.ToLower(host), ":")[0]+"/sale", res.GeneratedTinyURL)
+	}
+
+	testCases := map[string]struct {
+		host             string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		"root of brand-a": {
+			host:             "brand-a.link",
+			path:             "/sale",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://a.io",
+		},
+		"root of brand-b": {
+			host:             "BRAND-B.link",
+			path:             "/sale",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://b.io",
+		},
+		"api path of brand-a": {
+			host:             "brand-a.link",
+			path:             apiURL + "/sale",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://a.io",
+		},
+		"unknown key of brand-a": {
+			host:           "brand-a.link",
+			path:           "/other",
+			expectedStatus: http.StatusNotFound,
+		},
+		"default tenant": {
+			host:           "example.com",
+			path:           apiURL + "/sale",
+			expectedStatus: http.StatusNotFound,
+		},
+		"root of default tenant": {
+			host:           "example.com",
+			path:           "/sale",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+			req.Host = testCase.host
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			a.Equal(testCase.expectedStatus, rec.Code, rec.Body.String())
+			a.Equal(testCase.expectedLocation, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	schema := &recordingSchema{OpenAPISchema: h.(*handler).schema}
+	h.(*handler).schema = schema
+	s := &types.Server{Echo: echo.New()}
+	h.Register(s)
+
+	for alias, body := range map[string]string{
+		"docs":     `{"url":"https://foo.com/docs?lang=en","passthrough":true}`,
+		"shop":     `{"url":"https://shop.example/{path}?ref={query.ref}&all={query}","passthrough":true}`,
+		"plain":    `{"url":"https://foo.com/plain"}`,
+		"locked":   `{"url":"https://foo.com/locked","passthrough":true,"password":"s3cret"}`,
+		"bad-host": `{"url":"https://{path}.example/","passthrough":true}`,
+		"unknown":  `{"url":"https://foo.com/{other}","passthrough":true}`,
+	} {
+		body = strings.Replace(body, "{", `{"alias":"`+alias+`",`, 1)
+		req := httptest.NewRequest(http.MethodPost, apiURL+"/generate", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(types.APIKeyHeader, bootstrapKey)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if alias == "bad-host" || alias == "unknown" {
+			a.Equal(http.StatusBadRequest, rec.Code, rec.Body.String())
+			continue
+		}
+		a.Equal(http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	testCases := map[string]struct {
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		"path and query appended": {
+			path:             apiURL + "/docs/guide/intro?utm_source=x&lang=fr",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://foo.com/docs/guide/intro?lang=en&utm_source=x",
+		},
+		"dot segments dropped": {
+			path:             apiURL + "/docs/../../admin",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://foo.com/docs/admin?lang=en",
+		},
+		"escaped path": {
+			path:             apiURL + "/docs/a%20b/c%3Fd",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://foo.com/docs/a%20b/c%3Fd?lang=en",
+		},
+		"template": {
+			path:             apiURL + "/shop/shoes/red?ref=a%26b%3Dc",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://shop.example/shoes/red?ref=a%26b%3Dc&all=ref=a%26b%3Dc",
+		},
+		"template without extras": {
+			path:             apiURL + "/shop",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://shop.example/?ref=&all=",
+		},
+		"query dropped without passthrough": {
+			path:             apiURL + "/plain?utm_source=x",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://foo.com/plain",
+		},
+		"path without passthrough": {
+			path:           apiURL + "/plain/extra",
+			expectedStatus: http.StatusNotFound,
+		},
+		"other operations keep their routes": {
+			path:           apiURL + "/plain/info",
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			a.Equal(testCase.expectedStatus, rec.Code, rec.Body.String())
+			a.Equal(testCase.expectedLocation, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+
+	// the password form passes the path and the query on to the unlock request
+	req := httptest.NewRequest(http.MethodGet, apiURL+"/locked/a/b?x=1", nil)
+	req.Header.Set(echo.HeaderAccept, echo.MIMETextHTML)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusUnauthorized, rec.Code)
+	a.Contains(rec.Body.String(), `action="/tinyurlsvc/locked/unlock?x=1"`)
+	a.Contains(rec.Body.String(), `name="path" value="a/b"`)
+	req = httptest.NewRequest(http.MethodPost, apiURL+"/locked/unlock?x=1",
+		strings.NewReader("password=s3cret&path=a%2Fb"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusSeeOther, rec.Code, rec.Body.String())
+	a.Equal("https://foo.com/locked/a/b?x=1", rec.Header().Get(echo.HeaderLocation))
+
+	// visits with a trailing path are authenticated and validated as GetURL of their key
+	schema.paths = nil
+	req = httptest.NewRequest(http.MethodGet, apiURL+"/docs/guide", nil)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusFound, rec.Code)
+	a.Equal("https://foo.com/docs/guide?lang=en", rec.Header().Get(echo.HeaderLocation))
+	a.Equal([]string{"authenticate " + apiURL + "/docs", "validate " + apiURL + "/docs"}, schema.paths)
+}
+
+// recordingSchema records the paths of the requests going through its middleware
+type recordingSchema struct {
+	types.OpenAPISchema
+	paths []string
+}
+
+func (rs *recordingSchema) AuthenticationMiddleware(auth types.Authenticator) types.MiddlewareFunc {
+	return rs.record("authenticate", rs.OpenAPISchema.AuthenticationMiddleware(auth))
+}
+
+func (rs *recordingSchema) ValidationMiddleware() types.MiddlewareFunc {
+	return rs.record("validate", rs.OpenAPISchema.ValidationMiddleware())
+}
+
+func (rs *recordingSchema) record(step string, middleware types.MiddlewareFunc) types.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			rs.paths = append(rs.paths, step+" "+ctx.Request().URL.Path)
+			return middleware(next)(ctx)
+		}
+	}
+}
+
+func TestTargeting(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{},
+		types.HandlerConfig{CountryHeader: "CF-IPCountry"})
+	a.NotNil(h)
+	a.Nil(err)
+	s := &types.Server{Echo: echo.New()}
+	h.Register(s)
+
+	body := `{"alias":"app","url":"https://foo.com","rules":[` +
+		`{"platforms":["ios"],"destination":"https://apps.apple.com/app/id1"},` +
+		`{"platforms":["android"],"destination":"https://play.google.com/store/apps/details?id=foo"},` +
+		`{"languages":["de"],"countries":["de","at"],"destination":"https://foo.com/de"}]}`
+	req := httptest.NewRequest(http.MethodPost, apiURL+"/generate", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(types.APIKeyHeader, bootstrapKey)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusCreated, rec.Code, rec.Body.String())
+
+	testCases := map[string]struct {
+		headers          map[string]string
+		expectedLocation string
+	}{
+		"iphone": {
+			headers:          map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"},
+			expectedLocation: "https://apps.apple.com/app/id1",
+		},
+		"android": {
+			headers:          map[string]string{"User-Agent": "Mozilla/5.0 (Linux; Android 14; Pixel 8)"},
+			expectedLocation: "https://play.google.com/store/apps/details?id=foo",
+		},
+		"language and country": {
+			headers: map[string]string{
+				"User-Agent":      "Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
+				"Accept-Language": "en;q=0.5, de-AT;q=0.9",
+				"CF-IPCountry":    "at",
+			},
+			expectedLocation: "https://foo.com/de",
+		},
+		"language of another country": {
+			headers:          map[string]string{"Accept-Language": "de-CH", "CF-IPCountry": "CH"},
+			expectedLocation: "https://foo.com",
+		},
+		"no rule matches": {
+			headers:          map[string]string{"User-Agent": "curl/8.5.0"},
+			expectedLocation: "https://foo.com",
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, apiURL+"/app", nil)
+			for k, v := range testCase.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			a.Equal(http.StatusFound, rec.Code, rec.Body.String())
+			a.Equal(testCase.expectedLocation, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+
+	req = httptest.NewRequest(http.MethodGet, apiURL+"/app/info", nil)
+	req.Header.Set(types.APIKeyHeader, bootstrapKey)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusOK, rec.Code, rec.Body.String())
+	info := new(v0.URLInfo)
+	a.Nil(json.Unmarshal(rec.Body.Bytes(), info))
+	a.NotNil(info.Rules)
+	a.Len(*info.Rules, 3)
+	a.Equal([]string{"DE", "AT"}, *(*info.Rules)[2].Countries)
+}
+
+func TestVariants(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	s := &types.Server{Echo: echo.New()}
+	h.Register(s)
+
+	body := `{"alias":"split","url":"https://foo.com","variants":[` +
+		`{"url":"https://foo.com/a","weight":70},{"url":"https://foo.com/b","weight":30}]}`
+	req := httptest.NewRequest(http.MethodPost, apiURL+"/generate", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(types.APIKeyHeader, bootstrapKey)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusCreated, rec.Code, rec.Body.String())
+
+	// the first visit sets the visitor cookie, which keeps the variant when the IP changes
+	req = httptest.NewRequest(http.MethodGet, apiURL+"/split", nil)
+	req.RemoteAddr = "203.0.113.7:4711"
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(http.StatusFound, rec.Code, rec.Body.String())
+	location := rec.Header().Get(echo.HeaderLocation)
+	a.Contains([]string{"https://foo.com/a", "https://foo.com/b"}, location)
+	cookies := rec.Result().Cookies()
+	a.Len(cookies, 1)
+	a.Equal(visitorCookie, cookies[0].Name)
+	a.True(cookies[0].HttpOnly)
+	for i := 0; i < 5; i++ {
+		req = httptest.NewRequest(http.MethodGet, apiURL+"/split", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:4711", i)
+		req.AddCookie(cookies[0])
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		a.Equal(http.StatusFound, rec.Code, rec.Body.String())
+		a.Equal(location, rec.Header().Get(echo.HeaderLocation))
+		a.Empty(rec.Result().Cookies())
+	}
+	// visitors without cookies keep their variant through their IP
+	req = httptest.NewRequest(http.MethodGet, apiURL+"/split", nil)
+	req.RemoteAddr = "203.0.113.7:4712"
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	a.Equal(location, rec.Header().Get(echo.HeaderLocation))
+
+	testCases := map[string]struct {
+		apiKey         string
+		expectedStatus int
+	}{
+		"without api key": {
+			expectedStatus: http.StatusUnauthorized,
+		},
+		"owner": {
+			apiKey:         bootstrapKey,
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, apiURL+"/split/stats", nil)
+			if testCase.apiKey != "" {
+				req.Header.Set(types.APIKeyHeader, testCase.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			a.Equal(testCase.expectedStatus, rec.Code, rec.Body.String())
+			if testCase.expectedStatus != http.StatusOK {
+				return
+			}
+			stats := new(v0.URLStats)
+			a.Nil(json.Unmarshal(rec.Body.Bytes(), stats))
+			a.Equal(int64(7), stats.Clicks)
+			a.NotNil(stats.Variants)
+			for _, v := range *stats.Variants {
+				if v.Url == location {
+					a.Equal(int64(7), v.Clicks)
+				} else {
+					a.Zero(v.Clicks)
+				}
+			}
+		})
+	}
+}
+
+func TestGetURLQR(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	tinyURL, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{LongURL: "https://foo.com"})
+	a.Nil(err)
+	protected, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL:  "https://foo.com/protected",
+		Password: "s3cret",
+	})
+	a.Nil(err)
+	suspended, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{
+		LongURL: "https://foo.com/suspended",
+	})
+	a.Nil(err)
+	_, err = svc.SuspendTinyURL(context.Background(), suspended.URLKey, "", admin)
+	a.Nil(err)
+	r.Data["Ex9Pd"] = types.URLDocument{URLKey: "Ex9Pd", LongURL: "https://foo.com", ExpireTime: time.Now()}
+	svgFormat := v0.Svg
+
+	testCases := map[string]struct {
+		urlKey              string
+		params              v0.GetURLQRParams
+		expectedStatus      int
+		expectedContentType string
+	}{
+		"png by default": {
+			urlKey:              tinyURL.URLKey,
+			params:              v0.GetURLQRParams{Size: intPtr(128)},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "image/png",
+		},
+		"svg": {
+			urlKey:              tinyURL.URLKey,
+			params:              v0.GetURLQRParams{Format: &svgFormat},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "image/svg+xml",
+		},
+		"password protected": {
+			urlKey:              protected.URLKey,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "image/png",
+		},
+		"margin out of range": {
+			urlKey:         tinyURL.URLKey,
+			params:         v0.GetURLQRParams{Margin: intPtr(17)},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"tiny url not found": {
+			urlKey:         "6hgtEs",
+			expectedStatus: http.StatusNotFound,
+		},
+		"expired": {
+			urlKey:         "Ex9Pd",
+			expectedStatus: http.StatusGone,
+		},
+		"suspended": {
+			urlKey:         suspended.URLKey,
+			expectedStatus: http.StatusGone,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			a.Nil(h.GetURLQR(ctx, testCase.urlKey, testCase.params))
+			res := rec.Result()
+			defer res.Body.Close()
+			a.Equal(testCase.expectedStatus, res.StatusCode)
+			if testCase.expectedStatus != http.StatusOK {
+				return
+			}
+			a.Equal(testCase.expectedContentType, res.Header.Get(echo.HeaderContentType))
+			if testCase.expectedContentType == "image/png" {
+				img, err := png.Decode(res.Body)
+				a.Nil(err)
+				size := 256
+				if testCase.params.Size != nil {
+					size = *testCase.params.Size
+				}
+				a.Equal(size, img.Bounds().Dx())
+			}
+		})
+	}
+}
+
+func TestExportQRCodes(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := url.NewTinyURLService(l, r, c, url.NewRandomKeyGenerator(), newClickCounter(r, c), url.DefaultConfig())
+	h, err := NewHandler(l, svc, newKeyService(l), &types.MockClickRecorder{}, types.HandlerConfig{})
+	a.NotNil(h)
+	a.Nil(err)
+	first, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{LongURL: "https://foo.com/a"})
+	a.Nil(err)
+	second, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{LongURL: "https://foo.com/b"})
+	a.Nil(err)
+	suspended, _, err := svc.GenerateTinyURL(context.Background(), types.GenerateRequest{LongURL: "https://foo.com/c"})
+	a.Nil(err)
+	_, err = svc.SuspendTinyURL(context.Background(), suspended.URLKey, "", admin)
+	a.Nil(err)
+	r.Data["Ex9Pd"] = types.URLDocument{URLKey: "Ex9Pd", LongURL: "https://foo.com", ExpireTime: time.Now()}
+	svgFormat := v0.Svg
+
+	testCases := map[string]struct {
+		req             v0.QRBatchRequest
+		expectedStatus  int
+		expectedEntries []string
+	}{
+		"png codes": {
+			req:             v0.QRBatchRequest{Keys: []string{first.URLKey, second.URLKey}},
+			expectedStatus:  http.StatusOK,
+			expectedEntries: []string{first.URLKey + ".png", second.URLKey + ".png"},
+		},
+		"svg codes": {
+			req:             v0.QRBatchRequest{Keys: []string{first.URLKey}, Format: &svgFormat},
+			expectedStatus:  http.StatusOK,
+			expectedEntries: []string{first.URLKey + ".svg"},
+		},
+		"invalid options": {
+			req:            v0.QRBatchRequest{Keys: []string{first.URLKey}, Size: intPtr(4096)},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown key": {
+			req:            v0.QRBatchRequest{Keys: []string{first.URLKey, "6hgtEs"}},
+			expectedStatus: http.StatusNotFound,
+		},
+		"expired key": {
+			req:            v0.QRBatchRequest{Keys: []string{first.URLKey, "Ex9Pd"}},
+			expectedStatus: http.StatusGone,
+		},
+		"suspended key": {
+			req:            v0.QRBatchRequest{Keys: []string{suspended.URLKey, second.URLKey}},
+			expectedStatus: http.StatusGone,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			body, err := json.Marshal(testCase.req)
+			a.Nil(err)
+			req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(body))
+			a.Nil(err)
+
+			ctx, rec := getCTX(req)
+			a.Nil(h.ExportQRCodes(ctx))
+			a.Equal(testCase.expectedStatus, rec.Code)
+			if testCase.expectedStatus != http.StatusOK {
+				return
+			}
+			a.Equal("application/zip", rec.Header().Get(echo.HeaderContentType))
+			a.Contains(rec.Header().Get(echo.HeaderContentDisposition), "qr-codes.zip")
+			archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+			a.Nil(err)
+			var entries []string
+			for _, f := range archive.File {
+				entries = append(entries, f.Name)
+			}
+			a.Equal(testCase.expectedEntries, entries)
+		})
+	}
+}
+
+const bootstrapKey = "bootstrap-secret"
+
+var admin = types.Principal{KeyID: "admin", Admin: true}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func newKeyService(l *zap.Logger) types.KeyService {
+	return auth.NewKeyService(l, &types.MockKeyRepo{Data: make(map[string]types.APIKey)}, bootstrapKey)
+}
+
+// newClickCounter returns a click counter adding the clicks it counts in the cache to the repo
+func newClickCounter(r types.URLRepo, c types.CacheService) types.ClickCounter {
+	return clicks.NewCounter(zap.NewNop(), r, c, clicks.DefaultCounterConfig())
+}
+
+// getCTX returns the context of a request authenticated as an admin
+func getCTX(r *http.Request) (echo.Context, *httptest.ResponseRecorder) {
+	return getCTXAs(r, admin)
+}
+
+func getCTXAs(r *http.Request, p types.Principal) (echo.Context, *httptest.ResponseRecorder) {
+	r = r.WithContext(types.WithPrincipal(r.Context(), p))
 	s := echo.New()
 	rec := httptest.NewRecorder()
 	ctx := s.NewContext(r, rec)
@@ -0,0 +1,131 @@
+package rest_v0
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+	v0 "github.com/vaishakdinesh/tiny-url-svc/types/api/rest/v0"
+)
+
+// ListAPIKeys Lists API keys
+// (GET /tinyurlsvc/admin/keys)
+func (h *handler) ListAPIKeys(ctx echo.Context) error {
+	if !principal(ctx).Admin {
+		return forbidden(ctx, types.ErrForbidden)
+	}
+	keys, err := h.keys.ListKeys(ctx.Request().Context())
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+			Code:    types.InternalServerError,
+			Message: err.Error(),
+		})
+	}
+	list := &v0.APIKeyList{Items: make([]v0.APIKey, len(keys))}
+	for i, key := range keys {
+		list.Items[i] = toAPIKey(key)
+	}
+	return ctx.JSON(http.StatusOK, list)
+}
+
+// CreateAPIKey Creates an API key
+// (POST /tinyurlsvc/admin/keys)
+func (h *handler) CreateAPIKey(ctx echo.Context) error {
+	if !principal(ctx).Admin {
+		return forbidden(ctx, types.ErrForbidden)
+	}
+	createReq := new(v0.CreateAPIKeyRequest)
+	if err := json.NewDecoder(ctx.Request().Body).Decode(createReq); err != nil {
+		return ctx.JSON(http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		})
+	}
+	admin := createReq.Admin != nil && *createReq.Admin
+	key, secret, err := h.keys.CreateKey(ctx.Request().Context(), createReq.Name, admin)
+	if err != nil {
+		if errors.Is(err, types.ErrInvalidKeyName) {
+			return ctx.JSON(http.StatusBadRequest, &types.APIError{
+				Code:    types.InputError,
+				Message: err.Error(),
+			})
+		}
+		return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+			Code:    types.InternalServerError,
+			Message: err.Error(),
+		})
+	}
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	return ctx.JSON(http.StatusCreated, &v0.APIKeySecret{Key: toAPIKey(key), Secret: secret})
+}
+
+// RevokeAPIKey Revokes an API key
+// (DELETE /tinyurlsvc/admin/keys/{keyID})
+func (h *handler) RevokeAPIKey(ctx echo.Context, keyID v0.KeyID) error {
+	if !principal(ctx).Admin {
+		return forbidden(ctx, types.ErrForbidden)
+	}
+	if err := h.keys.RevokeKey(ctx.Request().Context(), keyID); err != nil {
+		return keyError(ctx, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// RotateAPIKey Rotates an API key
+// (POST /tinyurlsvc/admin/keys/{keyID}/rotate)
+func (h *handler) RotateAPIKey(ctx echo.Context, keyID v0.KeyID) error {
+	if !principal(ctx).Admin {
+		return forbidden(ctx, types.ErrForbidden)
+	}
+	key, secret, err := h.keys.RotateKey(ctx.Request().Context(), keyID)
+	if err != nil {
+		return keyError(ctx, err)
+	}
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	return ctx.JSON(http.StatusOK, &v0.APIKeySecret{Key: toAPIKey(key), Secret: secret})
+}
+
+func keyError(ctx echo.Context, err error) error {
+	if errors.Is(err, types.ErrDocumentNotFound) {
+		return ctx.JSON(http.StatusNotFound, &types.APIError{
+			Code:    types.NotFoundError,
+			Message: err.Error(),
+		})
+	}
+	return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+		Code:    types.InternalServerError,
+		Message: err.Error(),
+	})
+}
+
+func toAPIKey(key types.APIKey) v0.APIKey {
+	apiKey := v0.APIKey{
+		Id:        key.ID,
+		Name:      key.Name,
+		Admin:     key.Admin,
+		CreatedAt: key.CreatedAt.UTC(),
+	}
+	if !key.RotatedAt.IsZero() {
+		apiKey.RotatedAt = timePtr(key.RotatedAt.UTC())
+	}
+	if !key.RevokedAt.IsZero() {
+		apiKey.RevokedAt = timePtr(key.RevokedAt.UTC())
+	}
+	return apiKey
+}
+
+// principal returns the caller authenticated by the authentication middleware
+func principal(ctx echo.Context) types.Principal {
+	p, _ := types.PrincipalFromContext(ctx.Request().Context())
+	return p
+}
+
+func forbidden(ctx echo.Context, err error) error {
+	return ctx.JSON(http.StatusForbidden, &types.APIError{
+		Code:    types.ForbiddenError,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,137 @@
+package rest_v0
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// passwordPage is served to browsers following a password protected tiny url
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post" action="{{.Action}}">
+<p>This link is password protected.</p>
+{{if .Wrong}}<p role="alert">Wrong password, try again.</p>{{end}}
+{{if .Path}}<input type="hidden" name="path" value="{{.Path}}">{{end}}
+<label>Password <input type="password" name="password" autocomplete="current-password" required autofocus></label>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// interstitialPage is served to browsers following a tiny url with an interstitial
+var interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Leaving for {{.Host}}</title>
+</head>
+<body>
+<p>This link takes you to <strong>{{.Host}}</strong>:</p>
+<p><code>{{.Destination}}</code></p>
+<p><a href="{{.Destination}}" rel="noopener">Continue to {{.Host}}</a></p>
+</body>
+</html>
+`))
+
+// defaultHoldingPage is served to browsers visiting a tiny url before its activation window opens
+const defaultHoldingPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Coming soon</title>
+</head>
+<body>
+<p>This link is not active yet.</p>
+{{if not .ActiveFrom.IsZero}}<p>Come back after
+<time datetime="{{.ActiveFrom.Format "2006-01-02T15:04:05Z07:00"}}">{{.ActiveFrom.Format "Jan 2, 2006 15:04 MST"}}</time>.
+</p>{{end}}
+</body>
+</html>
+`
+
+// parseHoldingPage parses the holding page template, falling back to the default page when it is empty
+func parseHoldingPage(page string) (*template.Template, error) {
+	if page == "" {
+		page = defaultHoldingPage
+	}
+	return template.New("holding").Parse(page)
+}
+
+// renderHoldingPage responds with the holding page of a tiny url that becomes active at activeFrom
+func renderHoldingPage(ctx echo.Context, page *template.Template, activeFrom time.Time) error {
+	buf := new(bytes.Buffer)
+	err := page.Execute(buf, struct {
+		ActiveFrom time.Time
+	}{
+		ActiveFrom: activeFrom.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.HTMLBlob(http.StatusServiceUnavailable, buf.Bytes())
+}
+
+// renderInterstitialPage responds with the interstitial page of a tiny url pointing to destination
+func renderInterstitialPage(ctx echo.Context, destination string) error {
+	buf := new(bytes.Buffer)
+	err := interstitialPage.Execute(buf, struct {
+		Host        string
+		Destination string
+	}{
+		Host:        types.URLDomain(destination),
+		Destination: destination,
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.HTMLBlob(http.StatusOK, buf.Bytes())
+}
+
+// renderPasswordPage responds with the password form of the tiny url. The form passes the trailing path and the
+// query of the visit on to the unlock request.
+func renderPasswordPage(ctx echo.Context, urlKey, path string, wrong bool) error {
+	action := apiURL + "/" + url.PathEscape(urlKey) + "/unlock"
+	if query := ctx.QueryString(); query != "" {
+		action += "?" + query
+	}
+	buf := new(bytes.Buffer)
+	err := passwordPage.Execute(buf, struct {
+		Action string
+		Path   string
+		Wrong  bool
+	}{
+		Action: action,
+		Path:   path,
+		Wrong:  wrong,
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Response().Header().Set("Cache-Control", "no-store")
+	return ctx.HTMLBlob(http.StatusUnauthorized, buf.Bytes())
+}
+
+// acceptsHTML reports whether the client asked for an HTML response, which browsers do for page navigations
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get(echo.HeaderAccept), echo.MIMETextHTML)
+}
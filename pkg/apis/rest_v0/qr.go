@@ -0,0 +1,123 @@
+package rest_v0
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/qr"
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+	v0 "github.com/vaishakdinesh/tiny-url-svc/types/api/rest/v0"
+)
+
+// GetURLQR Returns the QR code of a tiny url
+// (GET /tinyurlsvc/{urlKey}/qr)
+func (h *handler) GetURLQR(ctx echo.Context, urlKey string, params v0.GetURLQRParams) error {
+	tinyURL, err := h.shortURL(ctx, urlKey)
+	if err != nil {
+		return qrError(ctx, err)
+	}
+	img, err := qr.Render(tinyURL, toQROptions(params.Format, params.Size, params.Margin, params.Level,
+		params.Foreground, params.Background))
+	if err != nil {
+		return qrError(ctx, err)
+	}
+	return ctx.Blob(http.StatusOK, img.ContentType, img.Data)
+}
+
+// ExportQRCodes Exports the QR codes of tiny urls
+// (POST /tinyurlsvc/qr/batch)
+func (h *handler) ExportQRCodes(ctx echo.Context) error {
+	batchReq := new(v0.QRBatchRequest)
+	if err := json.NewDecoder(ctx.Request().Body).Decode(batchReq); err != nil {
+		return ctx.JSON(http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		})
+	}
+	opts := toQROptions(batchReq.Format, batchReq.Size, batchReq.Margin, batchReq.Level, batchReq.Foreground,
+		batchReq.Background)
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for _, urlKey := range batchReq.Keys {
+		tinyURL, err := h.shortURL(ctx, urlKey)
+		if err != nil {
+			return qrError(ctx, fmt.Errorf("%w: %s", err, urlKey))
+		}
+		img, err := qr.Render(tinyURL, opts)
+		if err != nil {
+			return qrError(ctx, err)
+		}
+		w, err := archive.Create(urlKey + "." + img.Extension)
+		if err == nil {
+			_, err = w.Write(img.Data)
+		}
+		if err != nil {
+			return qrError(ctx, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return qrError(ctx, err)
+	}
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="qr-codes.zip"`)
+	return ctx.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// shortURL returns the tiny url of the key once it is known to exist. A QR code only carries the tiny url, so
+// password protected tiny urls do not need the password.
+func (h *handler) shortURL(ctx echo.Context, urlKey string) (string, error) {
+	_, err := h.svc.GetTinyURLInfo(ctx.Request().Context(), urlKey, "")
+	if err != nil && !errors.Is(err, types.ErrPasswordRequired) {
+		return "", err
+	}
+	tinyURL := types.URLDocument{Tenant: types.TenantFromContext(ctx.Request().Context()), URLKey: urlKey}
+	return h.tinyURL(ctx, tinyURL), nil
+}
+
+func toQROptions(format *v0.QRFormat, size, margin *int, level *v0.QRLevel, foreground,
+	background *string) qr.Options {
+	opts := qr.Options{Margin: margin}
+	if format != nil {
+		opts.Format = qr.Format(*format)
+	}
+	if size != nil {
+		opts.Size = *size
+	}
+	if level != nil {
+		opts.Level = string(*level)
+	}
+	if foreground != nil {
+		opts.Foreground = *foreground
+	}
+	if background != nil {
+		opts.Background = *background
+	}
+	return opts
+}
+
+func qrError(ctx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, types.ErrDocumentNotFound):
+		return ctx.JSON(http.StatusNotFound, &types.APIError{
+			Code:    types.NotFoundError,
+			Message: err.Error(),
+		})
+	case errors.Is(err, types.ErrLinkExpired), errors.Is(err, types.ErrLinkSuspended):
+		return gone(ctx, err)
+	case errors.Is(err, types.ErrInvalidQROptions):
+		return ctx.JSON(http.StatusBadRequest, &types.APIError{
+			Code:    types.InputError,
+			Message: err.Error(),
+		})
+	default:
+		return ctx.JSON(http.StatusInternalServerError, &types.APIError{
+			Code:    types.InternalServerError,
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package rest_v0
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+	v0 "github.com/vaishakdinesh/tiny-url-svc/types/api/rest/v0"
+)
+
+const (
+	// visitorCookie holds the id of the visitor, which keeps the variants of split tiny urls it was assigned to
+	visitorCookie       = "tus_vid"
+	visitorCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+var visitorIDFormat = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// newVisit describes the client of a visit for targeting rules and variants
+func (h *handler) newVisit(ctx echo.Context, password, path string) types.Visit {
+	r := ctx.Request()
+	visit := types.Visit{
+		Password:  password,
+		Path:      path,
+		Platform:  clientPlatform(r.UserAgent()),
+		Language:  preferredLanguage(r.Header.Get("Accept-Language")),
+		VisitorID: h.visitorID(ctx),
+	}
+	if h.countryHeader != "" {
+		visit.Country = strings.ToUpper(strings.TrimSpace(r.Header.Get(h.countryHeader)))
+	}
+	return visit
+}
+
+// visitorID returns the id of the visitor from its cookie. Visitors without the cookie are identified by a hash of
+// their IP, which the cookie keeps once they visit a split tiny url.
+func (h *handler) visitorID(ctx echo.Context) string {
+	if c, err := ctx.Cookie(visitorCookie); err == nil && visitorIDFormat.MatchString(c.Value) {
+		return c.Value
+	}
+	return h.hashIP(ctx.RealIP())
+}
+
+// hashIP returns a hash of the IP of a client, so that clients can be told apart without keeping their IP. The hash is
+// keyed, as the few IPv4 addresses could all be hashed to find the IP of a plain hash.
+func (h *handler) hashIP(ip string) string {
+	mac := hmac.New(sha256.New, h.ipHashKey)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// recordClick records the click event of a visit of the tiny url, whose long url is the destination of the visit
+func (h *handler) recordClick(ctx echo.Context, urlDoc types.URLDocument) {
+	r := ctx.Request()
+	h.clicks.Record(types.ClickEvent{
+		Tenant:      urlDoc.Tenant,
+		URLKey:      urlDoc.URLKey,
+		Time:        time.Now(),
+		Referrer:    r.Referer(),
+		UserAgent:   r.UserAgent(),
+		IPHash:      h.hashIP(ctx.RealIP()),
+		Destination: urlDoc.LongURL,
+	})
+}
+
+// keepVisitor sets the visitor cookie on the visit of a split tiny url by a visitor without one, so that the visitor
+// keeps its variant when its IP changes
+func keepVisitor(ctx echo.Context, urlDoc types.URLDocument, visit types.Visit) {
+	if len(urlDoc.Variants) == 0 {
+		return
+	}
+	if c, err := ctx.Cookie(visitorCookie); err == nil && c.Value == visit.VisitorID {
+		return
+	}
+	ctx.SetCookie(&http.Cookie{
+		Name:     visitorCookie,
+		Value:    visit.VisitorID,
+		Path:     "/",
+		MaxAge:   visitorCookieMaxAge,
+		Secure:   ctx.Request().TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clientPlatform returns the platform of the client from its User-Agent, or an empty string when it is unknown. iPads
+// asking for desktop sites are seen as macOS.
+func clientPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return types.PlatformIOS
+	case strings.Contains(ua, "android"):
+		return types.PlatformAndroid
+	case strings.Contains(ua, "windows"):
+		return types.PlatformWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return types.PlatformMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"), strings.Contains(ua, "cros"):
+		return types.PlatformLinux
+	default:
+		return ""
+	}
+}
+
+// preferredLanguage returns the language tag of the Accept-Language header with the highest quality, the first one on
+// ties, or an empty string when there is none
+func preferredLanguage(acceptLanguage string) string {
+	var preferred string
+	var preferredQ float64
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.TrimSpace(tag)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if tag == "" || tag == "*" || q <= preferredQ {
+			continue
+		}
+		preferred, preferredQ = tag, q
+	}
+	return strings.ToLower(preferred)
+}
+
+func toTargetingRules(rules v0.TargetingRules) []types.TargetingRule {
+	converted := make([]types.TargetingRule, len(rules))
+	for i, rule := range rules {
+		converted[i].Destination = rule.Destination
+		if rule.Platforms != nil {
+			for _, p := range *rule.Platforms {
+				converted[i].Platforms = append(converted[i].Platforms, string(p))
+			}
+		}
+		if rule.Languages != nil {
+			converted[i].Languages = *rule.Languages
+		}
+		if rule.Countries != nil {
+			converted[i].Countries = *rule.Countries
+		}
+	}
+	return converted
+}
+
+func fromTargetingRules(rules []types.TargetingRule) v0.TargetingRules {
+	converted := make(v0.TargetingRules, len(rules))
+	for i, rule := range rules {
+		converted[i].Destination = rule.Destination
+		if len(rule.Platforms) > 0 {
+			platforms := make([]v0.TargetingRulePlatforms, len(rule.Platforms))
+			for j, p := range rule.Platforms {
+				platforms[j] = v0.TargetingRulePlatforms(p)
+			}
+			converted[i].Platforms = &platforms
+		}
+		if len(rule.Languages) > 0 {
+			converted[i].Languages = &rule.Languages
+		}
+		if len(rule.Countries) > 0 {
+			converted[i].Countries = &rule.Countries
+		}
+	}
+	return converted
+}
+
+func toVariants(variants v0.Variants) []types.Variant {
+	converted := make([]types.Variant, len(variants))
+	for i, v := range variants {
+		converted[i] = types.Variant{URL: v.Url, Weight: v.Weight}
+	}
+	return converted
+}
+
+func fromVariants(variants []types.Variant) v0.Variants {
+	converted := make(v0.Variants, len(variants))
+	for i, v := range variants {
+		converted[i] = v0.Variant{Url: v.URL, Weight: v.Weight}
+	}
+	return converted
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	// secretPrefix makes API keys recognizable, for example by secret scanners
+	secretPrefix = "tus_"
+	secretBytes  = 32
+	keyIDBytes   = 8
+	maxNameLen   = 64
+	// BootstrapKeyID is the principal of the bootstrap admin key
+	BootstrapKeyID = "bootstrap"
+)
+
+type keySVC struct {
+	l    *zap.Logger
+	repo types.APIKeyRepo
+	// bootstrapHash is the hash of the admin key configured through the environment, used to create the first keys
+	bootstrapHash []byte
+}
+
+// NewKeyService returns the API key service. bootstrapKey is an optional admin key that is not stored in the db, it
+// lets the first keys be created.
+func NewKeyService(l *zap.Logger, r types.APIKeyRepo, bootstrapKey string) types.KeyService {
+	svc := &keySVC{l: l, repo: r}
+	if bootstrapKey != "" {
+		sum := sha256.Sum256([]byte(bootstrapKey))
+		svc.bootstrapHash = sum[:]
+	}
+	return svc
+}
+
+// Authenticate looks the API key up by the hash of its secret. Secrets are random, so an unsalted hash is enough to
+// keep them from being read back from the db.
+func (k *keySVC) Authenticate(ctx context.Context, apiKey string) (types.Principal, error) {
+	if apiKey == "" {
+		return types.Principal{}, types.ErrUnauthenticated
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	if k.bootstrapHash != nil && subtle.ConstantTimeCompare(sum[:], k.bootstrapHash) == 1 {
+		return types.Principal{KeyID: BootstrapKeyID, Admin: true}, nil
+	}
+	if !strings.HasPrefix(apiKey, secretPrefix) {
+		return types.Principal{}, types.ErrUnauthenticated
+	}
+	key, err := k.repo.GetByHash(ctx, hex.EncodeToString(sum[:]))
+	if errors.Is(err, types.ErrDocumentNotFound) {
+		return types.Principal{}, types.ErrUnauthenticated
+	}
+	if err != nil {
+		k.l.Error("failed to look up api key", zap.Error(err))
+		return types.Principal{}, err
+	}
+	if !key.RevokedAt.IsZero() {
+		return types.Principal{}, types.ErrUnauthenticated
+	}
+	return types.Principal{KeyID: key.ID, Admin: key.Admin}, nil
+}
+
+// CreateKey stores a new API key and returns it along with its secret
+func (k *keySVC) CreateKey(ctx context.Context, name string, admin bool) (types.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameLen {
+		return types.APIKey{}, "", types.ErrInvalidKeyName
+	}
+	id, err := randomString(keyIDBytes, hex.EncodeToString)
+	if err != nil {
+		return types.APIKey{}, "", err
+	}
+	secret, hash, err := newSecret()
+	if err != nil {
+		return types.APIKey{}, "", err
+	}
+	key := types.APIKey{
+		ID:        id,
+		Name:      name,
+		Admin:     admin,
+		Hash:      hash,
+		CreatedAt: time.Now(),
+	}
+	if err = k.repo.Put(ctx, key); err != nil {
+		k.l.Error("failed to store api key", zap.Error(err), zap.String("key-id", id))
+		return types.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// ListKeys returns every key, including rotated and revoked ones
+func (k *keySVC) ListKeys(ctx context.Context) ([]types.APIKey, error) {
+	keys, err := k.repo.List(ctx)
+	if err != nil {
+		k.l.Error("failed to list api keys", zap.Error(err))
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RotateKey replaces the secret of a key that was not revoked
+func (k *keySVC) RotateKey(ctx context.Context, keyID string) (types.APIKey, string, error) {
+	secret, hash, err := newSecret()
+	if err != nil {
+		return types.APIKey{}, "", err
+	}
+	key, err := k.repo.Rotate(ctx, keyID, hash, time.Now())
+	if err != nil {
+		k.l.Error("failed to rotate api key", zap.Error(err), zap.String("key-id", keyID))
+		return types.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// RevokeKey revokes a key, after which its secret is no longer accepted
+func (k *keySVC) RevokeKey(ctx context.Context, keyID string) error {
+	if err := k.repo.Revoke(ctx, keyID, time.Now()); err != nil {
+		k.l.Error("failed to revoke api key", zap.Error(err), zap.String("key-id", keyID))
+		return err
+	}
+	return nil
+}
+
+// newSecret returns a new random secret and the hex encoded sha256 hash stored for it
+func newSecret() (string, string, error) {
+	secret, err := randomString(secretBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", "", err
+	}
+	secret = secretPrefix + secret
+	sum := sha256.Sum256([]byte(secret))
+	return secret, hex.EncodeToString(sum[:]), nil
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
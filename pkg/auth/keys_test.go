@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+func TestKeyService(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	r := &types.MockKeyRepo{Data: make(map[string]types.APIKey)}
+	svc := NewKeyService(zap.NewNop(), r, "bootstrap-secret")
+
+	p, err := svc.Authenticate(ctx, "bootstrap-secret")
+	a.Nil(err)
+	a.Equal(types.Principal{KeyID: BootstrapKeyID, Admin: true}, p)
+
+	key, secret, err := svc.CreateKey(ctx, " marketing ", false)
+	a.Nil(err)
+	a.Equal("marketing", key.Name)
+	a.True(strings.HasPrefix(secret, secretPrefix))
+	a.NotContains(r.Data[key.ID].Hash, secret)
+	p, err = svc.Authenticate(ctx, secret)
+	a.Nil(err)
+	a.Equal(types.Principal{KeyID: key.ID}, p)
+
+	rotated, newSecret, err := svc.RotateKey(ctx, key.ID)
+	a.Nil(err)
+	a.False(rotated.RotatedAt.IsZero())
+	_, err = svc.Authenticate(ctx, secret)
+	a.ErrorIs(err, types.ErrUnauthenticated)
+	p, err = svc.Authenticate(ctx, newSecret)
+	a.Nil(err)
+	a.Equal(key.ID, p.KeyID)
+
+	a.Nil(svc.RevokeKey(ctx, key.ID))
+	_, err = svc.Authenticate(ctx, newSecret)
+	a.ErrorIs(err, types.ErrUnauthenticated)
+	a.ErrorIs(svc.RevokeKey(ctx, key.ID), types.ErrDocumentNotFound)
+	_, _, err = svc.RotateKey(ctx, key.ID)
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+
+	keys, err := svc.ListKeys(ctx)
+	a.Nil(err)
+	a.Len(keys, 1)
+	a.False(keys[0].RevokedAt.IsZero())
+
+	testCases := map[string]struct {
+		apiKey string
+	}{
+		"missing key":  {apiKey: ""},
+		"unknown key":  {apiKey: secretPrefix + "unknown"},
+		"not a key":    {apiKey: "bootstrap"},
+		"revoked key":  {apiKey: newSecret},
+		"rotated away": {apiKey: secret},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Authenticate(ctx, testCase.apiKey)
+			a.ErrorIs(err, types.ErrUnauthenticated)
+		})
+	}
+
+	_, _, err = svc.CreateKey(ctx, " ", true)
+	a.ErrorIs(err, types.ErrInvalidKeyName)
+}
@@ -2,6 +2,9 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -11,6 +14,28 @@ import (
 
 const cacheExpire = time.Hour * 24 * 1 // 1 day
 
+// decrementIfPresent decrements a counter without creating it when it does not exist
+var decrementIfPresent = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("DECR", KEYS[1])
+end
+return false
+`)
+
+// takeFields reads and deletes a hash in one step, so that no increment of its fields is lost in between
+var takeFields = redis.NewScript(`
+local fields = redis.call("HGETALL", KEYS[1])
+redis.call("DEL", KEYS[1])
+return fields
+`)
+
+// takeField reads and deletes a field of a hash in one step
+var takeField = redis.NewScript(`
+local n = redis.call("HGET", KEYS[1], ARGV[1])
+redis.call("HDEL", KEYS[1], ARGV[1])
+return n
+`)
+
 type cacheService struct {
 	c *redis.Client
 }
@@ -20,12 +45,120 @@ func NewCacheService(c *redis.Client) types.CacheService {
 	return &cacheService{c: c}
 }
 
-// Cache stores a key:value pair in the cache
-func (c *cacheService) Cache(ctx context.Context, key string, val any) error {
-	statusCMD := c.c.Set(ctx, key, val, cacheExpire)
+// Cache stores a key:value pair in the cache. The entry expires after ttl, capped at a day.
+func (c *cacheService) Cache(ctx context.Context, key string, val any, ttl time.Duration) error {
+	if ttl <= 0 || ttl > cacheExpire {
+		ttl = cacheExpire
+	}
+	statusCMD := c.c.Set(ctx, key, val, ttl)
 	return statusCMD.Err()
 }
 
+// CacheIfAbsent stores a key:value pair in the cache unless the key is already cached. The entry expires after ttl,
+// capped at a day.
+func (c *cacheService) CacheIfAbsent(ctx context.Context, key string, val any, ttl time.Duration) error {
+	if ttl <= 0 || ttl > cacheExpire {
+		ttl = cacheExpire
+	}
+	boolCMD := c.c.SetNX(ctx, key, val, ttl)
+	return boolCMD.Err()
+}
+
+// CacheMany stores all the entries using one pipeline. Every entry expires after its ttl, capped at a day.
+func (c *cacheService) CacheMany(ctx context.Context, entries []types.CacheEntry) error {
+	_, err := c.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, e := range entries {
+			ttl := e.TTL
+			if ttl <= 0 || ttl > cacheExpire {
+				ttl = cacheExpire
+			}
+			p.Set(ctx, e.Key, e.Value, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// Increment adds one to the counter stored for the key and returns the new value. The counter expires ttl after it
+// was created.
+func (c *cacheService) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, err := c.c.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		err = c.c.Expire(ctx, key, ttl).Err()
+	}
+	return n, err
+}
+
+// SetCounter stores a counter for the key. Unlike cached values the counter is not capped at a day, it expires after
+// ttl or never when ttl is zero.
+func (c *cacheService) SetCounter(ctx context.Context, key string, val int64, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return c.c.Set(ctx, key, val, ttl).Err()
+}
+
+// DecrementIfPresent atomically decrements the counter of the key, if there is one
+func (c *cacheService) DecrementIfPresent(ctx context.Context, key string) (int64, bool, error) {
+	n, err := decrementIfPresent.Run(ctx, c.c, []string{key}).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+// IncrementFields adds the counts to the fields of the hash of the key using one pipeline. The hash does not expire.
+func (c *cacheService) IncrementFields(ctx context.Context, key string, counts map[string]int64) error {
+	_, err := c.c.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for field, n := range counts {
+			p.HIncrBy(ctx, key, field, n)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetFieldCounter returns the counter of the field of the hash of the key
+func (c *cacheService) GetFieldCounter(ctx context.Context, key, field string) (int64, error) {
+	n, err := c.c.HGet(ctx, key, field).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// TakeFieldCounters atomically deletes the hash of the key and returns the counters of its fields
+func (c *cacheService) TakeFieldCounters(ctx context.Context, key string) (map[string]int64, error) {
+	fields, err := takeFields.Run(ctx, c.c, []string{key}).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		n, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter of field %q of %s: %w", fields[i], key, err)
+		}
+		counts[fields[i]] = n
+	}
+	return counts, nil
+}
+
+// TakeFieldCounter atomically deletes the field of the hash of the key and returns its counter
+func (c *cacheService) TakeFieldCounter(ctx context.Context, key, field string) (int64, error) {
+	n, err := takeField.Run(ctx, c.c, []string{key}, field).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
 // Delete removes a key:value pair from the cache
 func (c *cacheService) Delete(ctx context.Context, key string) error {
 	deleted := c.c.Del(ctx, key)
@@ -0,0 +1,175 @@
+package clicks
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	defaultCounterFlushInterval = 10 * time.Second
+	// countsKey is the cache hash counting the clicks of every tiny url that were not added to the db yet
+	countsKey = types.ClickCountsPrefix + "pending"
+)
+
+type counter struct {
+	l             *zap.Logger
+	repo          types.URLRepo
+	cache         types.CacheService
+	flushInterval time.Duration
+}
+
+// DefaultCounterConfig returns the default settings of the click counter
+func DefaultCounterConfig() types.ClickCounterConfig {
+	return types.ClickCounterConfig{FlushInterval: defaultCounterFlushInterval}
+}
+
+// NewCounter returns a click counter incrementing the clicks of tiny urls in the cache, which are added to the db of
+// the tiny urls every flush interval. Every instance of the service shares the counts of the cache.
+func NewCounter(l *zap.Logger, r types.URLRepo, c types.CacheService, cfg types.ClickCounterConfig) types.ClickCounter {
+	return &counter{l: l, repo: r, cache: c, flushInterval: cfg.FlushInterval}
+}
+
+// Count adds a click to the count of the tiny url in the cache. The click is added to the db right away when the
+// cache fails.
+func (c *counter) Count(ctx context.Context, tenant, urlKey string) {
+	c.count(ctx, types.ClickCount{Tenant: tenant, URLKey: urlKey, Clicks: 1})
+}
+
+// CountVariant adds a click to the count of the variant of the tiny url in the cache. The click is added to the db
+// right away when the cache fails.
+func (c *counter) CountVariant(ctx context.Context, tenant, urlKey string, variant int) {
+	c.count(ctx, types.ClickCount{Tenant: tenant, URLKey: urlKey, Variant: &variant, Clicks: 1})
+}
+
+func (c *counter) count(ctx context.Context, click types.ClickCount) {
+	err := c.cache.IncrementFields(ctx, countsKey, map[string]int64{clickField(click): click.Clicks})
+	if err == nil {
+		return
+	}
+	c.l.Warn("failed to count click", zap.Error(err), zap.String("db-key", click.URLKey))
+	if err = c.repo.AddClicks(ctx, []types.ClickCount{click}); err != nil {
+		c.l.Error("failed to add click", zap.Error(err), zap.String("db-key", click.URLKey))
+	}
+}
+
+// Pending returns the clicks of the tiny url counted in the cache
+func (c *counter) Pending(ctx context.Context, tenant, urlKey string) (int64, error) {
+	return c.cache.GetFieldCounter(ctx, countsKey, countField(tenant, urlKey))
+}
+
+// PendingVariants returns the clicks of the first n variants of the tiny url counted in the cache
+func (c *counter) PendingVariants(ctx context.Context, tenant, urlKey string, n int) ([]int64, error) {
+	pending := make([]int64, n)
+	for i := range pending {
+		clicks, err := c.cache.GetFieldCounter(ctx, countsKey, variantField(tenant, urlKey, i))
+		if err != nil {
+			return nil, err
+		}
+		pending[i] = clicks
+	}
+	return pending, nil
+}
+
+// Flush takes the clicks of the tiny url from the cache and adds them to the db. The clicks are put back in the cache
+// when the write fails.
+func (c *counter) Flush(ctx context.Context, tenant, urlKey string) error {
+	field := countField(tenant, urlKey)
+	n, err := c.cache.TakeFieldCounter(ctx, countsKey, field)
+	if err != nil || n == 0 {
+		return err
+	}
+	err = c.repo.AddClicks(ctx, []types.ClickCount{{Tenant: tenant, URLKey: urlKey, Clicks: n}})
+	if err == nil {
+		return nil
+	}
+	if restoreErr := c.cache.IncrementFields(ctx, countsKey, map[string]int64{field: n}); restoreErr != nil {
+		c.l.Error("failed to restore click count", zap.Error(restoreErr), zap.String("db-key", urlKey))
+	}
+	return err
+}
+
+// Run adds the counted clicks to the db every flush interval until the context is done, and a last time then
+func (c *counter) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// the counts left are flushed with a context of their own
+			flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			c.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			c.flush(ctx)
+		}
+	}
+}
+
+// flush takes the counts from the cache and adds them to the db with a single write. The counts are put back in the
+// cache when the write fails, so that the next flush adds them.
+func (c *counter) flush(ctx context.Context) {
+	counts, err := c.cache.TakeFieldCounters(ctx, countsKey)
+	if err != nil {
+		c.l.Error("failed to take click counts", zap.Error(err))
+		return
+	}
+	if len(counts) == 0 {
+		return
+	}
+	clicks := make([]types.ClickCount, 0, len(counts))
+	for field, n := range counts {
+		clicks = append(clicks, parseClickField(field, n))
+	}
+	if err = c.repo.AddClicks(ctx, clicks); err == nil {
+		return
+	}
+	c.l.Error("failed to add click counts", zap.Error(err), zap.Int("count", len(clicks)))
+	if err = c.cache.IncrementFields(ctx, countsKey, counts); err != nil {
+		c.l.Error("failed to restore click counts", zap.Error(err), zap.Int("count", len(clicks)))
+	}
+}
+
+// countField is the field counting the clicks of the tiny url of the key of the tenant. Keys and tenant ids never
+// contain a colon or a '#'.
+func countField(tenant, urlKey string) string {
+	if tenant == "" {
+		return urlKey
+	}
+	return tenant + ":" + urlKey
+}
+
+// variantField is the field counting the clicks of the variant at the index of the tiny url
+func variantField(tenant, urlKey string, variant int) string {
+	return countField(tenant, urlKey) + "#" + strconv.Itoa(variant)
+}
+
+// clickField is the field counting the clicks of the count
+func clickField(click types.ClickCount) string {
+	if click.Variant != nil {
+		return variantField(click.Tenant, click.URLKey, *click.Variant)
+	}
+	return countField(click.Tenant, click.URLKey)
+}
+
+// parseClickField returns the count of n clicks of the tiny url, or of its variant, counted by the field
+func parseClickField(field string, n int64) types.ClickCount {
+	click := types.ClickCount{URLKey: field, Clicks: n}
+	if rest, index, ok := strings.Cut(field, "#"); ok {
+		if variant, err := strconv.Atoi(index); err == nil {
+			click.URLKey, click.Variant = rest, &variant
+		}
+	}
+	if tenant, urlKey, ok := strings.Cut(click.URLKey, ":"); ok {
+		click.Tenant, click.URLKey = tenant, urlKey
+	}
+	return click
+}
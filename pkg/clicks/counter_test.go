@@ -0,0 +1,104 @@
+package clicks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// failingRepo fails to add clicks
+type failingRepo struct {
+	*types.MockRepo
+}
+
+func (failingRepo) AddClicks(context.Context, []types.ClickCount) error {
+	return errors.New("failed to add clicks")
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	r := &types.MockRepo{Data: map[string]types.URLDocument{
+		"Hx21p":        {URLKey: "Hx21p", Clicks: 5},
+		"brand-a:sale": {Tenant: "brand-a", URLKey: "sale"},
+		"brand-b:sale": {Tenant: "brand-b", URLKey: "sale"},
+	}}
+	c := &types.MockCache{Data: make(map[string]string)}
+	cc := NewCounter(zap.NewNop(), r, c, DefaultCounterConfig()).(*counter)
+
+	cc.Count(ctx, "", "Hx21p")
+	cc.Count(ctx, "", "Hx21p")
+	cc.Count(ctx, "brand-a", "sale")
+	cc.Count(ctx, "brand-a", "gone")
+	pending, err := cc.Pending(ctx, "", "Hx21p")
+	a.Nil(err)
+	a.Equal(int64(2), pending)
+	pending, err = cc.Pending(ctx, "brand-b", "sale")
+	a.Nil(err)
+	a.Zero(pending)
+	// the db is only written when the counts are flushed
+	a.Equal(int64(5), r.Data["Hx21p"].Clicks)
+
+	cc.flush(ctx)
+	a.Equal(int64(7), r.Data["Hx21p"].Clicks)
+	a.Equal(int64(1), r.Data["brand-a:sale"].Clicks)
+	a.Zero(r.Data["brand-b:sale"].Clicks)
+	a.NotContains(r.Data, "brand-a:gone")
+	pending, err = cc.Pending(ctx, "", "Hx21p")
+	a.Nil(err)
+	a.Zero(pending)
+
+	// variant clicks are counted apart from the clicks of the tiny url, and clicks of variants that no longer exist
+	// are dropped
+	r.Data["brand-b:split"] = types.URLDocument{Tenant: "brand-b", URLKey: "split",
+		Variants: []types.Variant{{URL: "https://a.io"}, {URL: "https://b.io"}}}
+	cc.CountVariant(ctx, "brand-b", "split", 1)
+	cc.CountVariant(ctx, "brand-b", "split", 1)
+	cc.CountVariant(ctx, "brand-b", "split", 2)
+	variants, err := cc.PendingVariants(ctx, "brand-b", "split", 2)
+	a.Nil(err)
+	a.Equal([]int64{0, 2}, variants)
+	cc.flush(ctx)
+	a.Zero(r.Data["brand-b:split"].Clicks)
+	a.Len(r.Data["brand-b:split"].Variants, 2)
+	a.Zero(r.Data["brand-b:split"].Variants[0].Clicks)
+	a.Equal(int64(2), r.Data["brand-b:split"].Variants[1].Clicks)
+
+	// the counts of a single tiny url can be flushed on their own
+	cc.Count(ctx, "", "Hx21p")
+	cc.Count(ctx, "brand-a", "sale")
+	a.Nil(cc.Flush(ctx, "", "Hx21p"))
+	a.Equal(int64(8), r.Data["Hx21p"].Clicks)
+	a.Equal(int64(1), r.Data["brand-a:sale"].Clicks)
+	pending, err = cc.Pending(ctx, "brand-a", "sale")
+	a.Nil(err)
+	a.Equal(int64(1), pending)
+	cc.flush(ctx)
+
+	// counts that cannot be added to the db are kept for the next flush
+	cc.repo = failingRepo{r}
+	cc.Count(ctx, "brand-b", "sale")
+	cc.flush(ctx)
+	pending, err = cc.Pending(ctx, "brand-b", "sale")
+	a.Nil(err)
+	a.Equal(int64(1), pending)
+	a.NotNil(cc.Flush(ctx, "brand-b", "sale"))
+	pending, err = cc.Pending(ctx, "brand-b", "sale")
+	a.Nil(err)
+	a.Equal(int64(1), pending)
+
+	// the counts left are flushed when the counter stops
+	cc.repo = r
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	cc.Run(cancelled, wg)
+	a.Equal(int64(1), r.Data["brand-b:sale"].Clicks)
+}
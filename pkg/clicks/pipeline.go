@@ -0,0 +1,140 @@
+package clicks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	defaultQueueSize     = 10000
+	defaultWorkers       = 2
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	// shutdownTimeout bounds the writes of the events left in the queue once the pipeline stops
+	shutdownTimeout = 5 * time.Second
+
+	dropQueueFull   = "queue_full"
+	dropWriteFailed = "write_failed"
+)
+
+type pipeline struct {
+	l             *zap.Logger
+	repo          types.ClickRepo
+	queue         chan types.ClickEvent
+	workers       int
+	batchSize     int
+	flushInterval time.Duration
+	dropped       *prometheus.CounterVec
+}
+
+// DefaultPipelineConfig returns the default settings of the click event pipeline
+func DefaultPipelineConfig() types.ClickPipelineConfig {
+	return types.ClickPipelineConfig{
+		QueueSize:     defaultQueueSize,
+		Workers:       defaultWorkers,
+		BatchSize:     defaultBatchSize,
+		FlushInterval: defaultFlushInterval,
+	}
+}
+
+// NewPipeline returns a click event pipeline writing the events it records to the repo. Events are queued in memory
+// and dropped when the queue is full, so that visits never wait for the db.
+func NewPipeline(l *zap.Logger, r types.ClickRepo, cfg types.ClickPipelineConfig) types.ClickPipeline {
+	return &pipeline{
+		l:             l,
+		repo:          r,
+		queue:         make(chan types.ClickEvent, cfg.QueueSize),
+		workers:       cfg.Workers,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "click_events_dropped_total",
+			Namespace: "tiny_url_svc",
+			Help:      "click events that were not stored, by reason",
+		}, []string{"reason"}),
+	}
+}
+
+// RegisterProm registers the dropped events counter with prometheus
+func (p *pipeline) RegisterProm() error {
+	return prometheus.Register(p.dropped)
+}
+
+// Record queues the event, or drops it when the queue is full
+func (p *pipeline) Record(event types.ClickEvent) {
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped.WithLabelValues(dropQueueFull).Inc()
+	}
+}
+
+// Run writes the queued events with the workers until the context is done, and then writes the events left in the
+// queue
+func (p *pipeline) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	workers := new(sync.WaitGroup)
+	for i := 0; i < p.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			p.work(ctx)
+		}()
+	}
+	workers.Wait()
+}
+
+// work writes the queued events in batches, writing a partial batch every flush interval
+func (p *pipeline) work(ctx context.Context) {
+	batch := make([]types.ClickEvent, 0, p.batchSize)
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-p.queue:
+			if batch = append(batch, event); len(batch) == p.batchSize {
+				batch = p.write(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = p.write(ctx, batch)
+		case <-ctx.Done():
+			p.drain(batch)
+			return
+		}
+	}
+}
+
+// drain writes the batch and the events left in the queue once the pipeline stops
+func (p *pipeline) drain(batch []types.ClickEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for {
+		select {
+		case event := <-p.queue:
+			if batch = append(batch, event); len(batch) == p.batchSize {
+				batch = p.write(ctx, batch)
+			}
+		default:
+			p.write(ctx, batch)
+			return
+		}
+	}
+}
+
+// write stores the batch and returns it emptied. Events that cannot be stored are dropped and counted.
+func (p *pipeline) write(ctx context.Context, batch []types.ClickEvent) []types.ClickEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := p.repo.PutMany(ctx, batch); err != nil {
+		p.l.Error("failed to store click events", zap.Error(err), zap.Int("count", len(batch)))
+		p.dropped.WithLabelValues(dropWriteFailed).Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
@@ -0,0 +1,67 @@
+package clicks
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+func TestPipeline(t *testing.T) {
+	a := assert.New(t)
+	r := &types.MockClickRepo{}
+	p := NewPipeline(zap.NewNop(), r, types.ClickPipelineConfig{
+		QueueSize:     3,
+		Workers:       1,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	}).(*pipeline)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	go p.Run(ctx, wg)
+
+	// full batches are written right away
+	p.Record(types.ClickEvent{URLKey: "Hx21p"})
+	p.Record(types.ClickEvent{URLKey: "Hx21p"})
+	a.Eventually(func() bool { return r.Len() == 2 }, time.Second, time.Millisecond)
+
+	// the events left once the pipeline stops are written as well
+	p.Record(types.ClickEvent{URLKey: "Nv3Rx"})
+	cancel()
+	wg.Wait()
+	a.Len(r.Events, 3)
+	a.Zero(testutil.ToFloat64(p.dropped.WithLabelValues(dropWriteFailed)))
+}
+
+func TestPipelineDrops(t *testing.T) {
+	a := assert.New(t)
+	r := &types.MockClickRepo{}
+	p := NewPipeline(zap.NewNop(), r, types.ClickPipelineConfig{
+		QueueSize:     2,
+		Workers:       1,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+	}).(*pipeline)
+
+	// recording never waits for the workers
+	for i := 0; i < 3; i++ {
+		p.Record(types.ClickEvent{URLKey: "Hx21p"})
+	}
+	a.Equal(float64(1), testutil.ToFloat64(p.dropped.WithLabelValues(dropQueueFull)))
+
+	r.Fail = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	p.Run(ctx, wg)
+	a.Empty(r.Events)
+	a.Equal(float64(2), testutil.ToFloat64(p.dropped.WithLabelValues(dropWriteFailed)))
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const clickCollectionName = "clicks"
+
+type clickRepo struct {
+	client *mongo.Client
+}
+
+// NewClickRepo return a new click event repo. It ensures the indexes the repo relies on exist.
+func NewClickRepo(ctx context.Context, c *mongo.Client) (types.ClickRepo, error) {
+	r := &clickRepo{client: c}
+	if err := r.createIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// PutMany stores the events with a single unordered insert
+func (r *clickRepo) PutMany(ctx context.Context, events []types.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]any, len(events))
+	for i, e := range events {
+		docs[i] = e
+	}
+	_, err := r.collection().InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+// createIndexes creates an index on tenant, url_key and time to read the clicks of a tiny url over a period
+func (r *clickRepo) createIndexes(ctx context.Context) error {
+	_, err := r.collection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "url_key", Value: 1}, {Key: "time", Value: 1}},
+	})
+	return err
+}
+
+func (r *clickRepo) collection() *mongo.Collection {
+	return r.client.Database(dbName).Collection(clickCollectionName)
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const keyCollectionName = "api_keys"
+
+type keyRepo struct {
+	client *mongo.Client
+}
+
+// NewAPIKeyRepo return a new api key repo. It ensures the indexes the repo relies on exist.
+func NewAPIKeyRepo(ctx context.Context, c *mongo.Client) (types.APIKeyRepo, error) {
+	r := &keyRepo{client: c}
+	if err := r.createIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Put stores a new key. ErrDuplicateKey is returned when a key with the same id or hash exists.
+func (r *keyRepo) Put(ctx context.Context, key types.APIKey) error {
+	_, err := r.collection().InsertOne(ctx, key)
+	if mongo.IsDuplicateKeyError(err) {
+		return types.ErrDuplicateKey
+	}
+	return err
+}
+
+// GetByHash retrieves the key with the hash of a secret
+func (r *keyRepo) GetByHash(ctx context.Context, hash string) (types.APIKey, error) {
+	key := &types.APIKey{}
+	err := r.collection().FindOne(ctx, bson.M{"key_hash": hash}).Decode(key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return types.APIKey{}, types.ErrDocumentNotFound
+		}
+		return types.APIKey{}, err
+	}
+	return *key, nil
+}
+
+// List returns every key, oldest first
+func (r *keyRepo) List(ctx context.Context) ([]types.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]types.APIKey, 0)
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Rotate replaces the hash of the key unless it was revoked, and returns the updated key
+func (r *keyRepo) Rotate(ctx context.Context, keyID, hash string, rotatedAt time.Time) (types.APIKey, error) {
+	filter := bson.M{"key_id": keyID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"key_hash": hash, "rotated_at": rotatedAt}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	key := &types.APIKey{}
+	err := r.collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return types.APIKey{}, types.ErrDocumentNotFound
+		}
+		return types.APIKey{}, err
+	}
+	return *key, nil
+}
+
+// Revoke marks the key as revoked unless it already is
+func (r *keyRepo) Revoke(ctx context.Context, keyID string, revokedAt time.Time) error {
+	filter := bson.M{"key_id": keyID, "revoked_at": bson.M{"$exists": false}}
+	updated, err := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": revokedAt}})
+	if err != nil {
+		return err
+	}
+	if updated.MatchedCount == 0 {
+		return types.ErrDocumentNotFound
+	}
+	return nil
+}
+
+// createIndexes creates unique indexes on key_id, to look keys up by id, and on key_hash, to authenticate requests
+func (r *keyRepo) createIndexes(ctx context.Context) error {
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err := r.collection().Indexes().CreateMany(ctx, indexModels)
+	return err
+}
+
+func (r *keyRepo) collection() *mongo.Collection {
+	return r.client.Database(dbName).Collection(keyCollectionName)
+}
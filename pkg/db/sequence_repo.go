@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const sequenceCollectionName = "sequences"
+
+type sequenceDocument struct {
+	Name  string `bson:"_id"`
+	Value int64  `bson:"value"`
+}
+
+type sequenceRepo struct {
+	client *mongo.Client
+}
+
+// NewSequenceRepo return a new sequence repo
+func NewSequenceRepo(c *mongo.Client) types.SequenceRepo {
+	return &sequenceRepo{client: c}
+}
+
+// NextRange atomically increments the named sequence by size and returns the new value, which is the last id of
+// the reserved range. The sequence document is created on first use.
+func (s *sequenceRepo) NextRange(ctx context.Context, name string, size int64) (int64, error) {
+	seq := &sequenceDocument{}
+	filter := bson.M{"_id": name}
+	update := bson.M{"$inc": bson.M{"value": size}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := s.collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(seq)
+	if err != nil {
+		return 0, err
+	}
+	return seq.Value, nil
+}
+
+func (s *sequenceRepo) collection() *mongo.Collection {
+	return s.client.Database(dbName).Collection(sequenceCollectionName)
+}
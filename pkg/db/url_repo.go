@@ -3,6 +3,10 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/vaishakdinesh/tiny-url-svc/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -12,15 +16,34 @@ import (
 const (
 	dbName         = "tiny-url"
 	collectionName = "tiny_urls"
+	// dedupeHashIndex names the unique index on dedupe_hash so its duplicate key errors can be told apart
+	dedupeHashIndex = "dedupe_hash_unique"
+	// legacyURLKeyIndex is the unique index on url_key alone, which predates tenants and is replaced by a unique
+	// index on tenant and url_key
+	legacyURLKeyIndex = "url_key_1"
+	// textIndex searches the title, notes and tags of the documents
+	textIndex = "metadata_text"
+	// ttlIndex removes the documents once the expired retention after their expire_time has passed
+	ttlIndex = "expire_time_1"
+	// indexNotFound is the code of the error dropping an index that does not exist
+	indexNotFound = 27
+	// indexOptionsConflict is the code of the error creating an index that exists with other options
+	indexOptionsConflict = 85
 )
 
 type repo struct {
-	client *mongo.Client
+	client           *mongo.Client
+	expiredRetention time.Duration
 }
 
-// NewURLRepo return a new url repo
-func NewURLRepo(c *mongo.Client) types.URLRepo {
-	return &repo{client: c}
+// NewURLRepo return a new url repo which keeps expired documents for the expired retention, so that their fallback
+// urls can still be served. It ensures the indexes the repo relies on exist.
+func NewURLRepo(ctx context.Context, c *mongo.Client, expiredRetention time.Duration) (types.URLRepo, error) {
+	r := &repo{client: c, expiredRetention: expiredRetention}
+	if err := r.createIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 // Put stores the document in the datastore
@@ -28,31 +51,53 @@ func (r *repo) Put(ctx context.Context, document any) error {
 	collection := r.collection()
 	switch o := document.(type) {
 	case types.URLDocument:
-		_, err := collection.InsertOne(ctx, document)
-		if err != nil {
-			return err
-		}
-		if !o.LiveForever {
-			indexOptions := options.Index().SetExpireAfterSeconds(0)
-			indexModel := mongo.IndexModel{
-				Keys:    bson.D{{Key: "expire_time", Value: 1}},
-				Options: indexOptions,
-			}
-			_, err = collection.Indexes().CreateOne(context.Background(), indexModel)
-			return err
+		_, err := collection.InsertOne(ctx, o)
+		if mongo.IsDuplicateKeyError(err) {
+			return duplicateError(err.Error())
 		}
+		return err
 	default:
 		_, err := collection.InsertOne(ctx, o)
 		return err
 	}
-	return nil
+}
+
+// PutMany stores the documents with a single unordered insert, so a failed document does not stop the others
+func (r *repo) PutMany(ctx context.Context, documents []types.URLDocument) ([]error, error) {
+	errs := make([]error, len(documents))
+	if len(documents) == 0 {
+		return errs, nil
+	}
+	docs := make([]any, len(documents))
+	for i, d := range documents {
+		docs[i] = d
+	}
+	_, err := r.collection().InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err == nil {
+		return errs, nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return nil, err
+	}
+	for _, we := range bulkErr.WriteErrors {
+		if we.Index < 0 || we.Index >= len(errs) {
+			continue
+		}
+		if mongo.IsDuplicateKeyError(we.WriteError) {
+			errs[we.Index] = duplicateError(we.Message)
+		} else {
+			errs[we.Index] = we.WriteError
+		}
+	}
+	return errs, nil
 }
 
 // GetDocument retrieves a document based on the urlKey
-func (r *repo) GetDocument(ctx context.Context, urlKey string) (types.URLDocument, error) {
+func (r *repo) GetDocument(ctx context.Context, tenant, urlKey string) (types.URLDocument, error) {
 	urlDoc := &types.URLDocument{}
 	collection := r.collection()
-	filter := bson.M{"url_key": urlKey}
+	filter := keyFilter(tenant, urlKey)
 	err := collection.FindOne(ctx, filter).Decode(urlDoc)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -64,9 +109,9 @@ func (r *repo) GetDocument(ctx context.Context, urlKey string) (types.URLDocumen
 }
 
 // Delete deletes a document based the urlKey
-func (r *repo) Delete(ctx context.Context, urlKey string) error {
+func (r *repo) Delete(ctx context.Context, tenant, urlKey string) error {
 	collection := r.collection()
-	filter := bson.M{"url_key": urlKey}
+	filter := keyFilter(tenant, urlKey)
 	deleted, err := collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
@@ -78,6 +123,400 @@ func (r *repo) Delete(ctx context.Context, urlKey string) error {
 
 }
 
+// Trash marks the document of the urlKey as deleted and clears its dedupe hash, so that dedupe never returns it
+func (r *repo) Trash(ctx context.Context, tenant, urlKey string, deletedAt time.Time) error {
+	filter := keyFilter(tenant, urlKey)
+	filter["deleted_at"] = bson.M{"$exists": false}
+	change := bson.M{"$set": bson.M{"deleted_at": deletedAt}, "$unset": bson.M{"dedupe_hash": ""}}
+	updated, err := r.collection().UpdateOne(ctx, filter, change)
+	if err != nil {
+		return err
+	}
+	if updated.MatchedCount == 0 {
+		return types.ErrDocumentNotFound
+	}
+	return nil
+}
+
+// Restore removes the deletion mark of the document of the urlKey and returns the restored document
+func (r *repo) Restore(ctx context.Context, tenant, urlKey string) (types.URLDocument, error) {
+	filter := keyFilter(tenant, urlKey)
+	filter["deleted_at"] = bson.M{"$exists": true}
+	urlDoc := &types.URLDocument{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection().FindOneAndUpdate(ctx, filter, bson.M{"$unset": bson.M{"deleted_at": ""}}, opts).
+		Decode(urlDoc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return types.URLDocument{}, types.ErrDocumentNotFound
+		}
+		return types.URLDocument{}, err
+	}
+	return *urlDoc, nil
+}
+
+// PurgeTrash deletes the documents of every tenant that were moved to the trash before the given time
+func (r *repo) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := r.collection().DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lte": before}})
+	if err != nil {
+		return 0, err
+	}
+	return deleted.DeletedCount, nil
+}
+
+// SetSuspension sets the suspension of the document of the urlKey, or unsets it when nil, and returns the updated
+// document
+func (r *repo) SetSuspension(ctx context.Context, tenant, urlKey string,
+	suspension *types.Suspension) (types.URLDocument, error) {
+	change := bson.M{"$unset": bson.M{"suspension": ""}}
+	if suspension != nil {
+		change = bson.M{"$set": bson.M{"suspension": suspension}}
+	}
+	urlDoc := &types.URLDocument{}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection().FindOneAndUpdate(ctx, keyFilter(tenant, urlKey), change, opts).Decode(urlDoc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return types.URLDocument{}, types.ErrDocumentNotFound
+		}
+		return types.URLDocument{}, err
+	}
+	return *urlDoc, nil
+}
+
+// GetDocumentByDedupeHash retrieves the document generated with dedupe for the hash of a long url
+func (r *repo) GetDocumentByDedupeHash(ctx context.Context, dedupeHash string) (types.URLDocument, error) {
+	urlDoc := &types.URLDocument{}
+	filter := bson.M{"dedupe_hash": dedupeHash}
+	err := r.collection().FindOne(ctx, filter).Decode(urlDoc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return types.URLDocument{}, types.ErrDocumentNotFound
+		}
+		return types.URLDocument{}, err
+	}
+	return *urlDoc, nil
+}
+
+// ClearDedupeHash removes the dedupe hash of the document of the urlKey, unless it was changed in the meantime
+func (r *repo) ClearDedupeHash(ctx context.Context, tenant, urlKey, dedupeHash string) error {
+	filter := keyFilter(tenant, urlKey)
+	filter["dedupe_hash"] = dedupeHash
+	_, err := r.collection().UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"dedupe_hash": ""}})
+	return err
+}
+
+// Update applies the non nil fields of the update to the document of the urlKey and returns the updated document
+func (r *repo) Update(ctx context.Context, tenant, urlKey string,
+	update types.URLUpdate) (types.URLDocument, error) {
+	set := bson.M{}
+	if update.LongURL != nil {
+		set["long_url"] = *update.LongURL
+		set["domain"] = types.URLDomain(*update.LongURL)
+	}
+	if update.ExpireAt != nil {
+		set["expire_time"] = *update.ExpireAt
+	}
+	if update.LiveForever != nil {
+		set["live_forever"] = *update.LiveForever
+	}
+	if update.ActiveFrom != nil {
+		set["active_from"] = *update.ActiveFrom
+	}
+	if update.ActiveUntil != nil {
+		set["active_until"] = *update.ActiveUntil
+	}
+	if update.Interstitial != nil {
+		set["interstitial"] = *update.Interstitial
+	}
+	if update.Passthrough != nil {
+		set["passthrough"] = *update.Passthrough
+	}
+	if update.Rules != nil {
+		set["rules"] = *update.Rules
+	}
+	if update.Variants != nil {
+		set["variants"] = *update.Variants
+	}
+	unset := bson.M{}
+	for field, value := range map[string]*string{
+		"title":        update.Title,
+		"notes":        update.Notes,
+		"created_by":   update.CreatedBy,
+		"external_id":  update.ExternalID,
+		"fallback_url": update.FallbackURL,
+	} {
+		switch {
+		case value == nil:
+		case *value == "":
+			unset[field] = ""
+		default:
+			set[field] = *value
+		}
+	}
+	if update.Tags != nil {
+		if len(*update.Tags) == 0 {
+			unset["tags"] = ""
+		} else {
+			set["tags"] = *update.Tags
+		}
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return types.URLDocument{}, types.ErrEmptyUpdate
+	}
+	set["updated_at"] = time.Now()
+	if update.LongURL != nil {
+		// the document no longer points at the long url it was deduplicated for
+		unset["dedupe_hash"] = ""
+	}
+	change := bson.M{"$set": set}
+	if len(unset) > 0 {
+		change["$unset"] = unset
+	}
+	urlDoc := &types.URLDocument{}
+	filter := keyFilter(tenant, urlKey)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection().FindOneAndUpdate(ctx, filter, change, opts).Decode(urlDoc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return types.URLDocument{}, types.ErrDocumentNotFound
+		}
+		return types.URLDocument{}, err
+	}
+	return *urlDoc, nil
+}
+
+// AddClicks adds the clicks of every count to its document, or to the variant of its document, with a single unordered
+// bulk write. Counts of documents or variants that no longer exist match nothing and are ignored, so that a click is
+// never counted into variants that replaced it with fewer entries.
+func (r *repo) AddClicks(ctx context.Context, counts []types.ClickCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, len(counts))
+	for i, c := range counts {
+		filter, field := keyFilter(c.Tenant, c.URLKey), "clicks"
+		if c.Variant != nil {
+			variant := fmt.Sprintf("variants.%d", *c.Variant)
+			filter[variant] = bson.M{"$exists": true}
+			field = variant + ".clicks"
+		}
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$inc": bson.M{field: c.Clicks}})
+	}
+	_, err := r.collection().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// ConsumeClick adds one to the click count of the document of the urlKey if it is below the max clicks and returns
+// the clicks left. The check and the increment are a single update, so concurrent clicks cannot exceed the limit.
+func (r *repo) ConsumeClick(ctx context.Context, tenant, urlKey string) (int64, bool, error) {
+	filter := keyFilter(tenant, urlKey)
+	filter["$expr"] = bson.M{"$lt": bson.A{"$clicks", "$max_clicks"}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).
+		SetProjection(bson.M{"clicks": 1, "max_clicks": 1})
+	urlDoc := &types.URLDocument{}
+	err := r.collection().FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"clicks": 1}}, opts).Decode(urlDoc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return urlDoc.MaxClicks - urlDoc.Clicks, true, nil
+}
+
+// List returns a page of the documents matching the filter of the query. Pages continue after the cursor rather than
+// skipping the previous pages, so every page is read from the sort indexes at the same cost.
+func (r *repo) List(ctx context.Context, query types.ListQuery) ([]types.URLDocument, error) {
+	order := -1
+	if query.Ascending {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortField(query.Sort), Value: order}, {Key: "url_key", Value: order}}).
+		SetLimit(int64(query.Limit))
+	cursor, err := r.collection().Find(ctx, listFilter(query, time.Now()), opts)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]types.URLDocument, 0, query.Limit)
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// listFilter builds the filter of the query. Lower bounds of time ranges are inclusive and upper bounds exclusive.
+func listFilter(query types.ListQuery, now time.Time) bson.M {
+	f := query.Filter
+	and := bson.A{bson.M{"tenant": tenantValue(query.Tenant)}}
+	if f.Owner != "" {
+		and = append(and, bson.M{"owner": f.Owner})
+	}
+	if f.Domain != "" {
+		and = append(and, bson.M{"domain": f.Domain})
+	}
+	if len(f.Tags) > 0 {
+		and = append(and, bson.M{"tags": bson.M{"$all": f.Tags}})
+	}
+	if f.CreatedBy != "" {
+		and = append(and, bson.M{"created_by": f.CreatedBy})
+	}
+	if f.ExternalID != "" {
+		and = append(and, bson.M{"external_id": f.ExternalID})
+	}
+	if f.Search != "" {
+		and = append(and, bson.M{"$text": bson.M{"$search": f.Search}})
+	}
+	if created := timeRange(f.CreatedAfter, f.CreatedBefore); len(created) > 0 {
+		and = append(and, bson.M{"created_at": created})
+	}
+	if expires := timeRange(f.ExpiresAfter, f.ExpiresBefore); len(expires) > 0 {
+		and = append(and, bson.M{"expire_time": expires})
+	}
+	switch f.State {
+	case types.StateActive:
+		and = append(and, bson.M{"expire_time": bson.M{"$gt": now}})
+	case types.StateExpired:
+		and = append(and, bson.M{"expire_time": bson.M{"$lte": now}})
+	}
+	// only the trashed state lists the tiny urls in the trash
+	and = append(and, bson.M{"deleted_at": bson.M{"$exists": f.State == types.StateTrashed}})
+	if query.After != nil {
+		field := sortField(query.Sort)
+		var value any = query.After.CreatedAt
+		if query.Sort == types.SortClicks {
+			value = query.After.Clicks
+		}
+		op := "$lt"
+		if query.Ascending {
+			op = "$gt"
+		}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{op: value}},
+			bson.M{field: value, "url_key": bson.M{op: query.After.URLKey}},
+		}})
+	}
+	return bson.M{"$and": and}
+}
+
+// keyFilter matches the document of the url key in the tenant
+func keyFilter(tenant, urlKey string) bson.M {
+	return bson.M{"tenant": tenantValue(tenant), "url_key": urlKey}
+}
+
+// tenantValue is the value tenant is matched with. Documents of the default tenant have no tenant, which null matches.
+func tenantValue(tenant string) any {
+	if tenant == "" {
+		return nil
+	}
+	return tenant
+}
+
+func timeRange(from, until time.Time) bson.M {
+	r := bson.M{}
+	if !from.IsZero() {
+		r["$gte"] = from
+	}
+	if !until.IsZero() {
+		r["$lt"] = until
+	}
+	return r
+}
+
+func sortField(sort types.ListSort) string {
+	if sort == types.SortClicks {
+		return "clicks"
+	}
+	return "created_at"
+}
+
+// createIndexes creates a unique index on tenant and url_key so that two concurrent requests cannot claim the same
+// key of a tenant, a partial unique index on dedupe_hash so that a long url is only stored once with dedupe, a TTL
+// index that removes every document once the expired retention after its expire_time has passed, and the indexes
+// listing the documents of a tenant by creation time or clicks, optionally for an owner, a domain, a tag or a creator.
+// Tiny urls are looked up by their external id, and searched through a text index on their title, notes and tags. A
+// partial index on deleted_at finds the documents in the trash to purge. The unique index on url_key alone, which
+// would stop tenants from using the same key, is dropped once its replacement exists.
+func (r *repo) createIndexes(ctx context.Context) error {
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant", Value: 1}, {Key: "url_key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "dedupe_hash", Value: 1}},
+			Options: options.Index().SetName(dedupeHashIndex).SetUnique(true).
+				SetPartialFilterExpression(bson.M{"dedupe_hash": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"deleted_at": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "external_id", Value: 1}},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"external_id": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "title", Value: "text"}, {Key: "notes", Value: "text"}, {Key: "tags", Value: "text"}},
+			// whole words are matched, stemming would depend on the language of every tiny url
+			Options: options.Index().SetName(textIndex).SetDefaultLanguage("none"),
+		},
+	}
+	for _, prefix := range []string{"", "owner", "domain", "tags", "created_by"} {
+		for _, field := range []string{"created_at", "clicks"} {
+			keys := bson.D{{Key: "tenant", Value: 1}}
+			if prefix != "" {
+				keys = append(keys, bson.E{Key: prefix, Value: 1})
+			}
+			keys = append(keys, bson.E{Key: field, Value: 1}, bson.E{Key: "url_key", Value: 1})
+			indexModels = append(indexModels, mongo.IndexModel{Keys: keys})
+		}
+	}
+	if _, err := r.collection().Indexes().CreateMany(ctx, indexModels); err != nil {
+		return err
+	}
+	if err := r.ensureTTLIndex(ctx); err != nil {
+		return err
+	}
+	_, err := r.collection().Indexes().DropOne(ctx, legacyURLKeyIndex)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexNotFound {
+		return nil
+	}
+	return err
+}
+
+// ensureTTLIndex creates the TTL index on expire_time. The expiry of an existing index is changed to the expired
+// retention when that differs from the one it was created with.
+func (r *repo) ensureTTLIndex(ctx context.Context) error {
+	expireAfter := int32(r.expiredRetention.Seconds())
+	_, err := r.collection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_time", Value: 1}},
+		Options: options.Index().SetName(ttlIndex).SetExpireAfterSeconds(expireAfter),
+	})
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Code != indexOptionsConflict {
+		return err
+	}
+	return r.client.Database(dbName).RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collectionName},
+		{Key: "index", Value: bson.M{"name": ttlIndex, "expireAfterSeconds": expireAfter}},
+	}).Err()
+}
+
+// duplicateError maps the message of a duplicate key error to the index that was violated
+func duplicateError(msg string) error {
+	if strings.Contains(msg, dedupeHashIndex) {
+		return types.ErrDuplicateURL
+	}
+	return types.ErrDuplicateKey
+}
+
 func (r *repo) collection() *mongo.Collection {
 	return r.client.Database(dbName).Collection(collectionName)
 }
@@ -0,0 +1,204 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	defaultSize = 256
+	minSize     = 64
+	maxSize     = 2048
+	// defaultMargin is the quiet zone the QR code specification asks for
+	defaultMargin = 4
+	maxMargin     = 16
+)
+
+// Format is the image format a QR code is rendered in
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options controls how a QR code is rendered. Zero fields use the defaults.
+type Options struct {
+	Format Format
+	// Size is the width and height of the image in pixels
+	Size int
+	// Margin is the width of the quiet zone around the code in modules. Nil uses the default margin.
+	Margin *int
+	// Level is the error correction level: L, M, Q or H
+	Level string
+	// Foreground and Background are hex RGB colors such as #000000
+	Foreground string
+	Background string
+}
+
+// Image is a rendered QR code
+type Image struct {
+	Data        []byte
+	ContentType string
+	// Extension is the file extension of the format, without the dot
+	Extension string
+}
+
+// Render renders the content as a QR code. ErrInvalidQROptions is returned for options out of range and for a size
+// too small to draw every module with at least one pixel.
+func Render(content string, opts Options) (Image, error) {
+	opts, err := withDefaults(opts)
+	if err != nil {
+		return Image{}, err
+	}
+	fg, err := parseColor(opts.Foreground)
+	if err != nil {
+		return Image{}, err
+	}
+	bg, err := parseColor(opts.Background)
+	if err != nil {
+		return Image{}, err
+	}
+	code, err := qrcode.New(content, levels[opts.Level])
+	if err != nil {
+		return Image{}, fmt.Errorf("%w: %s", types.ErrInvalidQROptions, err)
+	}
+	code.DisableBorder = true
+	modules := code.Bitmap()
+	total := len(modules) + 2**opts.Margin
+	if opts.Size < total {
+		return Image{}, fmt.Errorf("%w: size must be at least %d pixels for the content and margin",
+			types.ErrInvalidQROptions, total)
+	}
+	if opts.Format == FormatSVG {
+		return Image{Data: renderSVG(modules, *opts.Margin, opts.Size, fg, bg), ContentType: "image/svg+xml",
+			Extension: "svg"}, nil
+	}
+	data, err := renderPNG(modules, *opts.Margin, opts.Size, fg, bg)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Data: data, ContentType: "image/png", Extension: "png"}, nil
+}
+
+// withDefaults fills the zero options with the defaults and checks the others are in range
+func withDefaults(opts Options) (Options, error) {
+	if opts.Format == "" {
+		opts.Format = FormatPNG
+	}
+	if opts.Format != FormatPNG && opts.Format != FormatSVG {
+		return Options{}, fmt.Errorf("%w: unknown format %q", types.ErrInvalidQROptions, opts.Format)
+	}
+	if opts.Size == 0 {
+		opts.Size = defaultSize
+	}
+	if opts.Size < minSize || opts.Size > maxSize {
+		return Options{}, fmt.Errorf("%w: size must be between %d and %d", types.ErrInvalidQROptions, minSize,
+			maxSize)
+	}
+	if opts.Margin == nil {
+		margin := defaultMargin
+		opts.Margin = &margin
+	}
+	if *opts.Margin < 0 || *opts.Margin > maxMargin {
+		return Options{}, fmt.Errorf("%w: margin must be between 0 and %d", types.ErrInvalidQROptions, maxMargin)
+	}
+	if opts.Level == "" {
+		opts.Level = "M"
+	}
+	opts.Level = strings.ToUpper(opts.Level)
+	if _, ok := levels[opts.Level]; !ok {
+		return Options{}, fmt.Errorf("%w: unknown error correction level %q", types.ErrInvalidQROptions, opts.Level)
+	}
+	if opts.Foreground == "" {
+		opts.Foreground = "#000000"
+	}
+	if opts.Background == "" {
+		opts.Background = "#ffffff"
+	}
+	return opts, nil
+}
+
+// parseColor parses a hex RGB color, with or without the leading #
+func parseColor(hex string) (color.RGBA, error) {
+	hex = strings.TrimPrefix(hex, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("%w: color %q must be 6 hex digits", types.ErrInvalidQROptions, hex)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// layout returns the size of a module in pixels and the offset of the quiet zone, which centers the code when the
+// size is not a multiple of the modules
+func layout(modules, margin, size int) (int, int) {
+	scale := size / (modules + 2*margin)
+	return scale, (size - scale*modules) / 2
+}
+
+func renderPNG(modules [][]bool, margin, size int, fg, bg color.RGBA) ([]byte, error) {
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{bg, fg})
+	scale, offset := layout(len(modules), margin, size)
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := offset + y*scale; py < offset+(y+1)*scale; py++ {
+				for px := offset + x*scale; px < offset+(x+1)*scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG draws the dark modules as one path, joining the modules of a row that touch
+func renderSVG(modules [][]bool, margin, size int, fg, bg color.RGBA) []byte {
+	scale, offset := layout(len(modules), margin, size)
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`shape-rendering="crispEdges">`, size, size, size, size)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="%s"/><path fill="%s" d="`, size, size, hexColor(bg),
+		hexColor(fg))
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(buf, "M%d %dh%dv%dh-%dz", offset+x*scale, offset+y*scale, run*scale, scale, run*scale)
+			x += run - 1
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,113 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+func TestRender(t *testing.T) {
+	a := assert.New(t)
+	content := "http://localhost:8080/f56Cd"
+	noMargin := 0
+
+	testCases := map[string]struct {
+		opts                Options
+		expectedError       error
+		expectedContentType string
+		validate            func(a *assert.Assertions, img Image)
+	}{
+		"png by default": {
+			expectedContentType: "image/png",
+			validate: func(a *assert.Assertions, img Image) {
+				decoded, err := png.Decode(bytes.NewReader(img.Data))
+				a.Nil(err)
+				a.Equal(defaultSize, decoded.Bounds().Dx())
+				a.Equal(defaultSize, decoded.Bounds().Dy())
+				// the corner is in the quiet zone
+				a.Equal(color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, color.RGBAModel.Convert(decoded.At(0, 0)))
+			},
+		},
+		"png colors without margin": {
+			opts:                Options{Size: 100, Margin: &noMargin, Foreground: "1a2b3c", Background: "#FFEEDD"},
+			expectedContentType: "image/png",
+			validate: func(a *assert.Assertions, img Image) {
+				decoded, err := png.Decode(bytes.NewReader(img.Data))
+				a.Nil(err)
+				a.Equal(100, decoded.Bounds().Dx())
+				paletted, ok := decoded.(*image.Paletted)
+				a.True(ok)
+				a.Equal(color.Palette{
+					color.RGBA{R: 0xff, G: 0xee, B: 0xdd, A: 0xff},
+					color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff},
+				}, paletted.Palette)
+				// without a margin the finder pattern starts at the centering offset
+				code, err := qrcode.New(content, qrcode.Medium)
+				a.Nil(err)
+				code.DisableBorder = true
+				_, offset := layout(len(code.Bitmap()), 0, 100)
+				a.Equal(uint8(1), paletted.ColorIndexAt(offset, offset))
+				a.Equal(uint8(0), paletted.ColorIndexAt(offset-1, offset-1))
+			},
+		},
+		"svg": {
+			opts:                Options{Format: FormatSVG, Size: 300, Level: "h", Foreground: "#112233"},
+			expectedContentType: "image/svg+xml",
+			validate: func(a *assert.Assertions, img Image) {
+				svg := string(img.Data)
+				a.True(strings.HasPrefix(svg, "<svg "))
+				a.Contains(svg, `width="300" height="300"`)
+				a.Contains(svg, `fill="#ffffff"`)
+				a.Contains(svg, `<path fill="#112233" d="M`)
+			},
+		},
+		"unknown format": {
+			opts:          Options{Format: "gif"},
+			expectedError: types.ErrInvalidQROptions,
+		},
+		"size too large": {
+			opts:          Options{Size: 4096},
+			expectedError: types.ErrInvalidQROptions,
+		},
+		"margin too large": {
+			opts:          Options{Margin: intPtr(17)},
+			expectedError: types.ErrInvalidQROptions,
+		},
+		"unknown level": {
+			opts:          Options{Level: "X"},
+			expectedError: types.ErrInvalidQROptions,
+		},
+		"invalid color": {
+			opts:          Options{Foreground: "#12345"},
+			expectedError: types.ErrInvalidQROptions,
+		},
+		"size too small for the code": {
+			opts:          Options{Size: 64, Margin: intPtr(16), Level: "H"},
+			expectedError: types.ErrInvalidQROptions,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			img, err := Render(content, testCase.opts)
+			if testCase.expectedError != nil {
+				a.ErrorIs(err, testCase.expectedError)
+				return
+			}
+			a.Nil(err)
+			a.Equal(testCase.expectedContentType, img.ContentType)
+			testCase.validate(a, img)
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
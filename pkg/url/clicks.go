@@ -0,0 +1,109 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// remainingClicksPrefix prefixes the cache key counting the clicks left on a click limited tiny url
+const remainingClicksPrefix = "remaining-clicks:"
+
+// Results of visits, used as metric labels
+const (
+	visitRedirected   = "redirected"
+	visitNotFound     = "not_found"
+	visitGone         = "gone"
+	visitNotYetActive = "not_yet_active"
+	visitPassword     = "password"
+	visitError        = "error"
+)
+
+// seedRemainingClicks caches the clicks left on a new click limited tiny url. When the counter cannot be cached the
+// db enforces the limit instead.
+func (u *urlSVC) seedRemainingClicks(ctx context.Context, tinyURL types.URLDocument) {
+	if tinyURL.MaxClicks <= 0 {
+		return
+	}
+	var ttl time.Duration
+	if !tinyURL.LiveForever {
+		ttl = time.Until(tinyURL.ExpireTime)
+	}
+	key := remainingClicksPrefix + cacheKey(tinyURL.Tenant, tinyURL.URLKey)
+	if err := u.cache.SetCounter(ctx, key, tinyURL.MaxClicks, ttl); err != nil {
+		u.l.Warn("failed to cache remaining clicks", zap.Error(err), zap.String("cache-key", key))
+	}
+}
+
+// useClick counts a redirect of the tiny url. Click limited tiny urls use up one of their remaining clicks, using the
+// cached counter when there is one and the db otherwise. The clicks of the tiny url that the click counter did not add
+// to the db yet are flushed before the db checks the limit, which the db still checks when the flush fails. The tiny
+// url is removed from the cache once its last click is used, and ErrClicksExhausted is returned when no click was left.
+func (u *urlSVC) useClick(ctx context.Context, tinyURL types.URLDocument) error {
+	if tinyURL.MaxClicks <= 0 {
+		u.countClick(ctx, tinyURL)
+		return nil
+	}
+	key := remainingClicksPrefix + cacheKey(tinyURL.Tenant, tinyURL.URLKey)
+	remaining, ok, err := u.cache.DecrementIfPresent(ctx, key)
+	if err != nil {
+		u.l.Warn("failed to decrement remaining clicks", zap.Error(err), zap.String("cache-key", key))
+	}
+	if ok && remaining >= 0 {
+		u.countClick(ctx, tinyURL)
+	}
+	if !ok {
+		// the counter is not cached, the db enforces the limit once it holds every click counted so far
+		if err = u.clicks.Flush(ctx, tinyURL.Tenant, tinyURL.URLKey); err != nil {
+			u.l.Warn("failed to flush clicks", zap.Error(err), zap.String("db-key", tinyURL.URLKey))
+		}
+		var consumed bool
+		remaining, consumed, err = u.repo.ConsumeClick(ctx, tinyURL.Tenant, tinyURL.URLKey)
+		if err != nil {
+			u.l.Error("failed to consume click", zap.Error(err), zap.String("db-key", tinyURL.URLKey))
+			return err
+		}
+		if !consumed {
+			remaining = -1
+		}
+	}
+	if remaining <= 0 {
+		u.uncache(ctx, cacheKey(tinyURL.Tenant, tinyURL.URLKey))
+	}
+	if remaining < 0 {
+		return types.ErrClicksExhausted
+	}
+	return nil
+}
+
+// visitResult returns the result of a visit that ended with the error
+func visitResult(err error) string {
+	if err == nil {
+		return visitRedirected
+	}
+	if _, gone := types.GoneReasonOf(err); gone {
+		return visitGone
+	}
+	switch {
+	case errors.Is(err, types.ErrDocumentNotFound):
+		return visitNotFound
+	case errors.Is(err, types.ErrLinkNotYetActive):
+		return visitNotYetActive
+	case errors.Is(err, types.ErrPasswordRequired), errors.Is(err, types.ErrWrongPassword),
+		errors.Is(err, types.ErrTooManyAttempts):
+		return visitPassword
+	default:
+		return visitError
+	}
+}
+
+// uncache removes the cache entry of the key
+func (u *urlSVC) uncache(ctx context.Context, key string) {
+	if err := u.cache.Delete(ctx, key); err != nil && !errors.Is(err, types.ErrCacheNotFound) {
+		u.l.Warn("failed to delete from cache", zap.Error(err), zap.String("cache-key", key))
+	}
+}
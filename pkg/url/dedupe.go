@@ -0,0 +1,107 @@
+package url
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// defaultPorts are dropped when normalizing a long url
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// dedupeEnabled reports whether the request asks for dedupe. Aliases, password protected, click limited, windowed,
+// interstitial, passthrough, fallback, targeted and split tiny urls always get a tiny url of their own.
+func (u *urlSVC) dedupeEnabled(req types.GenerateRequest) bool {
+	if req.Alias != "" || req.Password != "" || req.MaxClicks > 0 || !req.ActiveFrom.IsZero() ||
+		!req.ActiveUntil.IsZero() || req.Interstitial || req.Passthrough || req.FallbackURL != "" ||
+		len(req.Rules) > 0 || len(req.Variants) > 0 {
+		return false
+	}
+	if req.Dedupe != nil {
+		return *req.Dedupe
+	}
+	return u.cfg.Dedupe
+}
+
+// findExisting returns the tiny url stored with dedupe for the hash. ok is false when there is none or it can no
+// longer be reused, in which case the hash is cleared from it so that a new one can be stored for it.
+func (u *urlSVC) findExisting(ctx context.Context, dedupeHash string) (types.URLDocument, bool, error) {
+	doc, err := u.repo.GetDocumentByDedupeHash(ctx, dedupeHash)
+	if errors.Is(err, types.ErrDocumentNotFound) {
+		return types.URLDocument{}, false, nil
+	}
+	if err != nil {
+		u.l.Error("failed to look up tiny url for dedupe", zap.Error(err), zap.String("dedupe-hash", dedupeHash))
+		return types.URLDocument{}, false, err
+	}
+	if !reusable(doc, time.Now()) {
+		if err = u.repo.ClearDedupeHash(ctx, doc.Tenant, doc.URLKey, dedupeHash); err != nil {
+			return types.URLDocument{}, false, err
+		}
+		return types.URLDocument{}, false, nil
+	}
+	return doc, true, nil
+}
+
+// reusable reports whether dedupe can return the stored tiny url. Tiny urls that expired, are suspended or were given
+// an activation window after they were generated are not returned.
+func reusable(doc types.URLDocument, now time.Time) bool {
+	if (!doc.LiveForever && doc.ExpireTime.Before(now)) || doc.Suspension != nil {
+		return false
+	}
+	return doc.ActiveFrom.IsZero() && doc.ActiveUntil.IsZero()
+}
+
+// dedupeHash returns the hash identifying the long url of the owner in the tenant for dedupe, so that dedupe never
+// returns a tiny url of another owner or tenant
+func dedupeHash(tenant, owner, longURL string) string {
+	key := normalizeURL(longURL)
+	if owner != "" {
+		key = owner + "\x00" + key
+	}
+	if tenant != "" {
+		// the double separator keeps the tenant apart from an owner with the same id
+		key = tenant + "\x00\x00" + key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeURL returns the long url with a lower case scheme and host, without the default port, with an empty path
+// replaced by "/" and with the query parameters sorted. Urls that cannot be parsed are returned as is.
+func normalizeURL(longURL string) string {
+	parsed, err := neturl.Parse(strings.TrimSpace(longURL))
+	if err != nil {
+		return longURL
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Host)
+	if h, port, err := net.SplitHostPort(host); err == nil && defaultPorts[parsed.Scheme] == port {
+		host = h
+		if strings.Contains(h, ":") {
+			// keep ipv6 hosts bracketed
+			host = "[" + h + "]"
+		}
+	}
+	parsed.Host = host
+	if parsed.Path == "" {
+		parsed.Path = "/"
+	}
+	if query, err := neturl.ParseQuery(parsed.RawQuery); err == nil {
+		parsed.RawQuery = query.Encode()
+	}
+	parsed.ForceQuery = false
+	return parsed.String()
+}
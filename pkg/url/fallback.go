@@ -0,0 +1,48 @@
+package url
+
+import (
+	"fmt"
+	neturl "net/url"
+	"time"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const defaultExpiredRetention = time.Hour * 24 * 7 // 7 days
+
+// ValidateFallbackURL checks a fallback url is an http or https url. Empty urls are valid, they leave the fallback
+// unset.
+func ValidateFallbackURL(fallbackURL string) error {
+	if fallbackURL == "" {
+		return nil
+	}
+	dest, err := neturl.Parse(fallbackURL)
+	if err != nil || (dest.Scheme != "http" && dest.Scheme != "https") || dest.Host == "" {
+		return fmt.Errorf("%w: got %q", types.ErrInvalidFallback, fallbackURL)
+	}
+	return nil
+}
+
+// fallbackURL returns where the visits of the tiny url go once it no longer redirects: its own fallback url, or else
+// the default fallback url of its tenant or of the service. It is empty when there is none.
+func (u *urlSVC) fallbackURL(tinyURL types.URLDocument) string {
+	if tinyURL.FallbackURL != "" {
+		return tinyURL.FallbackURL
+	}
+	if fallback, ok := u.cfg.TenantFallbackURLs[tinyURL.Tenant]; ok {
+		return fallback
+	}
+	return u.cfg.FallbackURL
+}
+
+// gone returns the error of a visit of the tiny url, carrying the fallback url of the tiny url when the error is one
+// of a tiny url that no longer redirects
+func (u *urlSVC) gone(tinyURL types.URLDocument, err error) error {
+	if _, ok := types.GoneReasonOf(err); !ok {
+		return err
+	}
+	if fallback := u.fallbackURL(tinyURL); fallback != "" {
+		return &types.LinkGoneError{Err: err, FallbackURL: fallback}
+	}
+	return err
+}
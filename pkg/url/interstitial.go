@@ -0,0 +1,32 @@
+package url
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// ValidateTrustedDomains checks the trusted domains are host names without a scheme, port or path
+func ValidateTrustedDomains(domains []string) error {
+	for _, domain := range domains {
+		if domain == "" || strings.ContainsAny(domain, "/:@?# ") {
+			return fmt.Errorf("%w: trusted domain %q must be a host name", types.ErrInvalidInput, domain)
+		}
+	}
+	return nil
+}
+
+// trustedDomain reports whether the domain is one of the trusted domains or a subdomain of one. Every domain is
+// trusted when there are no trusted domains.
+func trustedDomain(trusted []string, domain string) bool {
+	if len(trusted) == 0 {
+		return true
+	}
+	for _, t := range trusted {
+		if domain == t || strings.HasSuffix(domain, "."+t) {
+			return true
+		}
+	}
+	return false
+}
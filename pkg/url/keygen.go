@@ -0,0 +1,105 @@
+package url
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	// defaultCounterBlockSize is the number of ids a counter key generator reserves per round trip to the db
+	defaultCounterBlockSize = 1000
+	counterSequenceName     = "url_key"
+	// hashKeyBits keeps hash based keys to at most 7 base58 characters
+	hashKeyBits = 40
+	// hashKeyAttempts is how many hash based ids are tried for a long url before falling back to random ids. It is
+	// kept below maxKeyAttempts so that a long url whose hash keys are all taken still gets a key.
+	hashKeyAttempts = 3
+)
+
+type randomKeyGenerator struct {
+	// max bounds the generated ids. When zero the current unix time is used.
+	max int64
+}
+
+// NewRandomKeyGenerator returns a key generator that picks a random id. Collisions are resolved by the service
+// retrying with a new id.
+func NewRandomKeyGenerator() types.KeyGenerator {
+	return &randomKeyGenerator{}
+}
+
+// Generate returns a random id and its base58 key
+func (g *randomKeyGenerator) Generate(_ context.Context, _ string, _ int) (int64, string, error) {
+	limit := g.max
+	if limit <= 0 {
+		limit = time.Now().Unix()
+	}
+	id := rand.Int64N(limit) + 1
+	return id, base58Encode(id), nil
+}
+
+type counterKeyGenerator struct {
+	seq       types.SequenceRepo
+	blockSize int64
+
+	mu   sync.Mutex
+	next int64
+	last int64
+}
+
+// NewCounterKeyGenerator returns a key generator backed by a monotonic sequence. Each instance reserves blockSize
+// ids at a time from the sequence repo, so ids never overlap across instances.
+func NewCounterKeyGenerator(seq types.SequenceRepo, blockSize int64) types.KeyGenerator {
+	if blockSize <= 0 {
+		blockSize = defaultCounterBlockSize
+	}
+	return &counterKeyGenerator{seq: seq, blockSize: blockSize}
+}
+
+// Generate returns the next id of the reserved range and its base58 key. A new range is reserved once the current
+// one is used up.
+func (g *counterKeyGenerator) Generate(ctx context.Context, _ string, _ int) (int64, string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.next == 0 || g.next > g.last {
+		last, err := g.seq.NextRange(ctx, counterSequenceName, g.blockSize)
+		if err != nil {
+			return 0, "", err
+		}
+		g.next, g.last = last-g.blockSize+1, last
+	}
+	id := g.next
+	g.next++
+	return id, base58Encode(id), nil
+}
+
+type hashKeyGenerator struct {
+	fallback randomKeyGenerator
+}
+
+// NewHashKeyGenerator returns a key generator that derives the id from a hash of the long url. The attempt is mixed
+// into the hash so that a collision yields a different key on retry. Once the hash attempts are used up random ids
+// of the same size are handed out instead.
+func NewHashKeyGenerator() types.KeyGenerator {
+	return &hashKeyGenerator{fallback: randomKeyGenerator{max: 1 << hashKeyBits}}
+}
+
+// Generate returns an id derived from the sha256 of the long url and the attempt, and its base58 key
+func (g *hashKeyGenerator) Generate(ctx context.Context, longURL string, attempt int) (int64, string, error) {
+	if attempt >= hashKeyAttempts {
+		return g.fallback.Generate(ctx, longURL, attempt)
+	}
+	input := longURL
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+	id := int64(binary.BigEndian.Uint64(sum[:8]) >> (64 - hashKeyBits))
+	return id, base58Encode(id), nil
+}
@@ -0,0 +1,152 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	concurrentWorkers = 20
+	keysPerWorker     = 50
+)
+
+// generateConcurrently generates tiny urls through the service from several goroutines and returns the keys
+func generateConcurrently(a *assert.Assertions, k types.KeyGenerator) (*types.MockRepo, []string) {
+	ctx := context.Background()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(zap.NewNop(), r, c, k, newClickCounter(r, c), DefaultConfig())
+
+	var mu sync.Mutex
+	keys := make([]string, 0, concurrentWorkers*keysPerWorker)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < concurrentWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < keysPerWorker; i++ {
+				doc, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+					LongURL: fmt.Sprintf("https://abc.io/%d/%d", w, i),
+				})
+				a.Nil(err)
+				mu.Lock()
+				keys = append(keys, doc.URLKey)
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+	return r, keys
+}
+
+func assertUnique(a *assert.Assertions, keys []string) {
+	seen := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		_, dup := seen[k]
+		a.False(dup, "duplicate key %s", k)
+		seen[k] = struct{}{}
+	}
+}
+
+func TestRandomKeyGenerator(t *testing.T) {
+	a := assert.New(t)
+	// a small key space forces collisions which have to be retried
+	r, keys := generateConcurrently(a, &randomKeyGenerator{max: 1 << 16})
+	a.Len(keys, concurrentWorkers*keysPerWorker)
+	a.Len(r.Data, concurrentWorkers*keysPerWorker)
+	assertUnique(a, keys)
+}
+
+func TestCounterKeyGenerator(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	seq := &types.MockSequenceRepo{Data: make(map[string]int64)}
+
+	t.Run("unique across instances", func(t *testing.T) {
+		// two generators sharing the sequence behave like two service instances
+		generators := []types.KeyGenerator{NewCounterKeyGenerator(seq, 7), NewCounterKeyGenerator(seq, 7)}
+		var mu sync.Mutex
+		keys := make([]string, 0, concurrentWorkers*keysPerWorker)
+		wg := new(sync.WaitGroup)
+		for w := 0; w < concurrentWorkers; w++ {
+			wg.Add(1)
+			go func(g types.KeyGenerator) {
+				defer wg.Done()
+				for i := 0; i < keysPerWorker; i++ {
+					id, key, err := g.Generate(ctx, "https://abc.io", 0)
+					a.Nil(err)
+					a.NotZero(id)
+					mu.Lock()
+					keys = append(keys, key)
+					mu.Unlock()
+				}
+			}(generators[w%len(generators)])
+		}
+		wg.Wait()
+		a.Len(keys, concurrentWorkers*keysPerWorker)
+		assertUnique(a, keys)
+	})
+
+	t.Run("unique through the service", func(t *testing.T) {
+		r, keys := generateConcurrently(a, NewCounterKeyGenerator(seq, 10))
+		a.Len(r.Data, concurrentWorkers*keysPerWorker)
+		assertUnique(a, keys)
+	})
+}
+
+func TestHashKeyGenerator(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	g := NewHashKeyGenerator()
+
+	t.Run("deterministic", func(t *testing.T) {
+		id, key, err := g.Generate(ctx, "https://abc.io", 0)
+		a.Nil(err)
+		idAgain, keyAgain, err := g.Generate(ctx, "https://abc.io", 0)
+		a.Nil(err)
+		a.Equal(id, idAgain)
+		a.Equal(key, keyAgain)
+
+		_, retryKey, err := g.Generate(ctx, "https://abc.io", 1)
+		a.Nil(err)
+		a.NotEqual(key, retryKey)
+	})
+
+	t.Run("unique through the service", func(t *testing.T) {
+		r, keys := generateConcurrently(a, g)
+		a.Len(r.Data, concurrentWorkers*keysPerWorker)
+		assertUnique(a, keys)
+	})
+
+	t.Run("same url twice gets a new key", func(t *testing.T) {
+		r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+		c := &types.MockCache{Data: make(map[string]string)}
+		svc := NewTinyURLService(zap.NewNop(), r, c, g, newClickCounter(r, c), DefaultConfig())
+		first, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io"})
+		a.Nil(err)
+		second, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io"})
+		a.Nil(err)
+		a.NotEqual(first.URLKey, second.URLKey)
+	})
+
+	t.Run("same url past the hash attempts falls back to random keys", func(t *testing.T) {
+		r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+		c := &types.MockCache{Data: make(map[string]string)}
+		svc := NewTinyURLService(zap.NewNop(), r, c, g, newClickCounter(r, c), DefaultConfig())
+		keys := make([]string, 0, 2*maxKeyAttempts)
+		for i := 0; i < 2*maxKeyAttempts; i++ {
+			doc, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/again"})
+			a.Nil(err)
+			keys = append(keys, doc.URLKey)
+		}
+		a.Len(r.Data, 2*maxKeyAttempts)
+		assertUnique(a, keys)
+	})
+}
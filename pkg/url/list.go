@@ -0,0 +1,116 @@
+package url
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+// pageCursor is the position a page continues from, along with the order it was listed in so that a cursor cannot
+// be used with another order
+type pageCursor struct {
+	types.ListCursor
+	Sort      types.ListSort `json:"s"`
+	Ascending bool           `json:"a,omitempty"`
+}
+
+// ListTinyURLs returns a page of the tiny urls of the tenant of the context matching the filter. Callers other than
+// admins only list their own tiny urls. One more document than the page holds is read to tell whether there is a next
+// page.
+func (u *urlSVC) ListTinyURLs(ctx context.Context, req types.ListRequest,
+	caller types.Principal) (types.URLPage, error) {
+	if !caller.Admin {
+		if caller.KeyID == "" || (req.Filter.Owner != "" && req.Filter.Owner != caller.KeyID) {
+			return types.URLPage{}, types.ErrForbidden
+		}
+		req.Filter.Owner = caller.KeyID
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return types.URLPage{}, fmt.Errorf("%w: must be between 1 and %d", types.ErrInvalidListLimit, maxListLimit)
+	}
+	switch req.Sort {
+	case "":
+		req.Sort = types.SortCreatedAt
+	case types.SortCreatedAt, types.SortClicks:
+	default:
+		return types.URLPage{}, fmt.Errorf("%w: unknown sort %q", types.ErrInvalidInput, req.Sort)
+	}
+	switch req.Filter.State {
+	case "", types.StateActive, types.StateExpired, types.StateTrashed:
+	default:
+		return types.URLPage{}, fmt.Errorf("%w: unknown state %q", types.ErrInvalidInput, req.Filter.State)
+	}
+	after, err := decodeCursor(req.Cursor, req.Sort, req.Ascending)
+	if err != nil {
+		return types.URLPage{}, err
+	}
+	filter := req.Filter
+	filter.Domain = strings.TrimSuffix(strings.ToLower(filter.Domain), ".")
+	if filter.Tags, err = normalizeTags(filter.Tags); err != nil {
+		return types.URLPage{}, fmt.Errorf("%w: %s", types.ErrInvalidInput, err)
+	}
+	filter.CreatedBy = strings.TrimSpace(filter.CreatedBy)
+	filter.ExternalID = strings.TrimSpace(filter.ExternalID)
+	filter.Search = strings.TrimSpace(filter.Search)
+	docs, err := u.repo.List(ctx, types.ListQuery{
+		Tenant:    types.TenantFromContext(ctx),
+		Filter:    filter,
+		Sort:      req.Sort,
+		Ascending: req.Ascending,
+		Limit:     limit + 1,
+		After:     after,
+	})
+	if err != nil {
+		u.l.Error("failed to list tiny urls", zap.Error(err))
+		return types.URLPage{}, err
+	}
+	if len(docs) <= limit {
+		return types.URLPage{Documents: docs}, nil
+	}
+	last := docs[limit-1]
+	cursor := pageCursor{ListCursor: types.ListCursor{URLKey: last.URLKey}, Sort: req.Sort, Ascending: req.Ascending}
+	if req.Sort == types.SortClicks {
+		cursor.Clicks = last.Clicks
+	} else {
+		cursor.CreatedAt = last.CreatedAt
+	}
+	next, err := json.Marshal(cursor)
+	if err != nil {
+		return types.URLPage{}, err
+	}
+	return types.URLPage{Documents: docs[:limit], NextCursor: base64.RawURLEncoding.EncodeToString(next)}, nil
+}
+
+// decodeCursor decodes the cursor of a page listed in the given order. A nil cursor is returned for the first page.
+func decodeCursor(encoded string, sort types.ListSort, ascending bool) (*types.ListCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, types.ErrInvalidCursor
+	}
+	cursor := pageCursor{}
+	if err = json.Unmarshal(raw, &cursor); err != nil || cursor.URLKey == "" {
+		return nil, types.ErrInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Ascending != ascending {
+		return nil, fmt.Errorf("%w: the cursor was issued for another sort order", types.ErrInvalidCursor)
+	}
+	return &cursor.ListCursor, nil
+}
@@ -0,0 +1,118 @@
+package url
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	maxTitleLength = 200
+	maxNotesLength = 2000
+	maxTags        = 20
+	maxTagLength   = 50
+	// maxIDLength bounds createdBy and externalID
+	maxIDLength = 128
+)
+
+// normalizeMetadata validates the metadata and returns it with the surrounding spaces of its values trimmed and its
+// tags lower cased and unique
+func normalizeMetadata(m types.Metadata) (types.Metadata, error) {
+	var err error
+	if m.Title, err = normalizeText("title", m.Title, maxTitleLength); err != nil {
+		return types.Metadata{}, err
+	}
+	if m.Notes, err = normalizeText("notes", m.Notes, maxNotesLength); err != nil {
+		return types.Metadata{}, err
+	}
+	if m.CreatedBy, err = normalizeID("createdBy", m.CreatedBy); err != nil {
+		return types.Metadata{}, err
+	}
+	if m.ExternalID, err = normalizeID("externalID", m.ExternalID); err != nil {
+		return types.Metadata{}, err
+	}
+	if m.Tags, err = normalizeTags(m.Tags); err != nil {
+		return types.Metadata{}, err
+	}
+	return m, nil
+}
+
+// normalizeMetadataUpdate normalizes the metadata the update sets
+func normalizeMetadataUpdate(update types.URLUpdate) (types.URLUpdate, error) {
+	m := types.Metadata{}
+	if update.Title != nil {
+		m.Title = *update.Title
+	}
+	if update.Notes != nil {
+		m.Notes = *update.Notes
+	}
+	if update.Tags != nil {
+		m.Tags = *update.Tags
+	}
+	if update.CreatedBy != nil {
+		m.CreatedBy = *update.CreatedBy
+	}
+	if update.ExternalID != nil {
+		m.ExternalID = *update.ExternalID
+	}
+	m, err := normalizeMetadata(m)
+	if err != nil {
+		return types.URLUpdate{}, err
+	}
+	if update.Title != nil {
+		update.Title = &m.Title
+	}
+	if update.Notes != nil {
+		update.Notes = &m.Notes
+	}
+	if update.Tags != nil {
+		update.Tags = &m.Tags
+	}
+	if update.CreatedBy != nil {
+		update.CreatedBy = &m.CreatedBy
+	}
+	if update.ExternalID != nil {
+		update.ExternalID = &m.ExternalID
+	}
+	return update, nil
+}
+
+// normalizeTags trims and lower cases the tags and drops the repeated ones. Nil is returned when there are no tags.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", types.ErrInvalidMetadata, maxTags)
+	}
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength || strings.ContainsFunc(tag, unicode.IsControl) {
+			return nil, fmt.Errorf("%w: tags must be 1-%d characters", types.ErrInvalidMetadata, maxTagLength)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+func normalizeText(name, text string, maxLength int) (string, error) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > maxLength {
+		return "", fmt.Errorf("%w: %s must be at most %d characters", types.ErrInvalidMetadata, name, maxLength)
+	}
+	return text, nil
+}
+
+// normalizeID trims an id, which is a single line of text
+func normalizeID(name, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if utf8.RuneCountInString(id) > maxIDLength || strings.ContainsFunc(id, unicode.IsControl) {
+		return "", fmt.Errorf("%w: %s must be at most %d characters without control characters",
+			types.ErrInvalidMetadata, name, maxIDLength)
+	}
+	return id, nil
+}
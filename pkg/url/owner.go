@@ -0,0 +1,35 @@
+package url
+
+import (
+	"context"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// ownedTinyURL returns the stored tiny url of the key of the tenant when the caller may change it. Tiny urls in the
+// trash are not found.
+func (u *urlSVC) ownedTinyURL(ctx context.Context, tenant, urlKey string,
+	caller types.Principal) (types.URLDocument, error) {
+	doc, err := u.ownedDocument(ctx, tenant, urlKey, caller)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if !doc.DeletedAt.IsZero() {
+		return types.URLDocument{}, types.ErrDocumentNotFound
+	}
+	return doc, nil
+}
+
+// ownedDocument returns the stored document of the key of the tenant, in the trash or not, when the caller may change
+// it
+func (u *urlSVC) ownedDocument(ctx context.Context, tenant, urlKey string,
+	caller types.Principal) (types.URLDocument, error) {
+	doc, err := u.repo.GetDocument(ctx, tenant, urlKey)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if !caller.Manages(doc) {
+		return types.URLDocument{}, types.ErrForbidden
+	}
+	return doc, nil
+}
@@ -0,0 +1,54 @@
+package url
+
+import (
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// validateDestinations checks the placeholders of the long url, the rule destinations and the variant urls of a
+// passthrough tiny url. Destinations of other tiny urls are never rendered, so their braces are left alone.
+func validateDestinations(longURL string, rules []types.TargetingRule, variants []types.Variant,
+	passthrough bool) error {
+	if !passthrough {
+		return nil
+	}
+	if err := types.ValidateDestinationTemplate(longURL); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := types.ValidateDestinationTemplate(rule.Destination); err != nil {
+			return err
+		}
+	}
+	for _, v := range variants {
+		if err := types.ValidateDestinationTemplate(v.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDestinationUpdate checks the destinations resulting from applying the update to the stored tiny url
+func validateDestinationUpdate(stored types.URLDocument, update types.URLUpdate) error {
+	longURL, rules, variants, passthrough := stored.LongURL, stored.Rules, stored.Variants, stored.Passthrough
+	if update.LongURL != nil {
+		longURL = *update.LongURL
+	}
+	if update.Rules != nil {
+		rules = *update.Rules
+	}
+	if update.Variants != nil {
+		variants = *update.Variants
+	}
+	if update.Passthrough != nil {
+		passthrough = *update.Passthrough
+	}
+	return validateDestinations(longURL, rules, variants, passthrough)
+}
+
+// checkPath treats visits with a path following the key as not found unless the tiny url passes it through
+func checkPath(tinyURL types.URLDocument, visit types.Visit) error {
+	if visit.Path != "" && !tinyURL.Passthrough {
+		return types.ErrDocumentNotFound
+	}
+	return nil
+}
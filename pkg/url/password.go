@@ -0,0 +1,65 @@
+package url
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	defaultPasswordAttempts      = 5
+	defaultPasswordAttemptWindow = time.Minute * 15
+	// passwordAttemptsPrefix prefixes the cache key counting the wrong passwords of a tiny url. ':' is never part of
+	// a url key, so the counter cannot collide with a cached tiny url.
+	passwordAttemptsPrefix = "password-attempts:"
+	minPasswordLength      = 4
+	// maxPasswordLength is the most bcrypt hashes
+	maxPasswordLength = 72
+)
+
+// hashPassword validates the password and returns its bcrypt hash
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", types.ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword checks the password given for a tiny url. Tiny urls without a password accept any password. Wrong
+// passwords are counted per key and further attempts are refused once the configured limit is reached.
+func (u *urlSVC) checkPassword(ctx context.Context, tinyURL types.URLDocument, password string) error {
+	if tinyURL.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return types.ErrPasswordRequired
+	}
+	key := passwordAttemptsPrefix + cacheKey(tinyURL.Tenant, tinyURL.URLKey)
+	if u.cfg.PasswordAttempts > 0 {
+		attempts, err := u.cache.GetCachedValue(ctx, key)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			u.l.Warn("failed to get password attempts", zap.Error(err), zap.String("cache-key", key))
+		}
+		if n, _ := strconv.Atoi(attempts); n >= u.cfg.PasswordAttempts {
+			return types.ErrTooManyAttempts
+		}
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(tinyURL.PasswordHash), []byte(password)); err != nil {
+		if _, cErr := u.cache.Increment(ctx, key, u.cfg.PasswordAttemptWindow); cErr != nil {
+			u.l.Warn("failed to count password attempt", zap.Error(cErr), zap.String("cache-key", key))
+		}
+		return types.ErrWrongPassword
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const maxReasonLength = 500
+
+// SuspendTinyURL suspends a tiny url of the tenant of the context. The suspended document replaces the cached one, so
+// that visits keep being answered from the cache.
+func (u *urlSVC) SuspendTinyURL(ctx context.Context, urlKey, reason string,
+	caller types.Principal) (types.URLDocument, error) {
+	if !caller.Admin {
+		return types.URLDocument{}, types.ErrForbidden
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return types.URLDocument{}, fmt.Errorf("%w: got %d", types.ErrInvalidReason, utf8.RuneCountInString(reason))
+	}
+	return u.setSuspension(ctx, urlKey, &types.Suspension{At: time.Now(), Reason: reason}, caller)
+}
+
+// UnsuspendTinyURL lifts the suspension of a tiny url of the tenant of the context
+func (u *urlSVC) UnsuspendTinyURL(ctx context.Context, urlKey string,
+	caller types.Principal) (types.URLDocument, error) {
+	if !caller.Admin {
+		return types.URLDocument{}, types.ErrForbidden
+	}
+	return u.setSuspension(ctx, urlKey, nil, caller)
+}
+
+// setSuspension sets or lifts the suspension of a tiny url that is not in the trash
+func (u *urlSVC) setSuspension(ctx context.Context, urlKey string, suspension *types.Suspension,
+	caller types.Principal) (types.URLDocument, error) {
+	tenant := types.TenantFromContext(ctx)
+	stored, err := u.ownedTinyURL(ctx, tenant, urlKey, caller)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if suspension == nil && stored.Suspension == nil {
+		return types.URLDocument{}, types.ErrNotSuspended
+	}
+	doc, err := u.repo.SetSuspension(ctx, tenant, urlKey, suspension)
+	if err != nil {
+		u.l.Error("failed to set suspension", zap.Error(err), zap.String("db-key", urlKey))
+		return types.URLDocument{}, err
+	}
+	u.replaceCached(ctx, tenant, doc)
+	return doc, nil
+}
+
+// checkSuspended returns ErrLinkSuspended for a suspended tiny url
+func checkSuspended(tinyURL types.URLDocument) error {
+	if tinyURL.Suspension != nil {
+		return types.ErrLinkSuspended
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package url
+
+import (
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// maxRules bounds the targeting rules of a tiny url, which are evaluated for every visit
+const maxRules = 20
+
+var (
+	rulePlatforms = map[string]struct{}{
+		types.PlatformIOS:     {},
+		types.PlatformAndroid: {},
+		types.PlatformWindows: {},
+		types.PlatformMacOS:   {},
+		types.PlatformLinux:   {},
+		types.PlatformMobile:  {},
+		types.PlatformDesktop: {},
+	}
+	// platformGroups are the platforms matched by the group platforms of rules
+	platformGroups = map[string]map[string]struct{}{
+		types.PlatformMobile:  {types.PlatformIOS: {}, types.PlatformAndroid: {}},
+		types.PlatformDesktop: {types.PlatformWindows: {}, types.PlatformMacOS: {}, types.PlatformLinux: {}},
+	}
+	languageTag = regexp.MustCompile(`^[a-z]{1,8}(-[a-z0-9]{1,8})*$`)
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// normalizeRules validates the targeting rules and returns them with lower cased platforms and languages and upper
+// cased countries
+func normalizeRules(rules []types.TargetingRule) ([]types.TargetingRule, error) {
+	if len(rules) > maxRules {
+		return nil, fmt.Errorf("%w: at most %d rules", types.ErrInvalidRules, maxRules)
+	}
+	normalized := make([]types.TargetingRule, len(rules))
+	for i, rule := range rules {
+		if len(rule.Platforms)+len(rule.Languages)+len(rule.Countries) == 0 {
+			return nil, fmt.Errorf("%w: rule %d has no criteria", types.ErrInvalidRules, i)
+		}
+		dest, err := neturl.Parse(rule.Destination)
+		if err != nil || (dest.Scheme != "http" && dest.Scheme != "https") || dest.Host == "" {
+			return nil, fmt.Errorf("%w: destination of rule %d must be an http or https url", types.ErrInvalidRules, i)
+		}
+		n := types.TargetingRule{Destination: rule.Destination}
+		for _, p := range rule.Platforms {
+			p = strings.ToLower(p)
+			if _, ok := rulePlatforms[p]; !ok {
+				return nil, fmt.Errorf("%w: unknown platform %q in rule %d", types.ErrInvalidRules, p, i)
+			}
+			n.Platforms = append(n.Platforms, p)
+		}
+		for _, l := range rule.Languages {
+			l = strings.ToLower(l)
+			if !languageTag.MatchString(l) {
+				return nil, fmt.Errorf("%w: invalid language %q in rule %d", types.ErrInvalidRules, l, i)
+			}
+			n.Languages = append(n.Languages, l)
+		}
+		for _, c := range rule.Countries {
+			c = strings.ToUpper(c)
+			if !countryCode.MatchString(c) {
+				return nil, fmt.Errorf("%w: invalid country %q in rule %d", types.ErrInvalidRules, c, i)
+			}
+			n.Countries = append(n.Countries, c)
+		}
+		normalized[i] = n
+	}
+	return normalized, nil
+}
+
+// target returns the tiny url with the destination of the first rule matching the visit as its long url. ok is false
+// when no rule matches.
+func target(tinyURL types.URLDocument, visit types.Visit) (types.URLDocument, bool) {
+	language, country := strings.ToLower(visit.Language), strings.ToUpper(visit.Country)
+	for _, rule := range tinyURL.Rules {
+		if matchesAny(rule.Platforms, visit.Platform, platformMatches) &&
+			matchesAny(rule.Languages, language, languageMatches) &&
+			matchesAny(rule.Countries, country, func(c, visitCountry string) bool { return c == visitCountry }) {
+			tinyURL.LongURL = rule.Destination
+			return tinyURL, true
+		}
+	}
+	return tinyURL, false
+}
+
+// matchesAny reports whether any of the values of a criterion matches the value of the visit. A criterion without
+// values matches every visit, others never match visits for which the value is unknown.
+func matchesAny(values []string, visitValue string, match func(value, visitValue string) bool) bool {
+	if len(values) == 0 {
+		return true
+	}
+	if visitValue == "" {
+		return false
+	}
+	for _, v := range values {
+		if match(v, visitValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func platformMatches(platform, visitPlatform string) bool {
+	if _, ok := platformGroups[platform][visitPlatform]; ok {
+		return true
+	}
+	return platform == visitPlatform
+}
+
+// languageMatches reports whether the language tag is the language of the visit or a less specific tag of it, so
+// that en matches en-us
+func languageMatches(tag, visitLanguage string) bool {
+	return visitLanguage == tag || strings.HasPrefix(visitLanguage, tag+"-")
+}
@@ -0,0 +1,44 @@
+package url
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// tenantFormat restricts tenant ids to lower case letters, digits and '-', so that they never contain the ':'
+// separating them from the key in cache keys
+var tenantFormat = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// reservedTenants are the prefixes of the internal cache keys of tiny urls. A tenant with one of these ids would
+// share its cache keys with the counters of the tiny urls.
+var reservedTenants = map[string]struct{}{
+	strings.TrimSuffix(remainingClicksPrefix, ":"):   {},
+	strings.TrimSuffix(passwordAttemptsPrefix, ":"):  {},
+	strings.TrimSuffix(types.ClickCountsPrefix, ":"): {},
+}
+
+// ValidateTenant checks the id of the tenant is valid and not reserved, and its domain is a host name without a
+// scheme, port or path
+func ValidateTenant(t types.Tenant) error {
+	if !tenantFormat.MatchString(t.ID) {
+		return fmt.Errorf("%w: id %q must be 1-32 lower case letters, digits or '-'", types.ErrInvalidTenant, t.ID)
+	}
+	if _, ok := reservedTenants[t.ID]; ok {
+		return fmt.Errorf("%w: id %q is reserved", types.ErrInvalidTenant, t.ID)
+	}
+	if t.Domain == "" || strings.ContainsAny(t.Domain, "/:@?# ") {
+		return fmt.Errorf("%w: domain %q of %s must be a host name", types.ErrInvalidTenant, t.Domain, t.ID)
+	}
+	return nil
+}
+
+// cacheKey returns the cache key of the url key of a tenant. The keys of the default tenant are cached as they are.
+func cacheKey(tenant, urlKey string) string {
+	if tenant == "" {
+		return urlKey
+	}
+	return tenant + ":" + urlKey
+}
@@ -0,0 +1,95 @@
+package url
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	defaultTrashRetention = time.Hour * 24 * 30 // 30 days
+	defaultPurgeInterval  = time.Hour
+)
+
+// RestoreTinyURL moves a tiny url of the tenant of the context out of the trash and caches it again
+func (u *urlSVC) RestoreTinyURL(ctx context.Context, urlKey string,
+	caller types.Principal) (types.URLDocument, error) {
+	tenant := types.TenantFromContext(ctx)
+	stored, err := u.ownedDocument(ctx, tenant, urlKey, caller)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if stored.DeletedAt.IsZero() {
+		return types.URLDocument{}, types.ErrNotInTrash
+	}
+	doc, err := u.repo.Restore(ctx, tenant, urlKey)
+	if err != nil {
+		u.l.Error("failed to restore tiny url", zap.Error(err), zap.String("db-key", urlKey))
+		return types.URLDocument{}, err
+	}
+	// the clicks left on click limited tiny urls are counted by the db until they are cached again
+	if err = u.cacheTinyURL(ctx, doc); err != nil {
+		u.l.Warn("failed to cache restored tiny url", zap.Error(err), zap.String("cache-key", urlKey))
+	}
+	return doc, nil
+}
+
+// PurgeTinyURL deletes a tiny url of the tenant of the context from the db for good, along with the cached entries and
+// metrics associated with it
+func (u *urlSVC) PurgeTinyURL(ctx context.Context, urlKey string, caller types.Principal) error {
+	if !caller.Admin {
+		return types.ErrForbidden
+	}
+	tenant := types.TenantFromContext(ctx)
+	if err := u.repo.Delete(ctx, tenant, urlKey); err != nil {
+		u.l.Error("failed to delete from db", zap.Error(err), zap.String("db-key", urlKey))
+		return err
+	}
+	u.forget(ctx, tenant, urlKey)
+	return nil
+}
+
+type purger struct {
+	l         *zap.Logger
+	repo      types.URLRepo
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewPurger returns a worker deleting the tiny urls of every tenant that have been in the trash for longer than the
+// retention period. The cache needs no cleaning, tiny urls leave it when they are moved to the trash.
+func NewPurger(l *zap.Logger, r types.URLRepo, cfg types.URLServiceConfig) types.Worker {
+	return &purger{l: l, repo: r, retention: cfg.TrashRetention, interval: cfg.PurgeInterval}
+}
+
+// Run purges the trash right away and then every interval until the context is done
+func (p *purger) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge deletes the tiny urls moved to the trash a retention period before now. Failures are logged and retried on
+// the next run.
+func (p *purger) purge(ctx context.Context, now time.Time) {
+	purged, err := p.repo.PurgeTrash(ctx, now.Add(-p.retention))
+	if err != nil {
+		p.l.Error("failed to purge trash", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		p.l.Info("purged trash", zap.Int64("count", purged))
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/redis/go-redis/v9"
 	"math/big"
-	"math/rand/v2"
+	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"go.uber.org/zap"
 
@@ -19,112 +22,662 @@ import (
 )
 
 const (
-	defaultExpiryTime = time.Hour * 24 * 365 // 1 year
+	defaultExpiryTime   = time.Hour * 24 * 365       // 1 year
+	minExpiryTime       = time.Minute                // 1 minute
+	maxExpiryTime       = time.Hour * 24 * 365 * 5   // 5 years
+	liveForeverTime     = time.Hour * 24 * 365 * 250 // arbitrary 250 years
+	defaultMaxBatchSize = 500
+	// maxKeyAttempts bounds how many keys are tried when generated keys collide with existing ones
+	maxKeyAttempts = 5
+	// tombstoneTTL is how long tiny urls removed from the db stay cached as a tombstone. It outlasts the visits that
+	// read them from the db before they were removed.
+	tombstoneTTL = time.Minute
 )
 
 type urlSVC struct {
-	l       *zap.Logger
-	repo    types.URLRepo
-	cache   types.CacheService
-	counter *prometheus.CounterVec
+	l      *zap.Logger
+	repo   types.URLRepo
+	cache  types.CacheService
+	keys   types.KeyGenerator
+	clicks types.ClickCounter
+	cfg    types.URLServiceConfig
+	// visits counts the visits of each tenant by result. Keys are not used as labels, so that the number of series
+	// stays bounded.
+	visits *prometheus.CounterVec
 }
 
-var base58Chars = []byte("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
+var (
+	base58Chars = []byte("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
+	aliasFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+	// reservedAliases are keys that collide with routes served by the application
+	reservedAliases = map[string]struct{}{
+		"admin":      {},
+		"generate":   {},
+		"healthy":    {},
+		"metrics":    {},
+		"qr":         {},
+		"tinyurlsvc": {},
+		"urls":       {},
+	}
+	redirectStatuses = map[int]struct{}{
+		http.StatusMovedPermanently:  {},
+		http.StatusFound:             {},
+		http.StatusTemporaryRedirect: {},
+		http.StatusPermanentRedirect: {},
+	}
+	referrerPolicies = map[string]struct{}{
+		"no-referrer":                     {},
+		"no-referrer-when-downgrade":      {},
+		"origin":                          {},
+		"origin-when-cross-origin":        {},
+		"same-origin":                     {},
+		"strict-origin":                   {},
+		"strict-origin-when-cross-origin": {},
+		"unsafe-url":                      {},
+	}
+)
+
+// DefaultConfig returns the default settings of the url service
+func DefaultConfig() types.URLServiceConfig {
+	return types.URLServiceConfig{
+		DefaultExpiry: defaultExpiryTime,
+		MinExpiry:     minExpiryTime,
+		MaxExpiry:     maxExpiryTime,
+		Redirect: types.RedirectConfig{
+			Status: http.StatusFound,
+		},
+		MaxBatchSize:          defaultMaxBatchSize,
+		PasswordAttempts:      defaultPasswordAttempts,
+		PasswordAttemptWindow: defaultPasswordAttemptWindow,
+		TrashRetention:        defaultTrashRetention,
+		PurgeInterval:         defaultPurgeInterval,
+		ExpiredRetention:      defaultExpiredRetention,
+	}
+}
 
-// NewTinyURLService return a new url service
-func NewTinyURLService(l *zap.Logger, r types.URLRepo, c types.CacheService) types.URLService {
+// NewTinyURLService return a new url service which uses the key generator for keys that are not aliases and the
+// click counter for the clicks of tiny urls
+func NewTinyURLService(l *zap.Logger, r types.URLRepo, c types.CacheService, k types.KeyGenerator,
+	cc types.ClickCounter, cfg types.URLServiceConfig) types.URLService {
 	svc := &urlSVC{
-		l:     l,
-		repo:  r,
-		cache: c,
-		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Name:      "tiny_url_usage",
+		l:      l,
+		repo:   r,
+		cache:  c,
+		keys:   k,
+		clicks: cc,
+		cfg:    cfg,
+		visits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "visits_total",
 			Namespace: "tiny_url_svc",
-		},
-			[]string{"url_key"}), // labels for cardinality
+			Help:      "visits of tiny urls by tenant and result",
+		}, []string{"tenant", "result"}),
 	}
 	return svc
 }
 
 // RegisterProm registers the metric vector with prometheus
 func (u *urlSVC) RegisterProm() error {
-	return prometheus.Register(u.counter)
+	return prometheus.Register(u.visits)
 }
 
-// GenerateTinyURL generates a tiny url from the given longUrl
-func (u *urlSVC) GenerateTinyURL(ctx context.Context, longURL string, liveForever bool) (types.URLDocument, error) {
-	tinyURL, err := formTinyURL(longURL, liveForever)
+// GenerateTinyURL generates a tiny url of the tenant of the context from the given request. When an alias is requested
+// it is used as the key. With dedupe the tiny url already stored for the long url is returned instead, if it has not
+// expired.
+func (u *urlSVC) GenerateTinyURL(ctx context.Context, req types.GenerateRequest) (types.URLDocument, bool, error) {
+	tinyURL, err := u.newTinyURL(types.TenantFromContext(ctx), req)
 	if err != nil {
+		return types.URLDocument{}, false, err
+	}
+	if tinyURL.DedupeHash != "" {
+		existing, ok, err := u.findExisting(ctx, tinyURL.DedupeHash)
+		if err != nil {
+			return types.URLDocument{}, false, err
+		}
+		if ok {
+			return existing, true, nil
+		}
+	}
+	if tinyURL.URLKey != "" {
+		err = u.repo.Put(ctx, tinyURL)
+		if errors.Is(err, types.ErrDuplicateKey) {
+			return types.URLDocument{}, false, types.ErrAliasTaken
+		}
+	} else {
+		tinyURL, err = u.putWithGeneratedKey(ctx, tinyURL, 0)
+	}
+	if errors.Is(err, types.ErrDuplicateURL) {
+		// a concurrent request stored the long url first
+		if existing, ok, fErr := u.findExisting(ctx, tinyURL.DedupeHash); fErr == nil && ok {
+			return existing, true, nil
+		}
+	}
+	if err != nil {
+		u.l.Error("failed to store tiny url in db", zap.Error(err), zap.String("db-key", req.LongURL))
+		return types.URLDocument{}, false, err
+	}
+	u.l.Info("added url to db", zap.String(tinyURL.LongURL, strconv.FormatInt(tinyURL.Base10ID, 10)))
+	u.seedRemainingClicks(ctx, tinyURL)
+	return tinyURL, false, u.cacheTinyURL(ctx, tinyURL)
+}
+
+// GenerateTinyURLs generates a tiny url for every request. The valid requests are stored with a single write and
+// cached with a single round trip. Generated keys that collide are retried one by one, and requests with dedupe
+// whose long url is already stored get the existing tiny url.
+func (u *urlSVC) GenerateTinyURLs(ctx context.Context, reqs []types.GenerateRequest) ([]types.GenerateResult, error) {
+	if u.cfg.MaxBatchSize > 0 && len(reqs) > u.cfg.MaxBatchSize {
+		return nil, fmt.Errorf("%w: at most %d items", types.ErrBatchTooLarge, u.cfg.MaxBatchSize)
+	}
+	tenant := types.TenantFromContext(ctx)
+	results := make([]types.GenerateResult, len(reqs))
+	// pending maps the documents to write to their index in reqs
+	pending := make([]int, 0, len(reqs))
+	docs := make([]types.URLDocument, 0, len(reqs))
+	for i, req := range reqs {
+		tinyURL, err := u.newTinyURL(tenant, req)
+		if err == nil && tinyURL.DedupeHash != "" {
+			existing, ok, fErr := u.findExisting(ctx, tinyURL.DedupeHash)
+			if ok {
+				results[i] = types.GenerateResult{Document: existing, Existing: true}
+				continue
+			}
+			err = fErr
+		}
+		if err == nil && tinyURL.URLKey == "" {
+			var key string
+			tinyURL.Base10ID, key, err = u.keys.Generate(ctx, tinyURL.LongURL, 0)
+			tinyURL.URLKey = key
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+		docs = append(docs, tinyURL)
+	}
+
+	putErrs, err := u.repo.PutMany(ctx, docs)
+	if err != nil {
+		u.l.Error("failed to store tiny urls in db", zap.Error(err), zap.Int("count", len(docs)))
+		return nil, err
+	}
+	entries := make([]types.CacheEntry, 0, len(docs))
+	for j, i := range pending {
+		tinyURL, putErr := docs[j], putErrs[j]
+		if errors.Is(putErr, types.ErrDuplicateKey) {
+			if reqs[i].Alias != "" {
+				putErr = types.ErrAliasTaken
+			} else {
+				tinyURL, putErr = u.putWithGeneratedKey(ctx, tinyURL, 1)
+			}
+		}
+		if errors.Is(putErr, types.ErrDuplicateURL) {
+			// the long url is stored by another item of the batch or a concurrent request
+			if existing, ok, fErr := u.findExisting(ctx, tinyURL.DedupeHash); fErr == nil && ok {
+				results[i] = types.GenerateResult{Document: existing, Existing: true}
+				continue
+			}
+		}
+		if putErr != nil {
+			results[i].Err = putErr
+			continue
+		}
+		results[i].Document = tinyURL
+		u.seedRemainingClicks(ctx, tinyURL)
+		if entry, ok := u.cacheEntry(tinyURL); ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err = u.cache.CacheMany(ctx, entries); err != nil {
+		u.l.Warn("failed to cache tiny urls", zap.Error(err), zap.Int("count", len(entries)))
+	}
+	return results, nil
+}
+
+// newTinyURL validates the request and forms the tiny url of the tenant it asks for. The key is only set for aliases.
+func (u *urlSVC) newTinyURL(tenant string, req types.GenerateRequest) (types.URLDocument, error) {
+	if req.Alias != "" {
+		if err := validateAlias(req.Alias); err != nil {
+			return types.URLDocument{}, err
+		}
+	}
+	if err := ValidateRedirect(types.RedirectConfig{
+		Status:         req.RedirectStatus,
+		CacheControl:   req.CacheControl,
+		ReferrerPolicy: req.ReferrerPolicy,
+	}); err != nil {
 		return types.URLDocument{}, err
 	}
-	err = u.repo.Put(ctx, tinyURL)
+	expireTime, err := u.expireTime(req)
 	if err != nil {
-		u.l.Error("failed to store tiny url in db", zap.Error(err), zap.String("db-key", longURL))
 		return types.URLDocument{}, err
 	}
-	u.l.Info("added url to db", zap.String(tinyURL.LongURL, strconv.FormatInt(tinyURL.Base10ID, 10)))
-	return tinyURL, u.cacheTinyURL(ctx, tinyURL)
+	if err = validateWindow(req.ActiveFrom, req.ActiveUntil); err != nil {
+		return types.URLDocument{}, err
+	}
+	rules, err := normalizeRules(req.Rules)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	variants, err := normalizeVariants(req.Variants)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	metadata, err := normalizeMetadata(req.Metadata)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = validateDestinations(req.LongURL, rules, variants, req.Passthrough); err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = ValidateFallbackURL(req.FallbackURL); err != nil {
+		return types.URLDocument{}, err
+	}
+	tinyURL := formTinyURL(req.LongURL, req.LiveForever, expireTime)
+	tinyURL.Tenant = tenant
+	tinyURL.UpdatedAt = tinyURL.CreatedAt
+	tinyURL.Metadata = metadata
+	tinyURL.Domain = types.URLDomain(req.LongURL)
+	tinyURL.Owner = req.Owner
+	tinyURL.URLKey = req.Alias
+	tinyURL.RedirectStatus = req.RedirectStatus
+	tinyURL.CacheControl = req.CacheControl
+	tinyURL.ReferrerPolicy = req.ReferrerPolicy
+	tinyURL.ActiveFrom = req.ActiveFrom
+	tinyURL.ActiveUntil = req.ActiveUntil
+	tinyURL.Interstitial = req.Interstitial
+	tinyURL.Passthrough = req.Passthrough
+	tinyURL.FallbackURL = req.FallbackURL
+	if len(rules) > 0 {
+		tinyURL.Rules = rules
+	}
+	tinyURL.Variants = variants
+	if req.MaxClicks < 0 {
+		return types.URLDocument{}, types.ErrInvalidMaxClicks
+	}
+	tinyURL.MaxClicks = req.MaxClicks
+	if req.Password != "" {
+		if tinyURL.PasswordHash, err = hashPassword(req.Password); err != nil {
+			return types.URLDocument{}, err
+		}
+	}
+	if u.dedupeEnabled(req) {
+		tinyURL.DedupeHash = dedupeHash(tenant, req.Owner, req.LongURL)
+	}
+	return tinyURL, nil
+}
+
+// GetTinyURL retrieves a tiny url of the tenant of the context for a visit. Password protected tiny urls are only
+// returned for the right password. Visits of tiny urls that no longer redirect fail with a LinkGoneError when there is
+// a fallback url to send them to.
+func (u *urlSVC) GetTinyURL(ctx context.Context, urlKey string, visit types.Visit) (types.URLDocument, error) {
+	doc, err := u.getTinyURL(ctx, urlKey, visit)
+	u.visits.WithLabelValues(types.TenantFromContext(ctx), visitResult(err)).Inc()
+	return doc, err
 }
 
-// GetTinyURL retrieves a tiny url
-func (u *urlSVC) GetTinyURL(ctx context.Context, urlKey string) (types.URLDocument, error) {
+// getTinyURL retrieves a tiny url of the tenant of the context for a visit
+func (u *urlSVC) getTinyURL(ctx context.Context, urlKey string, visit types.Visit) (types.URLDocument, error) {
 	var cacheAgain bool
-	u.counter.WithLabelValues(urlKey).Inc()
-	cachedURL, err := u.checkCacheForTinyURLDocument(ctx, urlKey)
+	tenant := types.TenantFromContext(ctx)
+	cachedURL, err := u.checkCacheForTinyURLDocument(ctx, cacheKey(tenant, urlKey))
 	if err != nil {
 		cacheAgain = errors.Is(err, redis.Nil)
 		u.l.Warn("failed to get cache for long url", zap.Error(err))
 	}
 	if cachedURL != nil {
-		// Check whether the cached tiny url expired
+		// tiny urls moved to the trash or deleted for good are cached as a tombstone for a while
+		if !cachedURL.DeletedAt.IsZero() {
+			return types.URLDocument{}, types.ErrDocumentNotFound
+		}
+		// Check whether the cached tiny url expired, is suspended or is outside its activation window. Tiny urls with
+		// a fallback url stay cached once they expire to send their visits to it.
+		hasFallback := u.fallbackURL(*cachedURL) != ""
 		if !cachedURL.LiveForever && cachedURL.ExpireTime.Before(time.Now()) {
-			if cErr := u.cache.Delete(ctx, urlKey); cErr != nil && !errors.Is(cErr, types.ErrCacheNotFound) {
-				u.l.Error("failed to delete cache", zap.Error(cErr), zap.String("db-key", urlKey))
+			if !hasFallback {
+				u.uncache(ctx, cacheKey(tenant, urlKey))
 			}
-			return types.URLDocument{}, types.ErrDocumentNotFound
+			return types.URLDocument{}, u.gone(*cachedURL, types.ErrLinkExpired)
+		}
+		if err = checkSuspended(*cachedURL); err != nil {
+			return types.URLDocument{}, u.gone(*cachedURL, err)
+		}
+		if err = checkActive(*cachedURL, time.Now()); err != nil {
+			if errors.Is(err, types.ErrLinkInactive) && !hasFallback {
+				u.uncache(ctx, cacheKey(tenant, urlKey))
+			}
+			return types.URLDocument{}, u.gone(*cachedURL, err)
+		}
+		if err = checkPath(*cachedURL, visit); err != nil {
+			return types.URLDocument{}, err
+		}
+		if err = u.checkPassword(ctx, *cachedURL, visit.Password); err != nil {
+			return types.URLDocument{}, err
+		}
+		if err = u.useClick(ctx, *cachedURL); err != nil {
+			return types.URLDocument{}, u.gone(*cachedURL, err)
 		}
-		return *cachedURL, nil
+		// the cached document holds the rules and variants, so visits are routed without reading the db
+		return u.withRedirectDefaults(u.route(ctx, *cachedURL, visit)), nil
 	}
-	doc, err := u.repo.GetDocument(ctx, urlKey)
+	doc, err := u.repo.GetDocument(ctx, tenant, urlKey)
 	if err != nil {
 		u.l.Error("failed to get tiny url", zap.Error(err), zap.String("db-key", urlKey))
 		return types.URLDocument{}, err
 	}
-	if cacheAgain {
-		err = u.cacheTinyURL(ctx, doc)
-		if err != nil {
-			u.l.Error("failed to cache tiny url", zap.Error(err), zap.String("cache-key", urlKey))
+	if !doc.DeletedAt.IsZero() {
+		return types.URLDocument{}, types.ErrDocumentNotFound
+	}
+	// expired documents are kept for the expired retention before the TTL index of the db removes them
+	if !doc.LiveForever && doc.ExpireTime.Before(time.Now()) {
+		if cacheAgain {
+			// the fallback url of an expired tiny url is served from the cache, others are not cached
+			u.recache(ctx, doc)
 		}
+		return types.URLDocument{}, u.gone(doc, types.ErrLinkExpired)
 	}
-	return doc, nil
+	if err = checkSuspended(doc); err != nil {
+		if cacheAgain {
+			// visits of a suspended tiny url are answered from the cache until it is unsuspended
+			u.recache(ctx, doc)
+		}
+		return types.URLDocument{}, u.gone(doc, err)
+	}
+	if err = checkActive(doc, time.Now()); err != nil {
+		if cacheAgain && (errors.Is(err, types.ErrLinkNotYetActive) || u.fallbackURL(doc) != "") {
+			// visits before the window opens, or sent to the fallback url after it closed, are answered from the cache
+			u.recache(ctx, doc)
+		}
+		return types.URLDocument{}, u.gone(doc, err)
+	}
+	if err = checkPath(doc, visit); err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = u.checkPassword(ctx, doc, visit.Password); err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = u.useClick(ctx, doc); err != nil {
+		return types.URLDocument{}, u.gone(doc, err)
+	}
+	// a click limited tiny url without clicks left is not cached again
+	if cacheAgain && (doc.MaxClicks == 0 || doc.Clicks+1 < doc.MaxClicks) {
+		u.recache(ctx, doc)
+	}
+	return u.withRedirectDefaults(u.route(ctx, doc, visit)), nil
+}
+
+// GetTinyURLStats retrieves the stored document of a tiny url of the tenant of the context owned by the caller, with
+// the current clicks of the tiny url and of its variants. The stats of expired tiny urls stay readable.
+func (u *urlSVC) GetTinyURLStats(ctx context.Context, urlKey string,
+	caller types.Principal) (types.URLDocument, error) {
+	doc, err := u.ownedTinyURL(ctx, types.TenantFromContext(ctx), urlKey, caller)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	return u.withPendingClicks(ctx, doc), nil
+}
+
+// GetTinyURLInfo retrieves the stored document of a tiny url of the tenant of the context from the db, with the
+// current click count
+func (u *urlSVC) GetTinyURLInfo(ctx context.Context, urlKey, password string) (types.URLDocument, error) {
+	doc, err := u.repo.GetDocument(ctx, types.TenantFromContext(ctx), urlKey)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if !doc.DeletedAt.IsZero() {
+		return types.URLDocument{}, types.ErrDocumentNotFound
+	}
+	if !doc.LiveForever && doc.ExpireTime.Before(time.Now()) {
+		return types.URLDocument{}, types.ErrLinkExpired
+	}
+	if err = checkSuspended(doc); err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = u.checkPassword(ctx, doc, password); err != nil {
+		return types.URLDocument{}, err
+	}
+	return u.withPendingClicks(ctx, doc), nil
 }
 
-// DeleteTinyURL deletes a tiny url, the cached entries and metrics associated with it
-func (u *urlSVC) DeleteTinyURL(ctx context.Context, urlKey string) error {
-	err := u.repo.Delete(ctx, urlKey)
+// countClick records a redirect of the tiny url with the click counter, which adds it to the db later
+func (u *urlSVC) countClick(ctx context.Context, tinyURL types.URLDocument) {
+	u.clicks.Count(ctx, tinyURL.Tenant, tinyURL.URLKey)
+}
+
+// withPendingClicks returns the tiny url with the clicks of the tiny url and of its variants the click counter has not
+// added to the db yet. The clicks of the db are returned when the counter fails.
+func (u *urlSVC) withPendingClicks(ctx context.Context, tinyURL types.URLDocument) types.URLDocument {
+	pending, err := u.clicks.Pending(ctx, tinyURL.Tenant, tinyURL.URLKey)
+	if err != nil {
+		u.l.Warn("failed to get pending clicks", zap.Error(err), zap.String("db-key", tinyURL.URLKey))
+		return tinyURL
+	}
+	tinyURL.Clicks += pending
+	if len(tinyURL.Variants) == 0 {
+		return tinyURL
+	}
+	pendingVariants, err := u.clicks.PendingVariants(ctx, tinyURL.Tenant, tinyURL.URLKey, len(tinyURL.Variants))
 	if err != nil {
-		u.l.Error("failed to delete from db", zap.Error(err), zap.String("db-key", urlKey))
+		u.l.Warn("failed to get pending variant clicks", zap.Error(err), zap.String("db-key", tinyURL.URLKey))
+		return tinyURL
+	}
+	variants := make([]types.Variant, len(tinyURL.Variants))
+	for i, v := range tinyURL.Variants {
+		v.Clicks += pendingVariants[i]
+		variants[i] = v
+	}
+	tinyURL.Variants = variants
+	return tinyURL
+}
+
+// DeleteTinyURL moves a tiny url of the tenant of the context to the trash, and deletes the cached entries associated
+// with it so that it stops resolving
+func (u *urlSVC) DeleteTinyURL(ctx context.Context, urlKey string, caller types.Principal) error {
+	tenant := types.TenantFromContext(ctx)
+	if _, err := u.ownedTinyURL(ctx, tenant, urlKey, caller); err != nil {
 		return err
 	}
-	err = u.cache.Delete(ctx, urlKey)
+	err := u.repo.Trash(ctx, tenant, urlKey, time.Now())
 	if err != nil {
-		u.l.Warn("failed to delete from cache", zap.Error(err), zap.String("cache-key", urlKey))
+		u.l.Error("failed to move to trash", zap.Error(err), zap.String("db-key", urlKey))
+		return err
 	}
-	u.counter.DeleteLabelValues(urlKey)
+	u.forget(ctx, tenant, urlKey)
 	return nil
 }
 
+// forget replaces the cached entry of a tiny url that no longer resolves with a tombstone and deletes the clicks left
+// on it. A visit that read the tiny url from the db before it was removed only caches it when no entry is cached, so
+// the tombstone keeps that visit from putting the tiny url back in the cache.
+func (u *urlSVC) forget(ctx context.Context, tenant, urlKey string) {
+	key := cacheKey(tenant, urlKey)
+	tombstone, err := json.Marshal(types.URLDocument{Tenant: tenant, URLKey: urlKey, DeletedAt: time.Now()})
+	if err == nil {
+		err = u.cache.Cache(ctx, key, tombstone, tombstoneTTL)
+	}
+	if err != nil {
+		u.l.Warn("failed to cache tombstone", zap.Error(err), zap.String("cache-key", key))
+		u.uncache(ctx, key)
+	}
+	u.uncache(ctx, remainingClicksPrefix+key)
+}
+
+// UpdateTinyURL updates the destination and expiry of a tiny url of the tenant of the context. The cached entry is
+// rewritten with the updated document so that redirects never serve the old destination once the update succeeds.
+func (u *urlSVC) UpdateTinyURL(ctx context.Context, urlKey string, update types.URLUpdate,
+	caller types.Principal) (types.URLDocument, error) {
+	update, err := u.resolveUpdate(update)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if update.Rules != nil {
+		rules, err := normalizeRules(*update.Rules)
+		if err != nil {
+			return types.URLDocument{}, err
+		}
+		update.Rules = &rules
+	}
+	if update.Variants != nil {
+		variants, err := normalizeVariants(*update.Variants)
+		if err != nil {
+			return types.URLDocument{}, err
+		}
+		update.Variants = &variants
+	}
+	if update, err = normalizeMetadataUpdate(update); err != nil {
+		return types.URLDocument{}, err
+	}
+	if update.FallbackURL != nil {
+		if err = ValidateFallbackURL(*update.FallbackURL); err != nil {
+			return types.URLDocument{}, err
+		}
+	}
+	tenant := types.TenantFromContext(ctx)
+	stored, err := u.ownedTinyURL(ctx, tenant, urlKey, caller)
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = validateWindowUpdate(stored, update); err != nil {
+		return types.URLDocument{}, err
+	}
+	if err = validateDestinationUpdate(stored, update); err != nil {
+		return types.URLDocument{}, err
+	}
+	doc, err := u.repo.Update(ctx, tenant, urlKey, update)
+	if err != nil {
+		u.l.Error("failed to update tiny url", zap.Error(err), zap.String("db-key", urlKey))
+		return types.URLDocument{}, err
+	}
+	u.replaceCached(ctx, tenant, doc)
+	return doc, nil
+}
+
+// replaceCached caches a tiny url that changed in the db. The cached entry is deleted when that fails, so that the
+// previous version of the tiny url is never served.
+func (u *urlSVC) replaceCached(ctx context.Context, tenant string, tinyURL types.URLDocument) {
+	err := u.cacheTinyURL(ctx, tinyURL)
+	if err != nil {
+		key := cacheKey(tenant, tinyURL.URLKey)
+		u.l.Warn("failed to cache updated tiny url", zap.Error(err), zap.String("cache-key", key))
+		if dErr := u.cache.Delete(ctx, key); dErr != nil {
+			u.l.Error("failed to delete stale cache", zap.Error(dErr), zap.String("cache-key", key))
+		}
+	}
+}
+
+// cacheTinyURL caches the tiny url until it expires, replacing any cached entry. The cached entry is deleted when the
+// tiny url is no longer cached, as it would keep serving the previous version of the tiny url until its own TTL.
 func (u *urlSVC) cacheTinyURL(ctx context.Context, tinyURL types.URLDocument) error {
+	entry, ok := u.cacheEntry(tinyURL)
+	if !ok {
+		return u.cache.Delete(ctx, cacheKey(tinyURL.Tenant, tinyURL.URLKey))
+	}
+	return u.cache.Cache(ctx, entry.Key, entry.Value, entry.TTL)
+}
+
+// recache caches a tiny url read from the db with recacheTinyURL, logging failures
+func (u *urlSVC) recache(ctx context.Context, tinyURL types.URLDocument) {
+	if err := u.recacheTinyURL(ctx, tinyURL); err != nil {
+		u.l.Error("failed to cache tiny url", zap.Error(err), zap.String("cache-key", tinyURL.URLKey))
+	}
+}
+
+// recacheTinyURL caches a tiny url read from the db unless an entry was cached in the meantime. A concurrent update
+// caches the updated document first, so it is never overwritten with the document read before the update.
+func (u *urlSVC) recacheTinyURL(ctx context.Context, tinyURL types.URLDocument) error {
+	entry, ok := u.cacheEntry(tinyURL)
+	if !ok {
+		return nil
+	}
+	return u.cache.CacheIfAbsent(ctx, entry.Key, entry.Value, entry.TTL)
+}
+
+// cacheEntry returns the cache entry of the tiny url, which lives until the tiny url expires or its activation
+// window closes. Entries of tiny urls with a fallback url live for the expired retention longer to serve it. ok is
+// false for tiny urls past that point and tiny urls that cannot be encoded.
+func (u *urlSVC) cacheEntry(tinyURL types.URLDocument) (types.CacheEntry, bool) {
+	var grace time.Duration
+	if u.fallbackURL(tinyURL) != "" {
+		grace = u.cfg.ExpiredRetention
+	}
+	ttl := time.Until(tinyURL.ExpireTime) + grace
+	if !tinyURL.LiveForever && ttl <= 0 {
+		return types.CacheEntry{}, false
+	}
+	untilInactive := time.Until(tinyURL.ActiveUntil) + grace
+	if !tinyURL.ActiveUntil.IsZero() && untilInactive < ttl {
+		if untilInactive <= 0 {
+			return types.CacheEntry{}, false
+		}
+		ttl = untilInactive
+	}
 	keyBytes, err := json.Marshal(tinyURL)
 	if err != nil {
-		return err
+		u.l.Error("failed to encode tiny url", zap.Error(err), zap.String("cache-key", tinyURL.URLKey))
+		return types.CacheEntry{}, false
 	}
 	// cache generatedKey -> URLDocument
-	return u.cache.Cache(ctx, tinyURL.URLKey, keyBytes)
+	return types.CacheEntry{Key: cacheKey(tinyURL.Tenant, tinyURL.URLKey), Value: keyBytes, TTL: ttl}, true
+}
+
+// resolveUpdate validates the update and resolves the expiry it asks for. Setting an expiry turns off live forever,
+// and turning off live forever requires an expiry.
+func (u *urlSVC) resolveUpdate(update types.URLUpdate) (types.URLUpdate, error) {
+	if update == (types.URLUpdate{}) {
+		return types.URLUpdate{}, types.ErrEmptyUpdate
+	}
+	if update.ExpireAt == nil && update.LiveForever == nil {
+		return update, nil
+	}
+	if update.ExpireAt == nil && !*update.LiveForever {
+		return types.URLUpdate{}, types.ErrMissingExpiry
+	}
+	req := types.GenerateRequest{}
+	if update.LiveForever != nil {
+		req.LiveForever = *update.LiveForever
+	}
+	if update.ExpireAt != nil {
+		req.ExpireAt = *update.ExpireAt
+	}
+	expireTime, err := u.expireTime(req)
+	if err != nil {
+		return types.URLUpdate{}, err
+	}
+	if req.LiveForever {
+		expireTime = time.Now().Add(liveForeverTime)
+	}
+	update.ExpireAt = &expireTime
+	update.LiveForever = &req.LiveForever
+	return update, nil
 }
 
-func (u *urlSVC) checkCacheForTinyURLDocument(ctx context.Context, urlKey string) (*types.URLDocument, error) {
-	cached, err := u.cache.GetCachedValue(ctx, urlKey)
+// expireTime resolves the expiry requested through either a ttl or an absolute time, bounded by the configured
+// min and max expiry. A zero time is returned for tiny urls that live forever.
+func (u *urlSVC) expireTime(req types.GenerateRequest) (time.Time, error) {
+	now := time.Now()
+	hasTTL, hasExpireAt := req.TTL > 0, !req.ExpireAt.IsZero()
+	if (hasTTL && hasExpireAt) || (req.LiveForever && (hasTTL || hasExpireAt)) {
+		return time.Time{}, types.ErrConflictExpiry
+	}
+	var ttl time.Duration
+	switch {
+	case req.LiveForever:
+		return time.Time{}, nil
+	case hasTTL:
+		ttl = req.TTL
+	case hasExpireAt:
+		ttl = req.ExpireAt.Sub(now)
+	default:
+		return now.Add(u.cfg.DefaultExpiry), nil
+	}
+	if ttl < u.cfg.MinExpiry || ttl > u.cfg.MaxExpiry {
+		return time.Time{}, fmt.Errorf("%w: must be between %s and %s", types.ErrExpiryOutOfRange,
+			u.cfg.MinExpiry, u.cfg.MaxExpiry)
+	}
+	return now.Add(ttl), nil
+}
+
+func (u *urlSVC) checkCacheForTinyURLDocument(ctx context.Context, key string) (*types.URLDocument, error) {
+	cached, err := u.cache.GetCachedValue(ctx, key)
 	if err != nil {
 		return nil, err
 	}
@@ -139,26 +692,87 @@ func (u *urlSVC) checkCacheForTinyURLDocument(ctx context.Context, urlKey string
 	return cachedURL, err
 }
 
-func formTinyURL(longURL string, liveForever bool) (types.URLDocument, error) {
-	cTime := time.Now()
-	id := rand.Int64N(cTime.Unix())
-	base58String := base58Encode(id)
-	// Should not happen
-	if base58String == "" {
-		return types.URLDocument{}, types.ErrInvalidInput
+// putWithGeneratedKey stores the tiny url under a key from the key generator, retrying with a new key when the
+// generated one is already taken. firstAttempt is the attempt passed to the key generator first.
+func (u *urlSVC) putWithGeneratedKey(ctx context.Context, tinyURL types.URLDocument,
+	firstAttempt int) (types.URLDocument, error) {
+	var err error
+	for attempt := firstAttempt; attempt < firstAttempt+maxKeyAttempts; attempt++ {
+		var base58String string
+		tinyURL.Base10ID, base58String, err = u.keys.Generate(ctx, tinyURL.LongURL, attempt)
+		if err != nil {
+			return types.URLDocument{}, err
+		}
+		// Should not happen
+		if base58String == "" {
+			return types.URLDocument{}, types.ErrInvalidInput
+		}
+		tinyURL.URLKey = base58String
+		err = u.repo.Put(ctx, tinyURL)
+		if !errors.Is(err, types.ErrDuplicateKey) {
+			break
+		}
+		u.l.Warn("generated key collided, retrying", zap.String("db-key", base58String), zap.Int("attempt", attempt))
+	}
+	if err != nil {
+		return types.URLDocument{}, err
+	}
+	return tinyURL, nil
+}
+
+// withRedirectDefaults fills the redirect settings the tiny url does not set with the service wide settings. Tiny
+// urls pointing to untrusted domains get the interstitial.
+func (u *urlSVC) withRedirectDefaults(doc types.URLDocument) types.URLDocument {
+	if doc.RedirectStatus == 0 {
+		doc.RedirectStatus = u.cfg.Redirect.Status
+	}
+	if doc.CacheControl == "" {
+		doc.CacheControl = u.cfg.Redirect.CacheControl
+	}
+	if doc.ReferrerPolicy == "" {
+		doc.ReferrerPolicy = u.cfg.Redirect.ReferrerPolicy
+	}
+	if !doc.Interstitial && !trustedDomain(u.cfg.TrustedDomains, types.URLDomain(doc.LongURL)) {
+		doc.Interstitial = true
+	}
+	return doc
+}
+
+// ValidateRedirect checks the redirect status is a redirect and the headers are valid. Empty settings are valid.
+func ValidateRedirect(r types.RedirectConfig) error {
+	if _, ok := redirectStatuses[r.Status]; r.Status != 0 && !ok {
+		return fmt.Errorf("%w: unsupported redirect status %d", types.ErrInvalidRedirect, r.Status)
+	}
+	if _, ok := referrerPolicies[r.ReferrerPolicy]; r.ReferrerPolicy != "" && !ok {
+		return fmt.Errorf("%w: unsupported referrer policy %q", types.ErrInvalidRedirect, r.ReferrerPolicy)
+	}
+	if strings.ContainsFunc(r.CacheControl, unicode.IsControl) {
+		return fmt.Errorf("%w: cache control contains control characters", types.ErrInvalidRedirect)
 	}
+	return nil
+}
+
+func validateAlias(alias string) error {
+	if !aliasFormat.MatchString(alias) {
+		return types.ErrInvalidAlias
+	}
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return types.ErrReservedAlias
+	}
+	return nil
+}
+
+func formTinyURL(longURL string, liveForever bool, expireTime time.Time) types.URLDocument {
 	urlObj := types.URLDocument{
-		Base10ID: id,
-		LongURL:  longURL,
-		URLKey:   base58String,
+		LongURL:    longURL,
+		ExpireTime: expireTime,
+		CreatedAt:  time.Now(),
 	}
 	if liveForever {
 		urlObj.LiveForever = true
-		urlObj.ExpireTime = cTime.Add(time.Hour * 24 * 365 * 250) // arbitrary 250 years
-	} else {
-		urlObj.ExpireTime = cTime.Add(defaultExpiryTime)
+		urlObj.ExpireTime = time.Now().Add(liveForeverTime)
 	}
-	return urlObj, nil
+	return urlObj
 }
 
 func base58Encode(n int64) string {
@@ -1,17 +1,41 @@
 package url
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/vaishakdinesh/tiny-url-svc/pkg/clicks"
 	"github.com/vaishakdinesh/tiny-url-svc/types"
 )
 
+// admin is the caller of the tests that are not about ownership
+var admin = types.Principal{KeyID: "admin", Admin: true}
+
+// newClickCounter returns a click counter adding the clicks it counts in the cache to the repo
+func newClickCounter(r types.URLRepo, c types.CacheService) types.ClickCounter {
+	return clicks.NewCounter(zap.NewNop(), r, c, clicks.DefaultCounterConfig())
+}
+
+// unflushedCounter is a click counter that fails to add the clicks of a tiny url to the repo, as when the cache is down
+type unflushedCounter struct {
+	types.ClickCounter
+}
+
+func (unflushedCounter) Flush(context.Context, string, string) error {
+	return errors.New("cache unavailable")
+}
+
 func TestEncoder(t *testing.T) {
 	testCases := map[string]struct {
 		input    int64
@@ -71,7 +95,13 @@ func TestGenerateTinyURL(t *testing.T) {
 	testCases := map[string]struct {
 		lURL          string
 		liveForever   bool
+		alias         string
+		ttl           time.Duration
+		expireAt      time.Time
+		redirect      types.RedirectConfig
 		expectedError bool
+		expectedErr   error
+		pre           func()
 	}{
 		"gen url": {
 			lURL: "https://abc.io",
@@ -98,28 +128,348 @@ func TestGenerateTinyURL(t *testing.T) {
 			lURL:          types.GetFail,
 			expectedError: true,
 		},
+		"gen url with alias": {
+			lURL:  "https://abc.io/sale",
+			alias: "spring-sale",
+		},
+		"alias taken": {
+			lURL:  "https://abc.io/sale",
+			alias: "summer_sale",
+			pre: func() {
+				r.Data["summer_sale"] = types.URLDocument{URLKey: "summer_sale"}
+			},
+			expectedError: true,
+			expectedErr:   types.ErrAliasTaken,
+		},
+		"reserved alias": {
+			lURL:          "https://abc.io/sale",
+			alias:         "Generate",
+			expectedError: true,
+			expectedErr:   types.ErrReservedAlias,
+		},
+		"alias too short": {
+			lURL:          "https://abc.io/sale",
+			alias:         "ab",
+			expectedError: true,
+			expectedErr:   types.ErrInvalidAlias,
+		},
+		"alias invalid charset": {
+			lURL:          "https://abc.io/sale",
+			alias:         "sale/2024",
+			expectedError: true,
+			expectedErr:   types.ErrInvalidAlias,
+		},
+		"gen url with ttl": {
+			lURL: "https://abc.io",
+			ttl:  time.Hour,
+		},
+		"gen url with expire at": {
+			lURL:     "https://abc.io",
+			expireAt: time.Now().Add(time.Hour * 48),
+		},
+		"ttl and expire at": {
+			lURL:          "https://abc.io",
+			ttl:           time.Hour,
+			expireAt:      time.Now().Add(time.Hour * 48),
+			expectedError: true,
+			expectedErr:   types.ErrConflictExpiry,
+		},
+		"live forever and ttl": {
+			lURL:          "https://abc.io",
+			liveForever:   true,
+			ttl:           time.Hour,
+			expectedError: true,
+			expectedErr:   types.ErrConflictExpiry,
+		},
+		"ttl below min": {
+			lURL:          "https://abc.io",
+			ttl:           time.Second,
+			expectedError: true,
+			expectedErr:   types.ErrExpiryOutOfRange,
+		},
+		"gen url with redirect settings": {
+			lURL: "https://abc.io",
+			redirect: types.RedirectConfig{
+				Status:         http.StatusPermanentRedirect,
+				CacheControl:   "no-store",
+				ReferrerPolicy: "origin",
+			},
+		},
+		"invalid redirect status": {
+			lURL:          "https://abc.io",
+			redirect:      types.RedirectConfig{Status: http.StatusSeeOther},
+			expectedError: true,
+			expectedErr:   types.ErrInvalidRedirect,
+		},
+		"invalid cache control": {
+			lURL:          "https://abc.io",
+			redirect:      types.RedirectConfig{CacheControl: "no-store\r\nSet-Cookie: a=b"},
+			expectedError: true,
+			expectedErr:   types.ErrInvalidRedirect,
+		},
+		"expire at beyond max": {
+			lURL:          "https://abc.io",
+			expireAt:      time.Now().Add(maxExpiryTime * 2),
+			expectedError: true,
+			expectedErr:   types.ErrExpiryOutOfRange,
+		},
 	}
 
-	svc := NewTinyURLService(l, r, c)
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
 	a.NotNil(svc)
 
 	for name, testCase := range testCases {
 		t.Run(name, func(t *testing.T) {
-			tURL, err := svc.GenerateTinyURL(ctx, testCase.lURL, testCase.liveForever)
+			if testCase.pre != nil {
+				testCase.pre()
+			}
+			tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+				LongURL:        testCase.lURL,
+				LiveForever:    testCase.liveForever,
+				Alias:          testCase.alias,
+				TTL:            testCase.ttl,
+				ExpireAt:       testCase.expireAt,
+				RedirectStatus: testCase.redirect.Status,
+				CacheControl:   testCase.redirect.CacheControl,
+				ReferrerPolicy: testCase.redirect.ReferrerPolicy,
+			})
 			if !testCase.expectedError {
 				a.Nil(err)
-				a.NotEmpty(tURL.Base10ID)
-				a.NotEmpty(tURL.URLKey)
+				if testCase.alias != "" {
+					a.Equal(testCase.alias, tURL.URLKey)
+				} else {
+					a.NotEmpty(tURL.Base10ID)
+					a.NotEmpty(tURL.URLKey)
+				}
 				if !testCase.liveForever {
 					a.NotEmpty(tURL.ExpireTime)
 				}
+				a.Equal(testCase.redirect.Status, tURL.RedirectStatus)
+				a.Equal(testCase.redirect.CacheControl, tURL.CacheControl)
+				switch {
+				case testCase.ttl > 0:
+					a.WithinDuration(time.Now().Add(testCase.ttl), tURL.ExpireTime, time.Second)
+				case !testCase.expireAt.IsZero():
+					a.WithinDuration(testCase.expireAt, tURL.ExpireTime, time.Second)
+				}
 			} else {
 				a.Error(err)
+				if testCase.expectedErr != nil {
+					a.ErrorIs(err, testCase.expectedErr)
+				}
 			}
 		})
 	}
 }
 
+func TestGenerateTinyURLs(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	r.Data["taken-alias"] = types.URLDocument{URLKey: "taken-alias"}
+	cfg := DefaultConfig()
+	cfg.MaxBatchSize = 10
+	// the hash strategy generates the same key for the same url, which collides within the batch
+	svc := NewTinyURLService(l, r, c, NewHashKeyGenerator(), newClickCounter(r, c), cfg)
+
+	results, err := svc.GenerateTinyURLs(ctx, []types.GenerateRequest{
+		{LongURL: "https://abc.io"},
+		{LongURL: "https://abc.io"},
+		{LongURL: "https://abc.io/sale", Alias: "batch-alias"},
+		{LongURL: "https://abc.io/sale", Alias: "batch-alias"},
+		{LongURL: "https://abc.io/sale", Alias: "taken-alias"},
+		{LongURL: "https://abc.io/sale", Alias: "generate"},
+		{LongURL: "https://abc.io", TTL: time.Second},
+		{LongURL: types.StoreFail},
+	})
+	a.Nil(err)
+	a.Len(results, 8)
+
+	a.Nil(results[0].Err)
+	a.Nil(results[1].Err)
+	a.NotEmpty(results[0].Document.URLKey)
+	a.NotEqual(results[0].Document.URLKey, results[1].Document.URLKey)
+	a.Nil(results[2].Err)
+	a.Equal("batch-alias", results[2].Document.URLKey)
+	a.ErrorIs(results[3].Err, types.ErrAliasTaken)
+	a.ErrorIs(results[4].Err, types.ErrAliasTaken)
+	a.ErrorIs(results[5].Err, types.ErrReservedAlias)
+	a.ErrorIs(results[6].Err, types.ErrExpiryOutOfRange)
+	a.Error(results[7].Err)
+
+	for _, i := range []int{0, 1, 2} {
+		key := results[i].Document.URLKey
+		a.Contains(r.Data, key)
+		a.Contains(c.Data, key)
+	}
+
+	_, err = svc.GenerateTinyURLs(ctx, make([]types.GenerateRequest, cfg.MaxBatchSize+1))
+	a.ErrorIs(err, types.ErrBatchTooLarge)
+}
+
+func TestGenerateTinyURLDedupe(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	dedupe, noDedupe := true, false
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+
+	first, existing, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+		LongURL: "https://abc.io/a?x=1&y=2",
+		Dedupe:  &dedupe,
+	})
+	a.Nil(err)
+	a.False(existing)
+	a.NotEmpty(first.DedupeHash)
+
+	testCases := map[string]struct {
+		req         types.GenerateRequest
+		expectedKey bool
+		existing    bool
+	}{
+		"same url": {
+			req:      types.GenerateRequest{LongURL: "https://abc.io/a?x=1&y=2", Dedupe: &dedupe},
+			existing: true,
+		},
+		"normalized url": {
+			req:      types.GenerateRequest{LongURL: "HTTPS://ABC.io:443/a?y=2&x=1", Dedupe: &dedupe},
+			existing: true,
+		},
+		"without dedupe": {
+			req: types.GenerateRequest{LongURL: "https://abc.io/a?x=1&y=2", Dedupe: &noDedupe},
+		},
+		"service default": {
+			req: types.GenerateRequest{LongURL: "https://abc.io/a?x=1&y=2"},
+		},
+		"alias": {
+			req: types.GenerateRequest{LongURL: "https://abc.io/a?x=1&y=2", Alias: "dedupe-alias", Dedupe: &dedupe},
+		},
+		"different path": {
+			req: types.GenerateRequest{LongURL: "https://abc.io/b?x=1&y=2", Dedupe: &dedupe},
+		},
+		"activation window": {
+			req: types.GenerateRequest{LongURL: "https://abc.io/a?x=1&y=2", Dedupe: &dedupe,
+				ActiveFrom: time.Now().Add(time.Hour)},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tURL, existing, err := svc.GenerateTinyURL(ctx, testCase.req)
+			a.Nil(err)
+			a.Equal(testCase.existing, existing)
+			if testCase.existing {
+				a.Equal(first.URLKey, tURL.URLKey)
+			} else {
+				a.NotEqual(first.URLKey, tURL.URLKey)
+			}
+		})
+	}
+
+	t.Run("expired url is replaced", func(t *testing.T) {
+		expired := r.Data[first.URLKey]
+		expired.ExpireTime = time.Now().Add(-time.Minute)
+		r.Data[first.URLKey] = expired
+		tURL, existing, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/a?x=1&y=2",
+			Dedupe: &dedupe})
+		a.Nil(err)
+		a.False(existing)
+		a.NotEqual(first.URLKey, tURL.URLKey)
+		a.Empty(r.Data[first.URLKey].DedupeHash)
+	})
+
+	t.Run("suspended url is replaced", func(t *testing.T) {
+		req := types.GenerateRequest{LongURL: "https://abc.io/suspended", Dedupe: &dedupe}
+		plain, _, err := svc.GenerateTinyURL(ctx, req)
+		a.Nil(err)
+		_, err = svc.SuspendTinyURL(ctx, plain.URLKey, "", admin)
+		a.Nil(err)
+		tURL, existing, err := svc.GenerateTinyURL(ctx, req)
+		a.Nil(err)
+		a.False(existing)
+		a.NotEqual(plain.URLKey, tURL.URLKey)
+		a.Empty(r.Data[plain.URLKey].DedupeHash)
+	})
+
+	t.Run("url given an activation window is replaced", func(t *testing.T) {
+		req := types.GenerateRequest{LongURL: "https://abc.io/window", Dedupe: &dedupe}
+		plain, _, err := svc.GenerateTinyURL(ctx, req)
+		a.Nil(err)
+		activeFrom := time.Now().Add(time.Hour)
+		_, err = svc.UpdateTinyURL(ctx, plain.URLKey, types.URLUpdate{ActiveFrom: &activeFrom}, admin)
+		a.Nil(err)
+		tURL, existing, err := svc.GenerateTinyURL(ctx, req)
+		a.Nil(err)
+		a.False(existing)
+		a.NotEqual(plain.URLKey, tURL.URLKey)
+		a.Empty(r.Data[plain.URLKey].DedupeHash)
+	})
+
+	t.Run("concurrent requests share a url", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Dedupe = true
+		r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+		svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), cfg)
+		var mu sync.Mutex
+		keys := make(map[string]struct{})
+		wg := new(sync.WaitGroup)
+		for w := 0; w < concurrentWorkers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/race"})
+				a.Nil(err)
+				mu.Lock()
+				keys[tURL.URLKey] = struct{}{}
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		a.Len(keys, 1)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		results, err := svc.GenerateTinyURLs(ctx, []types.GenerateRequest{
+			{LongURL: "https://abc.io/batch", Dedupe: &dedupe},
+			{LongURL: "https://abc.io/batch", Dedupe: &dedupe},
+			{LongURL: "https://abc.io/b?y=2&x=1", Dedupe: &dedupe},
+		})
+		a.Nil(err)
+		a.Nil(results[0].Err)
+		a.False(results[0].Existing)
+		a.Nil(results[1].Err)
+		a.True(results[1].Existing)
+		a.Equal(results[0].Document.URLKey, results[1].Document.URLKey)
+		a.Nil(results[2].Err)
+		a.True(results[2].Existing)
+	})
+}
+
+func TestNormalizeURL(t *testing.T) {
+	a := assert.New(t)
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"unchanged":         {input: "https://abc.io/a?x=1", expected: "https://abc.io/a?x=1"},
+		"case":              {input: "HTTPS://Abc.IO/Path", expected: "https://abc.io/Path"},
+		"default port":      {input: "http://abc.io:80/a", expected: "http://abc.io/a"},
+		"other port":        {input: "http://abc.io:8080/a", expected: "http://abc.io:8080/a"},
+		"empty path":        {input: "https://abc.io", expected: "https://abc.io/"},
+		"query order":       {input: "https://abc.io/?b=2&a=1&a=0", expected: "https://abc.io/?a=1&a=0&b=2"},
+		"empty query":       {input: "https://abc.io/?", expected: "https://abc.io/"},
+		"ipv6 default port": {input: "https://[::1]:443/a", expected: "https://[::1]/a"},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			a.Equal(testCase.expected, normalizeURL(testCase.input))
+		})
+	}
+}
+
 func TestGetTinyURL(t *testing.T) {
 	ctx := context.Background()
 	a := assert.New(t)
@@ -144,7 +494,7 @@ func TestGetTinyURL(t *testing.T) {
 					Base10ID:   1029208386,
 					URLKey:     "342dLy",
 					LongURL:    "https://foo.com?id=1",
-					ExpireTime: time.Now(),
+					ExpireTime: time.Now().Add(time.Hour),
 				}
 			},
 		},
@@ -176,23 +526,30 @@ func TestGetTinyURL(t *testing.T) {
 				c.Data["GdMuR"] = string(bytes)
 			},
 			expectError: true,
-			expectedErr: types.ErrDocumentNotFound,
+			expectedErr: types.ErrLinkExpired,
 		},
 	}
 
-	svc := NewTinyURLService(l, r, c)
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
 	a.NotNil(svc)
 	for name, testCase := range testCases {
 		t.Run(name, func(t *testing.T) {
 			if testCase.pre != nil {
 				testCase.pre(a)
 			}
-			tURL, err := svc.GetTinyURL(ctx, testCase.urlKey)
+			tURL, err := svc.GetTinyURL(ctx, testCase.urlKey, types.Visit{})
 			if !testCase.expectError {
 				a.Nil(err)
+				if _, ok := r.Data[testCase.urlKey]; ok {
+					stats, err := svc.GetTinyURLStats(ctx, testCase.urlKey, admin)
+					a.Nil(err)
+					a.Equal(int64(1), stats.Clicks)
+				}
 				a.NotEmpty(tURL.Base10ID)
 				a.NotEmpty(tURL.URLKey)
 				a.NotEmpty(tURL.ExpireTime)
+				// the service wide redirect status applies to tiny urls that do not set one
+				a.Equal(http.StatusFound, tURL.RedirectStatus)
 			} else {
 				a.Error(err)
 				a.Equal(err, testCase.expectedErr)
@@ -201,6 +558,966 @@ func TestGetTinyURL(t *testing.T) {
 	}
 }
 
+func TestGetTinyURLPassword(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	cfg := DefaultConfig()
+	cfg.PasswordAttempts = 2
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), cfg)
+
+	_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io", Password: "abc"})
+	a.ErrorIs(err, types.ErrInvalidPassword)
+
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io", Password: "s3cret"})
+	a.Nil(err)
+	a.NotEmpty(tURL.PasswordHash)
+	a.NotContains(tURL.PasswordHash, "s3cret")
+	a.NotContains(c.Data[tURL.URLKey], "s3cret")
+
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrPasswordRequired)
+	_, err = svc.GetTinyURLInfo(ctx, tURL.URLKey, "")
+	a.ErrorIs(err, types.ErrPasswordRequired)
+
+	got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{Password: "s3cret"})
+	a.Nil(err)
+	a.Equal("https://abc.io", got.LongURL)
+	stats, err := svc.GetTinyURLStats(ctx, tURL.URLKey, admin)
+	a.Nil(err)
+	a.Equal(int64(1), stats.Clicks)
+
+	// the cached document is checked the same way as the stored one
+	delete(c.Data, tURL.URLKey)
+	for i := 0; i < cfg.PasswordAttempts; i++ {
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{Password: "wrong"})
+		a.ErrorIs(err, types.ErrWrongPassword)
+	}
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{Password: "s3cret"})
+	a.ErrorIs(err, types.ErrTooManyAttempts)
+	_, err = svc.GetTinyURLInfo(ctx, tURL.URLKey, "s3cret")
+	a.ErrorIs(err, types.ErrTooManyAttempts)
+	stats, err = svc.GetTinyURLStats(ctx, tURL.URLKey, admin)
+	a.Nil(err)
+	a.Equal(int64(1), stats.Clicks)
+}
+
+func TestGetTinyURLMaxClicks(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+
+	testCases := map[string]struct {
+		maxClicks int64
+		pre       func(tinyURL types.URLDocument)
+	}{
+		"one-time link": {
+			maxClicks: 1,
+		},
+		"cached counter": {
+			maxClicks: 3,
+		},
+		"db fallback": {
+			maxClicks: 3,
+			pre: func(tinyURL types.URLDocument) {
+				delete(c.Data, remainingClicksPrefix+tinyURL.URLKey)
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+				LongURL:   "https://abc.io/invite",
+				MaxClicks: testCase.maxClicks,
+			})
+			a.Nil(err)
+			if testCase.pre != nil {
+				testCase.pre(tURL)
+			}
+			for i := int64(0); i < testCase.maxClicks; i++ {
+				_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+				a.Nil(err)
+			}
+			a.NotContains(c.Data, tURL.URLKey)
+			_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+			a.ErrorIs(err, types.ErrClicksExhausted)
+			a.NotContains(c.Data, tURL.URLKey)
+			stats, err := svc.GetTinyURLStats(ctx, tURL.URLKey, admin)
+			a.Nil(err)
+			a.Equal(testCase.maxClicks, stats.Clicks)
+		})
+	}
+
+	t.Run("db fallback counts the clicks not flushed yet", func(t *testing.T) {
+		tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/invite", MaxClicks: 3})
+		a.Nil(err)
+		for i := 0; i < 2; i++ {
+			_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+			a.Nil(err)
+		}
+		// the cached counter is lost before the click counter adds the clicks to the db
+		a.Zero(r.Data[tURL.URLKey].Clicks)
+		delete(c.Data, remainingClicksPrefix+tURL.URLKey)
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+		a.Nil(err)
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+		a.ErrorIs(err, types.ErrClicksExhausted)
+		a.Equal(int64(3), r.Data[tURL.URLKey].Clicks)
+	})
+
+	t.Run("db fallback enforces the limit when clicks cannot be flushed", func(t *testing.T) {
+		svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), unflushedCounter{newClickCounter(r, c)},
+			DefaultConfig())
+		tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/invite", MaxClicks: 1})
+		a.Nil(err)
+		delete(c.Data, remainingClicksPrefix+tURL.URLKey)
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+		a.Nil(err)
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+		a.ErrorIs(err, types.ErrClicksExhausted)
+	})
+
+	t.Run("concurrent clicks do not overshoot", func(t *testing.T) {
+		tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/invite", MaxClicks: 5})
+		a.Nil(err)
+		var mu sync.Mutex
+		var redirected int64
+		wg := new(sync.WaitGroup)
+		for w := 0; w < concurrentWorkers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{}); err == nil {
+					mu.Lock()
+					redirected++
+					mu.Unlock()
+				} else {
+					a.ErrorIs(err, types.ErrClicksExhausted)
+				}
+			}()
+		}
+		wg.Wait()
+		a.Equal(int64(5), redirected)
+	})
+
+	_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io", MaxClicks: -1})
+	a.ErrorIs(err, types.ErrInvalidMaxClicks)
+}
+
+func TestGetTinyURLActiveWindow(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	now := time.Now()
+
+	testCases := map[string]struct {
+		activeFrom    time.Time
+		activeUntil   time.Time
+		pre           func(tinyURL types.URLDocument)
+		expectedError error
+	}{
+		"within window": {
+			activeFrom:  now.Add(-time.Hour),
+			activeUntil: now.Add(time.Hour),
+		},
+		"not active yet": {
+			activeFrom:    now.Add(time.Hour),
+			expectedError: types.ErrLinkNotYetActive,
+		},
+		"not active yet from db": {
+			activeFrom: now.Add(time.Hour),
+			pre: func(tinyURL types.URLDocument) {
+				delete(c.Data, tinyURL.URLKey)
+			},
+			expectedError: types.ErrLinkNotYetActive,
+		},
+		"window closed": {
+			activeUntil: now.Add(time.Hour),
+			pre: func(tinyURL types.URLDocument) {
+				doc := r.Data[tinyURL.URLKey]
+				doc.ActiveUntil = now.Add(-time.Minute)
+				r.Data[tinyURL.URLKey] = doc
+				bytes, err := json.Marshal(doc)
+				a.Nil(err)
+				c.Data[tinyURL.URLKey] = string(bytes)
+			},
+			expectedError: types.ErrLinkExpired,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+				LongURL:     "https://abc.io/launch",
+				ActiveFrom:  testCase.activeFrom,
+				ActiveUntil: testCase.activeUntil,
+			})
+			a.Nil(err)
+			if testCase.pre != nil {
+				testCase.pre(tURL)
+			}
+			_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+			if testCase.expectedError == nil {
+				a.Nil(err)
+				return
+			}
+			a.ErrorIs(err, testCase.expectedError)
+			var notYetActive *types.LinkNotYetActiveError
+			if errors.As(err, &notYetActive) {
+				a.True(notYetActive.ActiveFrom.Equal(testCase.activeFrom))
+			} else {
+				a.NotContains(c.Data, tURL.URLKey)
+			}
+		})
+	}
+
+	t.Run("invalid window", func(t *testing.T) {
+		_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+			LongURL:     "https://abc.io/launch",
+			ActiveFrom:  now.Add(time.Hour),
+			ActiveUntil: now.Add(time.Minute),
+		})
+		a.ErrorIs(err, types.ErrInvalidWindow)
+	})
+
+	t.Run("update one bound against the stored one", func(t *testing.T) {
+		tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+			LongURL:     "https://abc.io/launch",
+			ActiveUntil: now.Add(time.Hour),
+		})
+		a.Nil(err)
+		activeFrom := now.Add(2 * time.Hour)
+		_, err = svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{ActiveFrom: &activeFrom}, admin)
+		a.ErrorIs(err, types.ErrInvalidWindow)
+		activeFrom = now.Add(time.Minute)
+		updated, err := svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{ActiveFrom: &activeFrom}, admin)
+		a.Nil(err)
+		a.True(updated.ActiveFrom.Equal(activeFrom))
+	})
+
+	t.Run("window closed by an update", func(t *testing.T) {
+		tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io/launch"})
+		a.Nil(err)
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+		a.Nil(err)
+		activeUntil := now.Add(-time.Minute)
+		_, err = svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{ActiveUntil: &activeUntil}, admin)
+		a.Nil(err)
+		a.NotContains(c.Data, tURL.URLKey)
+		_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+		a.ErrorIs(err, types.ErrLinkExpired)
+	})
+}
+
+func TestGetTinyURLInterstitial(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	cfg := DefaultConfig()
+	cfg.TrustedDomains = []string{"foo.com"}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), cfg)
+
+	testCases := map[string]struct {
+		req                  types.GenerateRequest
+		expectedInterstitial bool
+	}{
+		"trusted domain": {
+			req: types.GenerateRequest{LongURL: "https://foo.com/sale"},
+		},
+		"trusted subdomain": {
+			req: types.GenerateRequest{LongURL: "https://shop.FOO.com/sale"},
+		},
+		"untrusted domain": {
+			req:                  types.GenerateRequest{LongURL: "https://evilfoo.com/sale"},
+			expectedInterstitial: true,
+		},
+		"interstitial asked for a trusted domain": {
+			req:                  types.GenerateRequest{LongURL: "https://foo.com/sale", Interstitial: true},
+			expectedInterstitial: true,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tURL, _, err := svc.GenerateTinyURL(ctx, testCase.req)
+			a.Nil(err)
+			// only the interstitial asked for is stored, the policy applies when the tiny url is followed
+			a.Equal(testCase.req.Interstitial, r.Data[tURL.URLKey].Interstitial)
+			got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+			a.Nil(err)
+			a.Equal(testCase.expectedInterstitial, got.Interstitial)
+			// the tiny url is read from the cache the second time
+			got, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+			a.Nil(err)
+			a.Equal(testCase.expectedInterstitial, got.Interstitial)
+		})
+	}
+
+	// every domain is trusted without a policy
+	svc = NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://evilfoo.com/sale"})
+	a.Nil(err)
+	got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.Nil(err)
+	a.False(got.Interstitial)
+
+	// interstitial tiny urls are not deduplicated
+	dedupe := true
+	first, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://bar.com", Dedupe: &dedupe})
+	a.Nil(err)
+	second, existing, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://bar.com",
+		Dedupe: &dedupe, Interstitial: true})
+	a.Nil(err)
+	a.False(existing)
+	a.NotEqual(first.URLKey, second.URLKey)
+}
+
+func TestGetTinyURLPassthrough(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+
+	testCases := map[string]struct {
+		req         types.GenerateRequest
+		expectedErr error
+	}{
+		"template": {
+			req: types.GenerateRequest{LongURL: "https://foo.com/{path}?ref={query.ref}#{query}", Passthrough: true},
+		},
+		"braces without passthrough": {
+			req: types.GenerateRequest{LongURL: "https://foo.com/{other}"},
+		},
+		"unknown placeholder": {
+			req:         types.GenerateRequest{LongURL: "https://foo.com/{other}", Passthrough: true},
+			expectedErr: types.ErrInvalidTemplate,
+		},
+		"invalid query parameter": {
+			req:         types.GenerateRequest{LongURL: "https://foo.com/?ref={query.}", Passthrough: true},
+			expectedErr: types.ErrInvalidTemplate,
+		},
+		"placeholder in the host": {
+			req:         types.GenerateRequest{LongURL: "https://foo.com{path}", Passthrough: true},
+			expectedErr: types.ErrInvalidTemplate,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := svc.GenerateTinyURL(ctx, testCase.req)
+			if testCase.expectedErr != nil {
+				a.ErrorIs(err, testCase.expectedErr)
+				return
+			}
+			a.Nil(err)
+		})
+	}
+
+	plain, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com/{other}"})
+	a.Nil(err)
+	// the cached and the stored document both refuse a trailing path
+	for i := 0; i < 2; i++ {
+		_, err = svc.GetTinyURL(ctx, plain.URLKey, types.Visit{Path: "extra"})
+		a.ErrorIs(err, types.ErrDocumentNotFound)
+		delete(c.Data, plain.URLKey)
+	}
+	stats, err := svc.GetTinyURLStats(ctx, plain.URLKey, admin)
+	a.Nil(err)
+	a.Zero(stats.Clicks)
+
+	passthrough := true
+	_, err = svc.UpdateTinyURL(ctx, plain.URLKey, types.URLUpdate{Passthrough: &passthrough}, admin)
+	a.ErrorIs(err, types.ErrInvalidTemplate)
+	longURL := "https://foo.com/{path}"
+	doc, err := svc.UpdateTinyURL(ctx, plain.URLKey, types.URLUpdate{LongURL: &longURL, Passthrough: &passthrough},
+		admin)
+	a.Nil(err)
+	a.True(doc.Passthrough)
+	got, err := svc.GetTinyURL(ctx, plain.URLKey, types.Visit{Path: "extra"})
+	a.Nil(err)
+	a.Equal("https://foo.com/extra", got.Destination("extra", nil))
+}
+
+func TestGetTinyURLTargeting(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+		LongURL: "https://foo.com",
+		Rules: []types.TargetingRule{
+			{Platforms: []string{"iOS"}, Destination: "https://apps.apple.com/app/id1"},
+			{Platforms: []string{"android"}, Destination: "https://play.google.com/store/apps/details?id=foo"},
+			{Languages: []string{"FR"}, Countries: []string{"ca"}, Destination: "https://foo.com/fr-ca"},
+			{Platforms: []string{"desktop"}, Languages: []string{"de"}, Destination: "https://foo.com/de"},
+		},
+	})
+	a.Nil(err)
+	a.Equal([]string{"ios"}, r.Data[tURL.URLKey].Rules[0].Platforms)
+	a.Equal([]string{"CA"}, r.Data[tURL.URLKey].Rules[2].Countries)
+
+	testCases := map[string]struct {
+		visit               types.Visit
+		expectedDestination string
+	}{
+		"ios": {
+			visit:               types.Visit{Platform: types.PlatformIOS, Language: "de"},
+			expectedDestination: "https://apps.apple.com/app/id1",
+		},
+		"android": {
+			visit:               types.Visit{Platform: types.PlatformAndroid},
+			expectedDestination: "https://play.google.com/store/apps/details?id=foo",
+		},
+		"language and country": {
+			visit:               types.Visit{Platform: types.PlatformWindows, Language: "fr-ca", Country: "CA"},
+			expectedDestination: "https://foo.com/fr-ca",
+		},
+		"language without the country": {
+			visit:               types.Visit{Platform: types.PlatformWindows, Language: "fr", Country: "FR"},
+			expectedDestination: "https://foo.com",
+		},
+		"platform group": {
+			visit:               types.Visit{Platform: types.PlatformLinux, Language: "de-AT"},
+			expectedDestination: "https://foo.com/de",
+		},
+		"unknown client": {
+			expectedDestination: "https://foo.com",
+		},
+	}
+	// the rules of the cached document are used, not the ones stored in the db
+	stored := r.Data[tURL.URLKey]
+	stored.Rules = nil
+	r.Data[tURL.URLKey] = stored
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := svc.GetTinyURL(ctx, tURL.URLKey, testCase.visit)
+			a.Nil(err)
+			a.Equal(testCase.expectedDestination, got.LongURL)
+		})
+	}
+
+	invalidRules := map[string][]types.TargetingRule{
+		"no criteria":         {{Destination: "https://foo.com"}},
+		"unknown platform":    {{Platforms: []string{"beos"}, Destination: "https://foo.com"}},
+		"invalid language":    {{Languages: []string{"en_US"}, Destination: "https://foo.com"}},
+		"invalid country":     {{Countries: []string{"USA"}, Destination: "https://foo.com"}},
+		"invalid destination": {{Countries: []string{"US"}, Destination: "ftp://foo.com"}},
+		"too many rules":      make([]types.TargetingRule, maxRules+1),
+	}
+	for i := range invalidRules["too many rules"] {
+		invalidRules["too many rules"][i] = types.TargetingRule{Countries: []string{"US"}, Destination: "https://a.io"}
+	}
+	for name, rules := range invalidRules {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com", Rules: rules})
+			a.ErrorIs(err, types.ErrInvalidRules)
+		})
+	}
+
+	// an empty list removes the rules
+	doc, err := svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{Rules: &[]types.TargetingRule{}}, admin)
+	a.Nil(err)
+	a.Empty(doc.Rules)
+	got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{Platform: types.PlatformIOS})
+	a.Nil(err)
+	a.Equal("https://foo.com", got.LongURL)
+}
+
+func TestGetTinyURLVariants(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	owner := types.Principal{KeyID: "owner"}
+
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+		LongURL: "https://foo.com",
+		Rules:   []types.TargetingRule{{Platforms: []string{"ios"}, Destination: "https://apps.apple.com/app/id1"}},
+		Variants: []types.Variant{
+			{URL: "https://foo.com/a", Weight: 70, Clicks: 5},
+			{URL: "https://foo.com/b", Weight: 30},
+		},
+		Owner: owner.KeyID,
+	})
+	a.Nil(err)
+	a.Zero(r.Data[tURL.URLKey].Variants[0].Clicks)
+
+	// a visitor keeps its variant
+	first, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{VisitorID: "visitor"})
+	a.Nil(err)
+	for i := 0; i < 5; i++ {
+		got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{VisitorID: "visitor"})
+		a.Nil(err)
+		a.Equal(first.LongURL, got.LongURL)
+	}
+	// visitors are split by weight
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{VisitorID: fmt.Sprintf("visitor-%d", i)})
+		a.Nil(err)
+		counts[got.LongURL]++
+	}
+	a.InDelta(700, counts["https://foo.com/a"], 60)
+	a.InDelta(300, counts["https://foo.com/b"], 60)
+	// matching rules take precedence and do not count as variant clicks
+	got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{Platform: types.PlatformIOS, VisitorID: "visitor"})
+	a.Nil(err)
+	a.Equal("https://apps.apple.com/app/id1", got.LongURL)
+	// variant clicks are counted in the cache and only reach the db when the click counter flushes
+	a.Zero(r.Data[tURL.URLKey].Variants[0].Clicks)
+	a.Zero(r.Data[tURL.URLKey].Variants[1].Clicks)
+
+	_, err = svc.GetTinyURLStats(ctx, tURL.URLKey, types.Principal{KeyID: "other"})
+	a.ErrorIs(err, types.ErrForbidden)
+	stats, err := svc.GetTinyURLStats(ctx, tURL.URLKey, owner)
+	a.Nil(err)
+	a.Equal(int64(1007), stats.Clicks)
+	a.Len(stats.Variants, 2)
+	counts[first.LongURL] += 6
+	for _, v := range stats.Variants {
+		a.Equal(int64(counts[v.URL]), v.Clicks, v.URL)
+	}
+
+	invalidVariants := map[string][]types.Variant{
+		"single variant": {{URL: "https://foo.com/a", Weight: 1}},
+		"zero weight":    {{URL: "https://foo.com/a", Weight: 0}, {URL: "https://foo.com/b", Weight: 1}},
+		"invalid url":    {{URL: "ftp://foo.com/a", Weight: 1}, {URL: "https://foo.com/b", Weight: 1}},
+		"too many":       make([]types.Variant, maxVariants+1),
+	}
+	for i := range invalidVariants["too many"] {
+		invalidVariants["too many"][i] = types.Variant{URL: "https://foo.com", Weight: 1}
+	}
+	for name, variants := range invalidVariants {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com", Variants: variants})
+			a.ErrorIs(err, types.ErrInvalidVariants)
+		})
+	}
+
+	// an empty list removes the variants
+	doc, err := svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{Variants: &[]types.Variant{}}, owner)
+	a.Nil(err)
+	a.Empty(doc.Variants)
+	got, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{VisitorID: "visitor"})
+	a.Nil(err)
+	a.Equal("https://foo.com", got.LongURL)
+}
+
+func TestListTinyURLs(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	now := time.Now()
+	for i, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		domain := "foo.com"
+		if i%2 == 1 {
+			domain = "bar.com"
+		}
+		r.Data[key] = types.URLDocument{
+			URLKey:     key,
+			LongURL:    "https://" + domain + "/" + key,
+			Domain:     domain,
+			CreatedAt:  now.Add(time.Duration(i) * time.Minute),
+			ExpireTime: now.Add(time.Duration(i-1) * time.Hour),
+			// k4 and k5 have the same number of clicks, they are ordered by key
+			Clicks: int64(10 - min(i, 3)),
+		}
+	}
+
+	// listAll follows the cursors until the last page and returns the keys in the order they were listed
+	listAll := func(req types.ListRequest) []string {
+		var keys []string
+		for {
+			page, err := svc.ListTinyURLs(ctx, req, admin)
+			a.Nil(err)
+			a.LessOrEqual(len(page.Documents), cmp.Or(req.Limit, defaultListLimit))
+			for _, doc := range page.Documents {
+				keys = append(keys, doc.URLKey)
+			}
+			if page.NextCursor == "" {
+				return keys
+			}
+			req.Cursor = page.NextCursor
+		}
+	}
+
+	testCases := map[string]struct {
+		req          types.ListRequest
+		expectedKeys []string
+	}{
+		"newest first": {
+			req:          types.ListRequest{Limit: 2},
+			expectedKeys: []string{"k5", "k4", "k3", "k2", "k1"},
+		},
+		"oldest first": {
+			req:          types.ListRequest{Limit: 2, Ascending: true},
+			expectedKeys: []string{"k1", "k2", "k3", "k4", "k5"},
+		},
+		"most clicked first": {
+			req:          types.ListRequest{Limit: 1, Sort: types.SortClicks},
+			expectedKeys: []string{"k1", "k2", "k3", "k5", "k4"},
+		},
+		"domain": {
+			req:          types.ListRequest{Limit: 2, Filter: types.URLFilter{Domain: "FOO.com"}},
+			expectedKeys: []string{"k5", "k3", "k1"},
+		},
+		"created range": {
+			req: types.ListRequest{Filter: types.URLFilter{
+				CreatedAfter:  now.Add(time.Minute),
+				CreatedBefore: now.Add(3 * time.Minute),
+			}},
+			expectedKeys: []string{"k3", "k2"},
+		},
+		"expired": {
+			req:          types.ListRequest{Filter: types.URLFilter{State: types.StateExpired}},
+			expectedKeys: []string{"k2", "k1"},
+		},
+		"active expiring soon": {
+			req: types.ListRequest{Filter: types.URLFilter{
+				State:         types.StateActive,
+				ExpiresBefore: now.Add(3 * time.Hour),
+			}},
+			expectedKeys: []string{"k4", "k3"},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			a.Equal(testCase.expectedKeys, listAll(testCase.req))
+		})
+	}
+
+	t.Run("invalid requests", func(t *testing.T) {
+		page, err := svc.ListTinyURLs(ctx, types.ListRequest{Limit: 1}, admin)
+		a.Nil(err)
+		a.NotEmpty(page.NextCursor)
+		_, err = svc.ListTinyURLs(ctx, types.ListRequest{Limit: 1, Cursor: page.NextCursor, Sort: types.SortClicks}, admin)
+		a.ErrorIs(err, types.ErrInvalidCursor)
+		_, err = svc.ListTinyURLs(ctx, types.ListRequest{Cursor: "not a cursor"}, admin)
+		a.ErrorIs(err, types.ErrInvalidCursor)
+		_, err = svc.ListTinyURLs(ctx, types.ListRequest{Limit: maxListLimit + 1}, admin)
+		a.ErrorIs(err, types.ErrInvalidListLimit)
+	})
+}
+
+func TestTinyURLMetadata(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+
+	generate := func(alias string, metadata types.Metadata) types.URLDocument {
+		doc, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+			LongURL:  "https://foo.com/" + alias,
+			Alias:    alias,
+			Metadata: metadata,
+		})
+		a.Nil(err)
+		return doc
+	}
+	spring := generate("spring", types.Metadata{
+		Title:      " Spring sale ",
+		Notes:      "Sent with the April newsletter",
+		Tags:       []string{"Sale", "email ", "sale"},
+		CreatedBy:  "jane",
+		ExternalID: "campaign-1",
+	})
+	a.Equal(types.Metadata{
+		Title:      "Spring sale",
+		Notes:      "Sent with the April newsletter",
+		Tags:       []string{"sale", "email"},
+		CreatedBy:  "jane",
+		ExternalID: "campaign-1",
+	}, r.Data["spring"].Metadata)
+	a.Equal(spring.CreatedAt, spring.UpdatedAt)
+	generate("summer", types.Metadata{Title: "Summer sale", Tags: []string{"sale", "social"}, CreatedBy: "joe"})
+	generate("docs", types.Metadata{Notes: "printed on the manual"})
+
+	testCases := map[string]struct {
+		filter       types.URLFilter
+		expectedKeys []string
+	}{
+		"tag": {
+			filter:       types.URLFilter{Tags: []string{"SALE"}},
+			expectedKeys: []string{"spring", "summer"},
+		},
+		"all the tags": {
+			filter:       types.URLFilter{Tags: []string{"sale", "social"}},
+			expectedKeys: []string{"summer"},
+		},
+		"created by": {
+			filter:       types.URLFilter{CreatedBy: "jane"},
+			expectedKeys: []string{"spring"},
+		},
+		"external id": {
+			filter:       types.URLFilter{ExternalID: "campaign-1"},
+			expectedKeys: []string{"spring"},
+		},
+		"search": {
+			filter:       types.URLFilter{Search: "Summer manual"},
+			expectedKeys: []string{"summer", "docs"},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			page, err := svc.ListTinyURLs(ctx, types.ListRequest{Filter: testCase.filter, Ascending: true}, admin)
+			a.Nil(err)
+			var keys []string
+			for _, doc := range page.Documents {
+				keys = append(keys, doc.URLKey)
+			}
+			a.ElementsMatch(testCase.expectedKeys, keys)
+		})
+	}
+
+	invalidMetadata := map[string]types.Metadata{
+		"long title":      {Title: strings.Repeat("a", maxTitleLength+1)},
+		"long notes":      {Notes: strings.Repeat("a", maxNotesLength+1)},
+		"empty tag":       {Tags: []string{" "}},
+		"long tag":        {Tags: []string{strings.Repeat("a", maxTagLength+1)}},
+		"too many tags":   {Tags: strings.Split(strings.Repeat("a,", maxTags)+"b", ",")},
+		"multi line id":   {ExternalID: "a\nb"},
+		"long created by": {CreatedBy: strings.Repeat("a", maxIDLength+1)},
+	}
+	for name, metadata := range invalidMetadata {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com", Metadata: metadata})
+			a.ErrorIs(err, types.ErrInvalidMetadata)
+		})
+	}
+	_, err := svc.ListTinyURLs(ctx, types.ListRequest{Filter: types.URLFilter{Tags: []string{""}}}, admin)
+	a.ErrorIs(err, types.ErrInvalidInput)
+
+	// empty values remove the metadata
+	title, empty := "Spring sale 2", ""
+	doc, err := svc.UpdateTinyURL(ctx, "spring", types.URLUpdate{Title: &title, Notes: &empty, Tags: &[]string{}},
+		admin)
+	a.Nil(err)
+	a.Equal(types.Metadata{Title: "Spring sale 2", CreatedBy: "jane", ExternalID: "campaign-1"}, doc.Metadata)
+	a.True(doc.UpdatedAt.After(doc.CreatedAt))
+}
+
+func TestTinyURLOwnership(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	owner := types.Principal{KeyID: "owner"}
+	other := types.Principal{KeyID: "other"}
+
+	owned, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io", Owner: owner.KeyID})
+	a.Nil(err)
+	a.Equal(owner.KeyID, r.Data[owned.URLKey].Owner)
+	unowned, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io"})
+	a.Nil(err)
+
+	longURL := "https://abc.io/updated"
+	testCases := map[string]struct {
+		urlKey        string
+		caller        types.Principal
+		expectedError error
+	}{
+		"owner": {
+			urlKey: owned.URLKey,
+			caller: owner,
+		},
+		"admin": {
+			urlKey: owned.URLKey,
+			caller: admin,
+		},
+		"other key": {
+			urlKey:        owned.URLKey,
+			caller:        other,
+			expectedError: types.ErrForbidden,
+		},
+		"no owner": {
+			urlKey:        unowned.URLKey,
+			caller:        other,
+			expectedError: types.ErrForbidden,
+		},
+		"not found": {
+			urlKey:        "Fh6Ty",
+			caller:        owner,
+			expectedError: types.ErrDocumentNotFound,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.UpdateTinyURL(ctx, testCase.urlKey, types.URLUpdate{LongURL: &longURL}, testCase.caller)
+			a.ErrorIs(err, testCase.expectedError)
+		})
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		a.ErrorIs(svc.DeleteTinyURL(ctx, owned.URLKey, other), types.ErrForbidden)
+		a.Contains(r.Data, owned.URLKey)
+		a.True(r.Data[owned.URLKey].DeletedAt.IsZero())
+		a.Nil(svc.DeleteTinyURL(ctx, owned.URLKey, owner))
+		a.False(r.Data[owned.URLKey].DeletedAt.IsZero())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://abc.io", Owner: other.KeyID})
+		a.Nil(err)
+		page, err := svc.ListTinyURLs(ctx, types.ListRequest{}, other)
+		a.Nil(err)
+		a.Len(page.Documents, 1)
+		a.Equal(other.KeyID, page.Documents[0].Owner)
+		_, err = svc.ListTinyURLs(ctx, types.ListRequest{Filter: types.URLFilter{Owner: owner.KeyID}}, other)
+		a.ErrorIs(err, types.ErrForbidden)
+		page, err = svc.ListTinyURLs(ctx, types.ListRequest{Filter: types.URLFilter{Owner: other.KeyID}}, admin)
+		a.Nil(err)
+		a.Len(page.Documents, 1)
+	})
+
+	t.Run("dedupe is scoped to the owner", func(t *testing.T) {
+		dedupe := true
+		req := types.GenerateRequest{LongURL: "https://abc.io/shared", Dedupe: &dedupe, Owner: owner.KeyID}
+		first, _, err := svc.GenerateTinyURL(ctx, req)
+		a.Nil(err)
+		req.Owner = other.KeyID
+		second, existing, err := svc.GenerateTinyURL(ctx, req)
+		a.Nil(err)
+		a.False(existing)
+		a.NotEqual(first.URLKey, second.URLKey)
+	})
+}
+
+func TestValidateTenant(t *testing.T) {
+	a := assert.New(t)
+	testCases := map[string]struct {
+		tenant        types.Tenant
+		expectedError error
+	}{
+		"valid": {
+			tenant: types.Tenant{ID: "brand-a", Domain: "brand-a.link"},
+		},
+		"invalid id": {
+			tenant:        types.Tenant{ID: "Brand:A", Domain: "brand-a.link"},
+			expectedError: types.ErrInvalidTenant,
+		},
+		"reserved remaining clicks": {
+			tenant:        types.Tenant{ID: "remaining-clicks", Domain: "brand-a.link"},
+			expectedError: types.ErrInvalidTenant,
+		},
+		"reserved password attempts": {
+			tenant:        types.Tenant{ID: "password-attempts", Domain: "brand-a.link"},
+			expectedError: types.ErrInvalidTenant,
+		},
+		"reserved click counts": {
+			tenant:        types.Tenant{ID: "click-counts", Domain: "brand-a.link"},
+			expectedError: types.ErrInvalidTenant,
+		},
+		"domain with port": {
+			tenant:        types.Tenant{ID: "brand-a", Domain: "brand-a.link:80"},
+			expectedError: types.ErrInvalidTenant,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			a.ErrorIs(ValidateTenant(testCase.tenant), testCase.expectedError)
+		})
+	}
+}
+
+func TestTenants(t *testing.T) {
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	brandA := types.WithTenant(context.Background(), "brand-a")
+	brandB := types.WithTenant(context.Background(), "brand-b")
+
+	first, _, err := svc.GenerateTinyURL(brandA, types.GenerateRequest{LongURL: "https://a.io", Alias: "sale"})
+	a.Nil(err)
+	a.Equal("brand-a", first.Tenant)
+	second, _, err := svc.GenerateTinyURL(brandB, types.GenerateRequest{LongURL: "https://b.io", Alias: "sale"})
+	a.Nil(err)
+	a.Equal("brand-b", second.Tenant)
+	_, _, err = svc.GenerateTinyURL(brandA, types.GenerateRequest{LongURL: "https://c.io", Alias: "sale"})
+	a.ErrorIs(err, types.ErrAliasTaken)
+	a.Contains(c.Data, "brand-a:sale")
+	a.Contains(c.Data, "brand-b:sale")
+	a.NotContains(c.Data, "sale")
+
+	testCases := map[string]struct {
+		ctx             context.Context
+		expectedLongURL string
+		expectedError   error
+	}{
+		"brand-a": {
+			ctx:             brandA,
+			expectedLongURL: "https://a.io",
+		},
+		"brand-b": {
+			ctx:             brandB,
+			expectedLongURL: "https://b.io",
+		},
+		"default tenant": {
+			ctx:           context.Background(),
+			expectedError: types.ErrDocumentNotFound,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := svc.GetTinyURL(testCase.ctx, "sale", types.Visit{})
+			a.ErrorIs(err, testCase.expectedError)
+			a.Equal(testCase.expectedLongURL, doc.LongURL)
+			info, err := svc.GetTinyURLInfo(testCase.ctx, "sale", "")
+			a.ErrorIs(err, testCase.expectedError)
+			a.Equal(testCase.expectedLongURL, info.LongURL)
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		page, err := svc.ListTinyURLs(brandB, types.ListRequest{}, admin)
+		a.Nil(err)
+		a.Len(page.Documents, 1)
+		a.Equal("https://b.io", page.Documents[0].LongURL)
+	})
+
+	t.Run("dedupe is scoped to the tenant", func(t *testing.T) {
+		dedupe := true
+		req := types.GenerateRequest{LongURL: "https://abc.io/shared", Dedupe: &dedupe}
+		first, _, err := svc.GenerateTinyURL(brandA, req)
+		a.Nil(err)
+		second, existing, err := svc.GenerateTinyURL(brandB, req)
+		a.Nil(err)
+		a.False(existing)
+		a.NotEqual(first.DedupeHash, second.DedupeHash)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		a.Nil(svc.DeleteTinyURL(brandA, "sale", admin))
+		a.False(r.Data[types.MockKey("brand-a", "sale")].DeletedAt.IsZero())
+		_, err := svc.GetTinyURL(brandA, "sale", types.Visit{})
+		a.ErrorIs(err, types.ErrDocumentNotFound)
+		_, err = svc.GetTinyURL(brandB, "sale", types.Visit{})
+		a.Nil(err)
+	})
+}
+
 func TestDeleteTinyURL(t *testing.T) {
 	ctx := context.Background()
 	a := assert.New(t)
@@ -248,7 +1565,7 @@ func TestDeleteTinyURL(t *testing.T) {
 		},
 	}
 
-	svc := NewTinyURLService(l, r, c)
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
 	a.NotNil(svc)
 
 	for name, testCase := range testCases {
@@ -256,7 +1573,7 @@ func TestDeleteTinyURL(t *testing.T) {
 			if testCase.pre != nil {
 				testCase.pre(a)
 			}
-			err := svc.DeleteTinyURL(ctx, testCase.urlKey)
+			err := svc.DeleteTinyURL(ctx, testCase.urlKey, admin)
 			if !testCase.expectedError {
 				a.Nil(err)
 			} else {
@@ -265,3 +1582,367 @@ func TestDeleteTinyURL(t *testing.T) {
 		})
 	}
 }
+
+func TestTrashTinyURL(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	owner := types.Principal{KeyID: "owner"}
+
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com", LiveForever: true,
+		Owner: owner.KeyID})
+	a.Nil(err)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.Nil(err)
+	_, err = svc.RestoreTinyURL(ctx, tURL.URLKey, owner)
+	a.ErrorIs(err, types.ErrNotInTrash)
+
+	// a tiny url in the trash neither resolves nor is listed along with the other tiny urls
+	a.Nil(svc.DeleteTinyURL(ctx, tURL.URLKey, owner))
+	a.False(r.Data[tURL.URLKey].DeletedAt.IsZero())
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+	// a visit that read the tiny url before it was moved to the trash does not cache it again over the tombstone
+	svc.(*urlSVC).recache(ctx, tURL)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+	delete(c.Data, tURL.URLKey)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+	a.NotContains(c.Data, tURL.URLKey)
+	_, err = svc.GetTinyURLInfo(ctx, tURL.URLKey, "")
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+	longURL := "https://bar.com"
+	_, err = svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{LongURL: &longURL}, owner)
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+	a.ErrorIs(svc.DeleteTinyURL(ctx, tURL.URLKey, owner), types.ErrDocumentNotFound)
+	page, err := svc.ListTinyURLs(ctx, types.ListRequest{}, owner)
+	a.Nil(err)
+	a.Empty(page.Documents)
+	page, err = svc.ListTinyURLs(ctx, types.ListRequest{Filter: types.URLFilter{State: types.StateTrashed}}, owner)
+	a.Nil(err)
+	a.Len(page.Documents, 1)
+
+	_, err = svc.RestoreTinyURL(ctx, tURL.URLKey, types.Principal{KeyID: "other"})
+	a.ErrorIs(err, types.ErrForbidden)
+	restored, err := svc.RestoreTinyURL(ctx, tURL.URLKey, owner)
+	a.Nil(err)
+	a.True(restored.DeletedAt.IsZero())
+	a.Contains(c.Data, tURL.URLKey)
+	got, err := svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.Nil(err)
+	a.Equal("https://foo.com", got.LongURL)
+
+	// only admins delete tiny urls for good
+	a.ErrorIs(svc.PurgeTinyURL(ctx, tURL.URLKey, owner), types.ErrForbidden)
+	a.Nil(svc.PurgeTinyURL(ctx, tURL.URLKey, admin))
+	a.NotContains(r.Data, tURL.URLKey)
+	svc.(*urlSVC).recache(ctx, restored)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+}
+
+func TestSuspendTinyURL(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	owner := types.Principal{KeyID: "owner"}
+
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com", Owner: owner.KeyID})
+	a.Nil(err)
+	_, err = svc.SuspendTinyURL(ctx, tURL.URLKey, "", owner)
+	a.ErrorIs(err, types.ErrForbidden)
+	_, err = svc.SuspendTinyURL(ctx, tURL.URLKey, strings.Repeat("x", maxReasonLength+1), admin)
+	a.ErrorIs(err, types.ErrInvalidReason)
+	_, err = svc.UnsuspendTinyURL(ctx, tURL.URLKey, admin)
+	a.ErrorIs(err, types.ErrNotSuspended)
+	_, err = svc.SuspendTinyURL(ctx, "Nv3Rx", "", admin)
+	a.ErrorIs(err, types.ErrDocumentNotFound)
+
+	suspended, err := svc.SuspendTinyURL(ctx, tURL.URLKey, "  reported as phishing ", admin)
+	a.Nil(err)
+	a.Equal("reported as phishing", suspended.Suspension.Reason)
+	a.Equal(suspended.Suspension, r.Data[tURL.URLKey].Suspension)
+	// the suspension is seen from the cache as well as from the db
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrLinkSuspended)
+	delete(c.Data, tURL.URLKey)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrLinkSuspended)
+	_, err = svc.GetTinyURLInfo(ctx, tURL.URLKey, "")
+	a.ErrorIs(err, types.ErrLinkSuspended)
+	stats, err := svc.GetTinyURLStats(ctx, tURL.URLKey, admin)
+	a.Nil(err)
+	a.Zero(stats.Clicks)
+
+	unsuspended, err := svc.UnsuspendTinyURL(ctx, tURL.URLKey, admin)
+	a.Nil(err)
+	a.Nil(unsuspended.Suspension)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.Nil(err)
+}
+
+func TestFallbackURL(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	cfg := DefaultConfig()
+	cfg.FallbackURL = "https://svc.io/gone"
+	cfg.TenantFallbackURLs = map[string]string{"brand-a": "https://a.io/gone"}
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), cfg)
+	fallbackURL := func(err error) string {
+		var linkGone *types.LinkGoneError
+		if !errors.As(err, &linkGone) {
+			return ""
+		}
+		return linkGone.FallbackURL
+	}
+
+	_, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{LongURL: "https://foo.com", FallbackURL: "ftp://foo.com"})
+	a.ErrorIs(err, types.ErrInvalidFallback)
+
+	tURL, _, err := svc.GenerateTinyURL(ctx, types.GenerateRequest{
+		LongURL:     "https://foo.com",
+		FallbackURL: "https://foo.com/ended",
+		MaxClicks:   1,
+	})
+	a.Nil(err)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.Nil(err)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrClicksExhausted)
+	a.Equal("https://foo.com/ended", fallbackURL(err))
+
+	_, err = svc.SuspendTinyURL(ctx, tURL.URLKey, "", admin)
+	a.Nil(err)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrLinkSuspended)
+	a.Equal("https://foo.com/ended", fallbackURL(err))
+
+	// the cache entry of an expired tiny url with a fallback url is kept to serve it
+	expired := types.URLDocument{
+		URLKey:      "Xp3Vq",
+		LongURL:     "https://foo.com",
+		ExpireTime:  time.Now().Add(-time.Minute),
+		FallbackURL: "https://foo.com/ended",
+	}
+	bytes, err := json.Marshal(expired)
+	a.Nil(err)
+	c.Data[expired.URLKey] = string(bytes)
+	r.Data[expired.URLKey] = expired
+	_, err = svc.GetTinyURL(ctx, expired.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrLinkExpired)
+	a.Equal("https://foo.com/ended", fallbackURL(err))
+	a.Contains(c.Data, expired.URLKey)
+	_, err = svc.GetTinyURLInfo(ctx, expired.URLKey, "")
+	a.ErrorIs(err, types.ErrLinkExpired)
+
+	noFallback := ""
+	_, err = svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{FallbackURL: &noFallback}, admin)
+	a.Nil(err)
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.Equal("https://svc.io/gone", fallbackURL(err))
+	invalid := "not a url"
+	_, err = svc.UpdateTinyURL(ctx, tURL.URLKey, types.URLUpdate{FallbackURL: &invalid}, admin)
+	a.ErrorIs(err, types.ErrInvalidFallback)
+
+	// tiny urls without a fallback url of their own use the default of their tenant
+	brandA := types.WithTenant(ctx, "brand-a")
+	r.Data[types.MockKey("brand-a", "sale")] = types.URLDocument{
+		Tenant:     "brand-a",
+		URLKey:     "sale",
+		LongURL:    "https://a.io",
+		ExpireTime: time.Now().Add(-time.Minute),
+	}
+	_, err = svc.GetTinyURL(brandA, "sale", types.Visit{})
+	a.ErrorIs(err, types.ErrLinkExpired)
+	a.Equal("https://a.io/gone", fallbackURL(err))
+
+	// without any fallback url visits fail with the plain error
+	svc = NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	_, err = svc.GetTinyURL(ctx, tURL.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrLinkSuspended)
+	a.Empty(fallbackURL(err))
+
+	// removing the fallback url of an expired tiny url drops its cache entry
+	_, err = svc.GetTinyURL(ctx, expired.URLKey, types.Visit{})
+	a.Equal("https://foo.com/ended", fallbackURL(err))
+	_, err = svc.UpdateTinyURL(ctx, expired.URLKey, types.URLUpdate{FallbackURL: &noFallback}, admin)
+	a.Nil(err)
+	a.NotContains(c.Data, expired.URLKey)
+	_, err = svc.GetTinyURL(ctx, expired.URLKey, types.Visit{})
+	a.ErrorIs(err, types.ErrLinkExpired)
+	a.Empty(fallbackURL(err))
+}
+
+func TestPurger(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	cfg := DefaultConfig()
+	now := time.Now()
+	r.Data["live"] = types.URLDocument{URLKey: "live", LiveForever: true}
+	r.Data["recent"] = types.URLDocument{URLKey: "recent", DeletedAt: now.Add(-time.Hour)}
+	r.Data["old"] = types.URLDocument{URLKey: "old", DeletedAt: now.Add(-cfg.TrashRetention - time.Hour)}
+
+	p := NewPurger(zap.NewNop(), r, cfg).(*purger)
+	p.purge(ctx, now)
+	a.Contains(r.Data, "live")
+	a.Contains(r.Data, "recent")
+	a.NotContains(r.Data, "old")
+}
+
+func TestVisitResult(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected string
+	}{
+		"redirected":     {expected: visitRedirected},
+		"unknown key":    {err: types.ErrDocumentNotFound, expected: visitNotFound},
+		"expired":        {err: types.ErrLinkInactive, expected: visitGone},
+		"fallback":       {err: &types.LinkGoneError{Err: types.ErrClicksExhausted}, expected: visitGone},
+		"not yet active": {err: &types.LinkNotYetActiveError{ActiveFrom: time.Now()}, expected: visitNotYetActive},
+		"wrong password": {err: types.ErrWrongPassword, expected: visitPassword},
+		"db failure":     {err: errors.New("connection refused"), expected: visitError},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, visitResult(testCase.err))
+		})
+	}
+}
+
+func TestUpdateTinyURL(t *testing.T) {
+	ctx := context.Background()
+	a := assert.New(t)
+	l := zap.NewNop()
+	r := &types.MockRepo{Data: make(map[string]types.URLDocument)}
+	c := &types.MockCache{Data: make(map[string]string)}
+	newURL := "https://bar.com"
+	liveForever := true
+	notLiveForever := false
+	interstitial := true
+	expireAt := time.Now().Add(time.Hour * 24 * 7)
+	testCases := map[string]struct {
+		urlKey      string
+		update      types.URLUpdate
+		expectedErr error
+		pre         func(a *assert.Assertions)
+		validate    func(a *assert.Assertions, doc types.URLDocument)
+	}{
+		"update url rewrites cache": {
+			urlKey: "Hx21p",
+			update: types.URLUpdate{LongURL: &newURL},
+			pre: func(a *assert.Assertions) {
+				doc := types.URLDocument{
+					Base10ID:   1029208386,
+					URLKey:     "Hx21p",
+					LongURL:    "https://foo.com?id=1",
+					ExpireTime: time.Now().Add(time.Hour),
+				}
+				bytes, err := json.Marshal(doc)
+				a.Nil(err)
+				c.Data["Hx21p"] = string(bytes)
+				r.Data["Hx21p"] = doc
+			},
+			validate: func(a *assert.Assertions, doc types.URLDocument) {
+				a.Equal(newURL, doc.LongURL)
+				cached := types.URLDocument{}
+				a.Nil(json.Unmarshal([]byte(c.Data["Hx21p"]), &cached))
+				a.Equal(newURL, cached.LongURL)
+			},
+		},
+		"update expire at": {
+			urlKey: "Kp9wQ",
+			update: types.URLUpdate{ExpireAt: &expireAt},
+			pre: func(a *assert.Assertions) {
+				r.Data["Kp9wQ"] = types.URLDocument{
+					URLKey:      "Kp9wQ",
+					LongURL:     "https://foo.com",
+					LiveForever: true,
+					ExpireTime:  time.Now().Add(liveForeverTime),
+				}
+			},
+			validate: func(a *assert.Assertions, doc types.URLDocument) {
+				a.False(doc.LiveForever)
+				a.WithinDuration(expireAt, doc.ExpireTime, time.Second)
+			},
+		},
+		"update live forever": {
+			urlKey: "Zt4rM",
+			update: types.URLUpdate{LiveForever: &liveForever},
+			pre: func(a *assert.Assertions) {
+				r.Data["Zt4rM"] = types.URLDocument{
+					URLKey:     "Zt4rM",
+					LongURL:    "https://foo.com",
+					ExpireTime: time.Now().Add(time.Hour),
+				}
+			},
+			validate: func(a *assert.Assertions, doc types.URLDocument) {
+				a.True(doc.LiveForever)
+				a.True(doc.ExpireTime.After(time.Now().Add(maxExpiryTime)))
+			},
+		},
+		"update interstitial": {
+			urlKey: "Wq3eR",
+			update: types.URLUpdate{Interstitial: &interstitial},
+			pre: func(a *assert.Assertions) {
+				r.Data["Wq3eR"] = types.URLDocument{
+					URLKey:     "Wq3eR",
+					LongURL:    "https://foo.com",
+					ExpireTime: time.Now().Add(time.Hour),
+				}
+			},
+			validate: func(a *assert.Assertions, doc types.URLDocument) {
+				a.True(doc.Interstitial)
+				cached := types.URLDocument{}
+				a.Nil(json.Unmarshal([]byte(c.Data["Wq3eR"]), &cached))
+				a.True(cached.Interstitial)
+			},
+		},
+		"live forever with expire at": {
+			urlKey:      "Zt4rM",
+			update:      types.URLUpdate{ExpireAt: &expireAt, LiveForever: &liveForever},
+			expectedErr: types.ErrConflictExpiry,
+		},
+		"turn off live forever without expire at": {
+			urlKey:      "Zt4rM",
+			update:      types.URLUpdate{LiveForever: &notLiveForever},
+			expectedErr: types.ErrMissingExpiry,
+		},
+		"empty update": {
+			urlKey:      "Zt4rM",
+			expectedErr: types.ErrEmptyUpdate,
+		},
+		"not found": {
+			urlKey:      "Nn8uY",
+			update:      types.URLUpdate{LongURL: &newURL},
+			expectedErr: types.ErrDocumentNotFound,
+		},
+	}
+
+	svc := NewTinyURLService(l, r, c, NewRandomKeyGenerator(), newClickCounter(r, c), DefaultConfig())
+	a.NotNil(svc)
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if testCase.pre != nil {
+				testCase.pre(a)
+			}
+			doc, err := svc.UpdateTinyURL(ctx, testCase.urlKey, testCase.update, admin)
+			if testCase.expectedErr != nil {
+				a.ErrorIs(err, testCase.expectedErr)
+				return
+			}
+			a.Nil(err)
+			testCase.validate(a, doc)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package url
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	neturl "net/url"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+const (
+	// maxVariants bounds the destinations a tiny url splits its visits across
+	maxVariants      = 10
+	maxVariantWeight = 1000
+)
+
+// normalizeVariants validates the variants and returns them without clicks, which only the db counts. A split tiny url
+// has at least two variants.
+func normalizeVariants(variants []types.Variant) ([]types.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < 2 || len(variants) > maxVariants {
+		return nil, fmt.Errorf("%w: between 2 and %d variants are required", types.ErrInvalidVariants, maxVariants)
+	}
+	normalized := make([]types.Variant, len(variants))
+	for i, v := range variants {
+		if v.Weight < 1 || v.Weight > maxVariantWeight {
+			return nil, fmt.Errorf("%w: weight of variant %d must be between 1 and %d", types.ErrInvalidVariants, i,
+				maxVariantWeight)
+		}
+		dest, err := neturl.Parse(v.URL)
+		if err != nil || (dest.Scheme != "http" && dest.Scheme != "https") || dest.Host == "" {
+			return nil, fmt.Errorf("%w: url of variant %d must be an http or https url", types.ErrInvalidVariants, i)
+		}
+		normalized[i] = types.Variant{URL: v.URL, Weight: v.Weight}
+	}
+	return normalized, nil
+}
+
+// route returns the tiny url with the destination of the visit as its long url: the destination of the first rule
+// matching the visit, or else the url of the variant the visitor is assigned to, whose click is counted by the click
+// counter
+func (u *urlSVC) route(ctx context.Context, tinyURL types.URLDocument, visit types.Visit) types.URLDocument {
+	if targeted, ok := target(tinyURL, visit); ok {
+		return targeted
+	}
+	i := assignVariant(tinyURL, visit.VisitorID)
+	if i < 0 {
+		return tinyURL
+	}
+	u.clicks.CountVariant(ctx, tinyURL.Tenant, tinyURL.URLKey, i)
+	tinyURL.LongURL = tinyURL.Variants[i].URL
+	return tinyURL
+}
+
+// assignVariant returns the index of the variant of the visitor, or -1 when the tiny url has no variants. Visitors are
+// spread across the variants by weight through a hash of their id and the key, so that they keep their variant as long
+// as the variants are unchanged. Visitors without an id get a random variant.
+func assignVariant(tinyURL types.URLDocument, visitorID string) int {
+	var total uint64
+	for _, v := range tinyURL.Variants {
+		total += uint64(v.Weight)
+	}
+	if total == 0 {
+		return -1
+	}
+	var point uint64
+	if visitorID == "" {
+		point = rand.Uint64N(total)
+	} else {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(cacheKey(tinyURL.Tenant, tinyURL.URLKey) + "/" + visitorID))
+		point = h.Sum64() % total
+	}
+	for i, v := range tinyURL.Variants {
+		if point < uint64(v.Weight) {
+			return i
+		}
+		point -= uint64(v.Weight)
+	}
+	return len(tinyURL.Variants) - 1
+}
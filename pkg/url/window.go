@@ -0,0 +1,39 @@
+package url
+
+import (
+	"time"
+
+	"github.com/vaishakdinesh/tiny-url-svc/types"
+)
+
+// checkActive returns an error unless now is within the activation window of the tiny url. Before the window opens
+// a LinkNotYetActiveError is returned, after it closes ErrLinkInactive, which is answered like an expired tiny url.
+func checkActive(tinyURL types.URLDocument, now time.Time) error {
+	if !tinyURL.ActiveFrom.IsZero() && now.Before(tinyURL.ActiveFrom) {
+		return &types.LinkNotYetActiveError{ActiveFrom: tinyURL.ActiveFrom}
+	}
+	if !tinyURL.ActiveUntil.IsZero() && !now.Before(tinyURL.ActiveUntil) {
+		return types.ErrLinkInactive
+	}
+	return nil
+}
+
+// validateWindow checks the activation window closes after it opens. Either bound can be left open.
+func validateWindow(activeFrom, activeUntil time.Time) error {
+	if !activeFrom.IsZero() && !activeUntil.IsZero() && !activeUntil.After(activeFrom) {
+		return types.ErrInvalidWindow
+	}
+	return nil
+}
+
+// validateWindowUpdate checks the activation window resulting from applying the update to the stored tiny url
+func validateWindowUpdate(stored types.URLDocument, update types.URLUpdate) error {
+	activeFrom, activeUntil := stored.ActiveFrom, stored.ActiveUntil
+	if update.ActiveFrom != nil {
+		activeFrom = *update.ActiveFrom
+	}
+	if update.ActiveUntil != nil {
+		activeUntil = *update.ActiveUntil
+	}
+	return validateWindow(activeFrom, activeUntil)
+}
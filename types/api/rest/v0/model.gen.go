@@ -3,24 +3,633 @@
 // Code generated by github.com/deepmap/oapi-codegen version v1.15.0 DO NOT EDIT.
 package v0
 
+import (
+	"time"
+)
+
+const (
+	ApiKeyAuthScopes = "ApiKeyAuth.Scopes"
+)
+
+// Defines values for GenerateURLRequestRedirectType.
+const (
+	N301 GenerateURLRequestRedirectType = 301
+	N302 GenerateURLRequestRedirectType = 302
+	N307 GenerateURLRequestRedirectType = 307
+	N308 GenerateURLRequestRedirectType = 308
+)
+
+// Defines values for GoneErrorReason.
+const (
+	GoneErrorReasonExhausted GoneErrorReason = "exhausted"
+	GoneErrorReasonExpired   GoneErrorReason = "expired"
+	GoneErrorReasonSuspended GoneErrorReason = "suspended"
+)
+
+// Defines values for QRFormat.
+const (
+	Png QRFormat = "png"
+	Svg QRFormat = "svg"
+)
+
+// Defines values for QRLevel.
+const (
+	H QRLevel = "H"
+	L QRLevel = "L"
+	M QRLevel = "M"
+	Q QRLevel = "Q"
+)
+
+// Defines values for ReferrerPolicy.
+const (
+	NoReferrer                  ReferrerPolicy = "no-referrer"
+	NoReferrerWhenDowngrade     ReferrerPolicy = "no-referrer-when-downgrade"
+	Origin                      ReferrerPolicy = "origin"
+	OriginWhenCrossOrigin       ReferrerPolicy = "origin-when-cross-origin"
+	SameOrigin                  ReferrerPolicy = "same-origin"
+	StrictOrigin                ReferrerPolicy = "strict-origin"
+	StrictOriginWhenCrossOrigin ReferrerPolicy = "strict-origin-when-cross-origin"
+	UnsafeUrl                   ReferrerPolicy = "unsafe-url"
+)
+
+// Defines values for TargetingRulePlatforms.
+const (
+	Android TargetingRulePlatforms = "android"
+	Desktop TargetingRulePlatforms = "desktop"
+	Ios     TargetingRulePlatforms = "ios"
+	Linux   TargetingRulePlatforms = "linux"
+	Macos   TargetingRulePlatforms = "macos"
+	Mobile  TargetingRulePlatforms = "mobile"
+	Windows TargetingRulePlatforms = "windows"
+)
+
+// Defines values for ListURLsParamsSort.
+const (
+	Clicks    ListURLsParamsSort = "clicks"
+	CreatedAt ListURLsParamsSort = "createdAt"
+)
+
+// Defines values for ListURLsParamsOrder.
+const (
+	Asc  ListURLsParamsOrder = "asc"
+	Desc ListURLsParamsOrder = "desc"
+)
+
+// Defines values for ListURLsParamsState.
+const (
+	ListURLsParamsStateActive  ListURLsParamsState = "active"
+	ListURLsParamsStateExpired ListURLsParamsState = "expired"
+	ListURLsParamsStateTrashed ListURLsParamsState = "trashed"
+)
+
 // APIError defines model for APIError.
 type APIError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
 
+// APIKey defines model for APIKey.
+type APIKey struct {
+	Admin     bool       `json:"admin"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+}
+
+// APIKeyList defines model for APIKeyList.
+type APIKeyList struct {
+	Items []APIKey `json:"items"`
+}
+
+// APIKeySecret defines model for APIKeySecret.
+type APIKeySecret struct {
+	Key APIKey `json:"key"`
+
+	// Secret the API key to send in X-API-Key. It cannot be retrieved again.
+	Secret string `json:"secret"`
+}
+
+// CreateAPIKeyRequest defines model for CreateAPIKeyRequest.
+type CreateAPIKeyRequest struct {
+	// Admin admin keys can manage API keys and change every tiny url
+	Admin *bool  `json:"admin,omitempty"`
+	Name  string `json:"name"`
+}
+
+// CreatedBy who created the tiny url, such as a user of the client generating it
+type CreatedBy = string
+
+// ExternalID id of the tiny url in the system of the client
+type ExternalID = string
+
+// GenerateURLBatchRequest defines model for GenerateURLBatchRequest.
+type GenerateURLBatchRequest struct {
+	// Items the urls to generate tiny urls for. The service limits the number of items per batch.
+	Items []GenerateURLRequest `json:"items"`
+}
+
+// GenerateURLBatchResponse defines model for GenerateURLBatchResponse.
+type GenerateURLBatchResponse struct {
+	Results []GenerateURLBatchResult `json:"results"`
+}
+
+// GenerateURLBatchResult the result of a batch item. Either generatedTinyURL or error is set.
+type GenerateURLBatchResult struct {
+	Error *APIError `json:"error,omitempty"`
+
+	// Existing true when dedupe returned an existing tiny url instead of generating one.
+	Existing *bool `json:"existing,omitempty"`
+
+	// ExpireTime RFC3339 time at which the generated url expires.
+	ExpireTime       *time.Time `json:"expireTime,omitempty"`
+	GeneratedTinyURL *string    `json:"generatedTinyURL,omitempty"`
+
+	// Index position of the item in the request
+	Index int `json:"index"`
+}
+
 // GenerateURLRequest defines model for GenerateURLRequest.
 type GenerateURLRequest struct {
+	// ActiveFrom optional RFC3339 time before which the tiny url does not redirect.
+	ActiveFrom *time.Time `json:"activeFrom,omitempty"`
+
+	// ActiveUntil optional RFC3339 time from which the tiny url no longer redirects. Must be after activeFrom.
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+
+	// Alias optional custom key used instead of a generated one. Reserved words such as `generate` are rejected.
+	Alias *string `json:"alias,omitempty"`
+
+	// CacheControl Cache-Control header sent with the redirect. Defaults to the service wide setting.
+	CacheControl *string `json:"cacheControl,omitempty"`
+
+	// CreatedBy who created the tiny url, such as a user of the client generating it
+	CreatedBy *CreatedBy `json:"createdBy,omitempty"`
+
+	// Dedupe return an existing tiny url of the same url instead of generating a new one. Urls are compared after
+	// normalizing the scheme, host, default port and query parameter order. Ignored when an alias is set.
+	// Defaults to the service wide setting.
+	Dedupe *bool `json:"dedupe,omitempty"`
+
+	// ExpireAt RFC3339 time at which the generated url expires. Cannot be combined with ttlSeconds or liveForever.
+	ExpireAt *time.Time `json:"expireAt,omitempty"`
+
+	// ExternalID id of the tiny url in the system of the client
+	ExternalID *ExternalID `json:"externalID,omitempty"`
+
+	// FallbackURL where visits go once the tiny url expired, was suspended or was followed as many times as its maxClicks
+	// allows, instead of getting a 410. Without it the default fallback url of the tenant or the service is used,
+	// if any. Tiny urls with a fallback url are never deduplicated.
+	FallbackURL *string `json:"fallbackURL,omitempty"`
+
+	// Interstitial show the destination and wait for the visitor to continue instead of redirecting. The service shows it for
+	// untrusted domains either way. Tiny urls with an interstitial are never deduplicated.
+	Interstitial *bool `json:"interstitial,omitempty"`
+
 	// LiveForever boolean indicating whether the generated url will not expire. Not required as the API will default to false.
-	LiveForever bool   `json:"liveForever"`
-	Url         string `json:"url"`
+	LiveForever bool `json:"liveForever"`
+
+	// MaxClicks optional number of redirects after which the tiny url stops working. 1 makes a one-time link. Tiny urls
+	// with maxClicks are never deduplicated.
+	MaxClicks *int64 `json:"maxClicks,omitempty"`
+
+	// Notes free-form notes on the tiny url. Empty on update removes them.
+	Notes *Notes `json:"notes,omitempty"`
+
+	// Passthrough pass the path following the key and the query of visits on to the url. The path is appended to the url and
+	// the query parameters the url does not set are added to it, unless the url is a template using the
+	// {path}, {query} and {query.<name>} placeholders after its host, such as
+	// https://shop.example/{path}?ref={query.ref}. Passthrough tiny urls are never deduplicated.
+	Passthrough *bool `json:"passthrough,omitempty"`
+
+	// Password optional password required to follow the tiny url. Only a hash of it is stored. Tiny urls with a password
+	// are never deduplicated.
+	Password *string `json:"password,omitempty"`
+
+	// RedirectType HTTP status used to redirect to the long url. Defaults to the service wide setting.
+	RedirectType *GenerateURLRequestRedirectType `json:"redirectType,omitempty"`
+
+	// ReferrerPolicy Referrer-Policy header sent with the redirect. Defaults to the service wide setting.
+	ReferrerPolicy *ReferrerPolicy `json:"referrerPolicy,omitempty"`
+
+	// Rules rules evaluated in order for every visit. The first rule matching the visit sends it to its destination
+	// instead of the url. Setting an empty list on update removes the rules. Targeted tiny urls are never
+	// deduplicated.
+	Rules *TargetingRules `json:"rules,omitempty"`
+
+	// Tags tags of the tiny url, lower cased and unique. An empty list on update removes them.
+	Tags *Tags `json:"tags,omitempty"`
+
+	// Title title of the tiny url. Empty on update removes it.
+	Title *Title `json:"title,omitempty"`
+
+	// TtlSeconds number of seconds the generated url lives for. Cannot be combined with expireAt or liveForever.
+	TtlSeconds *int64 `json:"ttlSeconds,omitempty"`
+	Url        string `json:"url"`
+
+	// Variants destinations the visits are split across by weight, e.g. 70 and 30. Visitors keep the variant they were
+	// assigned to through a cookie, or a hash of their IP for clients without cookies. The url is only used once the
+	// variants are removed, which an empty list on update does. Targeting rules matching a visit take precedence.
+	// At least two variants are required. Split tiny urls are never deduplicated.
+	Variants *Variants `json:"variants,omitempty"`
 }
 
+// GenerateURLRequestRedirectType HTTP status used to redirect to the long url. Defaults to the service wide setting.
+type GenerateURLRequestRedirectType int
+
 // GenerateURLResponse defines model for GenerateURLResponse.
 type GenerateURLResponse struct {
-	ExpireTime       *string `json:"expireTime,omitempty"`
-	GeneratedTinyURL string  `json:"generatedTinyURL"`
+	// ExpireTime RFC3339 time at which the generated url expires.
+	ExpireTime       *time.Time `json:"expireTime,omitempty"`
+	GeneratedTinyURL string     `json:"generatedTinyURL"`
+}
+
+// GoneError the reason a tiny url no longer redirects, in the shape of an APIError
+type GoneError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Reason  GoneErrorReason `json:"reason"`
+}
+
+// GoneErrorReason defines model for GoneError.Reason.
+type GoneErrorReason string
+
+// Interstitial the destination of a tiny url with an interstitial, in the shape of an APIError
+type Interstitial struct {
+	Code        int    `json:"code"`
+	Destination string `json:"destination"`
+	Message     string `json:"message"`
+}
+
+// Notes free-form notes on the tiny url. Empty on update removes them.
+type Notes = string
+
+// QRBatchRequest defines model for QRBatchRequest.
+type QRBatchRequest struct {
+	// Background hex RGB color, with or without the leading '#'
+	Background *QRColor `json:"background,omitempty"`
+
+	// Foreground hex RGB color, with or without the leading '#'
+	Foreground *QRColor `json:"foreground,omitempty"`
+
+	// Format image format of the QR code
+	Format *QRFormat `json:"format,omitempty"`
+	Keys   []string  `json:"keys"`
+
+	// Level error correction level, recovering about 7% (L), 15% (M), 25% (Q) or 30% (H) of the code
+	Level *QRLevel `json:"level,omitempty"`
+
+	// Margin width of the quiet zone around the QR code, in modules
+	Margin *QRMargin `json:"margin,omitempty"`
+
+	// Size width and height of the QR code in pixels
+	Size *QRSize `json:"size,omitempty"`
 }
 
+// QRColor hex RGB color, with or without the leading '#'
+type QRColor = string
+
+// QRFormat image format of the QR code
+type QRFormat string
+
+// QRLevel error correction level, recovering about 7% (L), 15% (M), 25% (Q) or 30% (H) of the code
+type QRLevel string
+
+// QRMargin width of the quiet zone around the QR code, in modules
+type QRMargin = int
+
+// QRSize width and height of the QR code in pixels
+type QRSize = int
+
+// ReferrerPolicy Referrer-Policy header sent with the redirect. Defaults to the service wide setting.
+type ReferrerPolicy string
+
+// SuspendURLRequest defines model for SuspendURLRequest.
+type SuspendURLRequest struct {
+	// Reason note on why the tiny url is suspended
+	Reason *string `json:"reason,omitempty"`
+}
+
+// Tags tags of the tiny url, lower cased and unique. An empty list on update removes them.
+type Tags = []string
+
+// TargetingRule matches the visits matching all its criteria. A criterion matches when any of its values does, and at
+// least one criterion is required.
+type TargetingRule struct {
+	// Countries ISO 3166-1 alpha-2 country codes matched against the country header configured for the service.
+	Countries   *[]string `json:"countries,omitempty"`
+	Destination string    `json:"destination"`
+
+	// Languages language tags matched against the preferred language of Accept-Language. en also matches en-US.
+	Languages *[]string `json:"languages,omitempty"`
+
+	// Platforms platforms of the client from its User-Agent. mobile and desktop match groups of platforms.
+	Platforms *[]TargetingRulePlatforms `json:"platforms,omitempty"`
+}
+
+// TargetingRulePlatforms defines model for TargetingRule.Platforms.
+type TargetingRulePlatforms string
+
+// TargetingRules rules evaluated in order for every visit. The first rule matching the visit sends it to its destination
+// instead of the url. Setting an empty list on update removes the rules. Targeted tiny urls are never
+// deduplicated.
+type TargetingRules = []TargetingRule
+
+// Title title of the tiny url. Empty on update removes it.
+type Title = string
+
+// URLInfo defines model for URLInfo.
+type URLInfo struct {
+	ActiveFrom  *time.Time `json:"activeFrom,omitempty"`
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+
+	// Clicks number of times the tiny url redirected, only returned to the owner and admins. Listings, and their clicks
+	// sort, use the clicks stored at the last flush of the click counter, which may miss the latest ones.
+	Clicks    *int64     `json:"clicks,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+
+	// CreatedBy who created the tiny url, such as a user of the client generating it
+	CreatedBy *CreatedBy `json:"createdBy,omitempty"`
+
+	// DeletedAt time the tiny url was moved to the trash, only set on tiny urls in the trash
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+	ExpireTime *time.Time `json:"expireTime,omitempty"`
+
+	// ExternalID id of the tiny url in the system of the client
+	ExternalID *ExternalID `json:"externalID,omitempty"`
+
+	// FallbackURL where visits go once the tiny url no longer redirects, when it has a fallback url of its own
+	FallbackURL *string `json:"fallbackURL,omitempty"`
+
+	// Interstitial whether the tiny url asks for it to show its destination before redirecting
+	Interstitial *bool `json:"interstitial,omitempty"`
+	LiveForever  bool  `json:"liveForever"`
+
+	// MaxClicks number of redirects the tiny url allows, when limited
+	MaxClicks *int64 `json:"maxClicks,omitempty"`
+
+	// Notes free-form notes on the tiny url. Empty on update removes them.
+	Notes *Notes `json:"notes,omitempty"`
+
+	// Passthrough whether the path following the key and the query of visits are passed on to the url
+	Passthrough *bool `json:"passthrough,omitempty"`
+
+	// PasswordProtected whether following the tiny url needs a password
+	PasswordProtected *bool `json:"passwordProtected,omitempty"`
+
+	// Rules rules evaluated in order for every visit. The first rule matching the visit sends it to its destination
+	// instead of the url. Setting an empty list on update removes the rules. Targeted tiny urls are never
+	// deduplicated.
+	Rules *TargetingRules `json:"rules,omitempty"`
+
+	// SuspendedAt time the tiny url was suspended, only set on suspended tiny urls
+	SuspendedAt *time.Time `json:"suspendedAt,omitempty"`
+
+	// SuspensionReason note of the admin who suspended the tiny url
+	SuspensionReason *string `json:"suspensionReason,omitempty"`
+
+	// Tags tags of the tiny url, lower cased and unique. An empty list on update removes them.
+	Tags    *Tags  `json:"tags,omitempty"`
+	TinyURL string `json:"tinyURL"`
+
+	// Title title of the tiny url. Empty on update removes it.
+	Title *Title `json:"title,omitempty"`
+
+	// UpdatedAt time of the last update, the creation time until the tiny url is updated
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Url       string     `json:"url"`
+	UrlKey    string     `json:"urlKey"`
+
+	// Variants destinations the visits are split across by weight, e.g. 70 and 30. Visitors keep the variant they were
+	// assigned to through a cookie, or a hash of their IP for clients without cookies. The url is only used once the
+	// variants are removed, which an empty list on update does. Targeting rules matching a visit take precedence.
+	// At least two variants are required. Split tiny urls are never deduplicated.
+	Variants *Variants `json:"variants,omitempty"`
+}
+
+// URLList defines model for URLList.
+type URLList struct {
+	Items []URLInfo `json:"items"`
+
+	// NextCursor cursor of the next page, missing on the last page
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// URLStats defines model for URLStats.
+type URLStats struct {
+	// Clicks number of times the tiny url redirected
+	Clicks int64  `json:"clicks"`
+	UrlKey string `json:"urlKey"`
+
+	// Variants the variants of a split tiny url, in the order they were set
+	Variants *[]VariantStats `json:"variants,omitempty"`
+}
+
+// UnlockURLRequest defines model for UnlockURLRequest.
+type UnlockURLRequest struct {
+	Password string `json:"password"`
+
+	// Path the path following the key in the visited url, passed on by passthrough tiny urls
+	Path *string `json:"path,omitempty"`
+}
+
+// UpdateURLRequest defines model for UpdateURLRequest.
+type UpdateURLRequest struct {
+	// ActiveFrom RFC3339 time before which the url does not redirect.
+	ActiveFrom *time.Time `json:"activeFrom,omitempty"`
+
+	// ActiveUntil RFC3339 time from which the url no longer redirects. Must be after activeFrom.
+	ActiveUntil *time.Time `json:"activeUntil,omitempty"`
+
+	// CreatedBy who created the tiny url, such as a user of the client generating it
+	CreatedBy *CreatedBy `json:"createdBy,omitempty"`
+
+	// ExpireAt RFC3339 time at which the url expires. Cannot be combined with liveForever set to true.
+	ExpireAt *time.Time `json:"expireAt,omitempty"`
+
+	// ExternalID id of the tiny url in the system of the client
+	ExternalID *ExternalID `json:"externalID,omitempty"`
+
+	// FallbackURL where visits go once the tiny url no longer redirects. An empty string removes it.
+	FallbackURL *string `json:"fallbackURL,omitempty"`
+
+	// Interstitial show the destination and wait for the visitor to continue instead of redirecting.
+	Interstitial *bool `json:"interstitial,omitempty"`
+
+	// LiveForever boolean indicating whether the url will not expire. Setting it to false requires expireAt.
+	LiveForever *bool `json:"liveForever,omitempty"`
+
+	// Notes free-form notes on the tiny url. Empty on update removes them.
+	Notes *Notes `json:"notes,omitempty"`
+
+	// Passthrough pass the path following the key and the query of visits on to the url.
+	Passthrough *bool `json:"passthrough,omitempty"`
+
+	// Rules rules evaluated in order for every visit. The first rule matching the visit sends it to its destination
+	// instead of the url. Setting an empty list on update removes the rules. Targeted tiny urls are never
+	// deduplicated.
+	Rules *TargetingRules `json:"rules,omitempty"`
+
+	// Tags tags of the tiny url, lower cased and unique. An empty list on update removes them.
+	Tags *Tags `json:"tags,omitempty"`
+
+	// Title title of the tiny url. Empty on update removes it.
+	Title *Title  `json:"title,omitempty"`
+	Url   *string `json:"url,omitempty"`
+
+	// Variants destinations the visits are split across by weight, e.g. 70 and 30. Visitors keep the variant they were
+	// assigned to through a cookie, or a hash of their IP for clients without cookies. The url is only used once the
+	// variants are removed, which an empty list on update does. Targeting rules matching a visit take precedence.
+	// At least two variants are required. Split tiny urls are never deduplicated.
+	Variants *Variants `json:"variants,omitempty"`
+}
+
+// Variant defines model for Variant.
+type Variant struct {
+	Url string `json:"url"`
+
+	// Weight share of the visits relative to the weights of the other variants
+	Weight int `json:"weight"`
+}
+
+// VariantStats defines model for VariantStats.
+type VariantStats struct {
+	// Clicks number of visits sent to the variant since the variants were last set
+	Clicks int64  `json:"clicks"`
+	Url    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Variants destinations the visits are split across by weight, e.g. 70 and 30. Visitors keep the variant they were
+// assigned to through a cookie, or a hash of their IP for clients without cookies. The url is only used once the
+// variants are removed, which an empty list on update does. Targeting rules matching a visit take precedence.
+// At least two variants are required. Split tiny urls are never deduplicated.
+type Variants = []Variant
+
+// KeyID defines model for KeyID.
+type KeyID = string
+
+// LinkPassword defines model for LinkPassword.
+type LinkPassword = string
+
+// QRBackground hex RGB color, with or without the leading '#'
+type QRBackground = QRColor
+
+// QRForeground hex RGB color, with or without the leading '#'
+type QRForeground = QRColor
+
+// Forbidden defines model for Forbidden.
+type Forbidden = APIError
+
+// Gone the reason a tiny url no longer redirects, in the shape of an APIError
+type Gone = GoneError
+
+// NotYetActive defines model for NotYetActive.
+type NotYetActive = APIError
+
+// PasswordRequired defines model for PasswordRequired.
+type PasswordRequired = APIError
+
+// TooManyAttempts defines model for TooManyAttempts.
+type TooManyAttempts = APIError
+
+// Unauthenticated defines model for Unauthenticated.
+type Unauthenticated = APIError
+
+// ListURLsParams defines parameters for ListURLs.
+type ListURLsParams struct {
+	// Limit maximum number of tiny urls in the page
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor nextCursor of the previous page
+	Cursor *string              `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Sort   *ListURLsParamsSort  `form:"sort,omitempty" json:"sort,omitempty"`
+	Order  *ListURLsParamsOrder `form:"order,omitempty" json:"order,omitempty"`
+
+	// Owner id of the API key that generated the tiny urls. Only admins can list the tiny urls of others.
+	Owner *string `form:"owner,omitempty" json:"owner,omitempty"`
+
+	// Domain host of the long url
+	Domain        *string    `form:"domain,omitempty" json:"domain,omitempty"`
+	CreatedAfter  *time.Time `form:"createdAfter,omitempty" json:"createdAfter,omitempty"`
+	CreatedBefore *time.Time `form:"createdBefore,omitempty" json:"createdBefore,omitempty"`
+	ExpiresAfter  *time.Time `form:"expiresAfter,omitempty" json:"expiresAfter,omitempty"`
+	ExpiresBefore *time.Time `form:"expiresBefore,omitempty" json:"expiresBefore,omitempty"`
+
+	// State only lists tiny urls that have not expired yet (active), have expired (expired) or are in the trash (trashed). Tiny urls in the trash are left out of the other states.
+	State *ListURLsParamsState `form:"state,omitempty" json:"state,omitempty"`
+
+	// Tag only lists tiny urls having all the tags. Repeat the parameter for several tags.
+	Tag        *[]string `form:"tag,omitempty" json:"tag,omitempty"`
+	CreatedBy  *string   `form:"createdBy,omitempty" json:"createdBy,omitempty"`
+	ExternalID *string   `form:"externalID,omitempty" json:"externalID,omitempty"`
+
+	// Q only lists tiny urls with any of the words in their title, notes or tags
+	Q *string `form:"q,omitempty" json:"q,omitempty"`
+}
+
+// ListURLsParamsSort defines parameters for ListURLs.
+type ListURLsParamsSort string
+
+// ListURLsParamsOrder defines parameters for ListURLs.
+type ListURLsParamsOrder string
+
+// ListURLsParamsState defines parameters for ListURLs.
+type ListURLsParamsState string
+
+// DeleteURLParams defines parameters for DeleteURL.
+type DeleteURLParams struct {
+	// Permanent deletes the tiny url for good instead of moving it to the trash. Only admins can.
+	Permanent *bool `form:"permanent,omitempty" json:"permanent,omitempty"`
+}
+
+// GetURLParams defines parameters for GetURL.
+type GetURLParams struct {
+	// XLinkPassword password of a password protected tiny url
+	XLinkPassword *LinkPassword `json:"X-Link-Password,omitempty"`
+}
+
+// GetURLInfoParams defines parameters for GetURLInfo.
+type GetURLInfoParams struct {
+	// XLinkPassword password of a password protected tiny url
+	XLinkPassword *LinkPassword `json:"X-Link-Password,omitempty"`
+
+	// IfNoneMatch ETag of a previous response. A 304 is returned when the details have not changed.
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+}
+
+// GetURLQRParams defines parameters for GetURLQR.
+type GetURLQRParams struct {
+	Format *QRFormat `form:"format,omitempty" json:"format,omitempty"`
+	Size   *QRSize   `form:"size,omitempty" json:"size,omitempty"`
+	Margin *QRMargin `form:"margin,omitempty" json:"margin,omitempty"`
+	Level  *QRLevel  `form:"level,omitempty" json:"level,omitempty"`
+
+	// Foreground color of the modules
+	Foreground *QRForeground `form:"foreground,omitempty" json:"foreground,omitempty"`
+
+	// Background color of the background
+	Background *QRBackground `form:"background,omitempty" json:"background,omitempty"`
+}
+
+// CreateAPIKeyJSONRequestBody defines body for CreateAPIKey for application/json ContentType.
+type CreateAPIKeyJSONRequestBody = CreateAPIKeyRequest
+
 // GenerateURLJSONRequestBody defines body for GenerateURL for application/json ContentType.
 type GenerateURLJSONRequestBody = GenerateURLRequest
+
+// GenerateURLBatchJSONRequestBody defines body for GenerateURLBatch for application/json ContentType.
+type GenerateURLBatchJSONRequestBody = GenerateURLBatchRequest
+
+// ExportQRCodesJSONRequestBody defines body for ExportQRCodes for application/json ContentType.
+type ExportQRCodesJSONRequestBody = QRBatchRequest
+
+// UpdateURLJSONRequestBody defines body for UpdateURL for application/json ContentType.
+type UpdateURLJSONRequestBody = UpdateURLRequest
+
+// SuspendURLJSONRequestBody defines body for SuspendURL for application/json ContentType.
+type SuspendURLJSONRequestBody = SuspendURLRequest
+
+// UnlockURLFormdataRequestBody defines body for UnlockURL for application/x-www-form-urlencoded ContentType.
+type UnlockURLFormdataRequestBody = UnlockURLRequest
@@ -13,15 +13,60 @@ import (
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Lists API keys
+	// (GET /admin/keys)
+	ListAPIKeys(ctx echo.Context) error
+	// Creates an API key
+	// (POST /admin/keys)
+	CreateAPIKey(ctx echo.Context) error
+	// Revokes an API key
+	// (DELETE /admin/keys/{keyID})
+	RevokeAPIKey(ctx echo.Context, keyID KeyID) error
+	// Rotates an API key
+	// (POST /admin/keys/{keyID}/rotate)
+	RotateAPIKey(ctx echo.Context, keyID KeyID) error
 	// Generate a tiny url
 	// (POST /generate)
 	GenerateURL(ctx echo.Context) error
+	// Generate tiny urls in a batch
+	// (POST /generate/batch)
+	GenerateURLBatch(ctx echo.Context) error
+	// Exports the QR codes of tiny urls
+	// (POST /qr/batch)
+	ExportQRCodes(ctx echo.Context) error
+	// Lists tiny urls
+	// (GET /urls)
+	ListURLs(ctx echo.Context, params ListURLsParams) error
 	// Deletes a tiny url
 	// (DELETE /{urlKey})
-	DeleteURL(ctx echo.Context, urlKey string) error
+	DeleteURL(ctx echo.Context, urlKey string, params DeleteURLParams) error
 	// redirects to long url.
 	// (GET /{urlKey})
-	GetURL(ctx echo.Context, urlKey string) error
+	GetURL(ctx echo.Context, urlKey string, params GetURLParams) error
+	// Updates a tiny url
+	// (PATCH /{urlKey})
+	UpdateURL(ctx echo.Context, urlKey string) error
+	// Returns the details of a tiny url
+	// (GET /{urlKey}/info)
+	GetURLInfo(ctx echo.Context, urlKey string, params GetURLInfoParams) error
+	// Returns the QR code of a tiny url
+	// (GET /{urlKey}/qr)
+	GetURLQR(ctx echo.Context, urlKey string, params GetURLQRParams) error
+	// Restores a tiny url from the trash
+	// (POST /{urlKey}/restore)
+	RestoreURL(ctx echo.Context, urlKey string) error
+	// Returns the click stats of a tiny url
+	// (GET /{urlKey}/stats)
+	GetURLStats(ctx echo.Context, urlKey string) error
+	// Suspends a tiny url
+	// (POST /{urlKey}/suspend)
+	SuspendURL(ctx echo.Context, urlKey string) error
+	// Unlocks a password protected tiny url
+	// (POST /{urlKey}/unlock)
+	UnlockURL(ctx echo.Context, urlKey string) error
+	// Unsuspends a tiny url
+	// (POST /{urlKey}/unsuspend)
+	UnsuspendURL(ctx echo.Context, urlKey string) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -29,15 +74,215 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// ListAPIKeys converts echo context to params.
+func (w *ServerInterfaceWrapper) ListAPIKeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListAPIKeys(ctx)
+	return err
+}
+
+// CreateAPIKey converts echo context to params.
+func (w *ServerInterfaceWrapper) CreateAPIKey(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.CreateAPIKey(ctx)
+	return err
+}
+
+// RevokeAPIKey converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeAPIKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "keyID" -------------
+	var keyID KeyID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "keyID", runtime.ParamLocationPath, ctx.Param("keyID"), &keyID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter keyID: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RevokeAPIKey(ctx, keyID)
+	return err
+}
+
+// RotateAPIKey converts echo context to params.
+func (w *ServerInterfaceWrapper) RotateAPIKey(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "keyID" -------------
+	var keyID KeyID
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "keyID", runtime.ParamLocationPath, ctx.Param("keyID"), &keyID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter keyID: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RotateAPIKey(ctx, keyID)
+	return err
+}
+
 // GenerateURL converts echo context to params.
 func (w *ServerInterfaceWrapper) GenerateURL(ctx echo.Context) error {
 	var err error
 
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
 	err = w.Handler.GenerateURL(ctx)
 	return err
 }
 
+// GenerateURLBatch converts echo context to params.
+func (w *ServerInterfaceWrapper) GenerateURLBatch(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GenerateURLBatch(ctx)
+	return err
+}
+
+// ExportQRCodes converts echo context to params.
+func (w *ServerInterfaceWrapper) ExportQRCodes(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ExportQRCodes(ctx)
+	return err
+}
+
+// ListURLs converts echo context to params.
+func (w *ServerInterfaceWrapper) ListURLs(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListURLsParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// ------------- Optional query parameter "order" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "order", ctx.QueryParams(), &params.Order)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter order: %s", err))
+	}
+
+	// ------------- Optional query parameter "owner" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "owner", ctx.QueryParams(), &params.Owner)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter owner: %s", err))
+	}
+
+	// ------------- Optional query parameter "domain" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "domain", ctx.QueryParams(), &params.Domain)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter domain: %s", err))
+	}
+
+	// ------------- Optional query parameter "createdAfter" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdAfter", ctx.QueryParams(), &params.CreatedAfter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter createdAfter: %s", err))
+	}
+
+	// ------------- Optional query parameter "createdBefore" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdBefore", ctx.QueryParams(), &params.CreatedBefore)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter createdBefore: %s", err))
+	}
+
+	// ------------- Optional query parameter "expiresAfter" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "expiresAfter", ctx.QueryParams(), &params.ExpiresAfter)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter expiresAfter: %s", err))
+	}
+
+	// ------------- Optional query parameter "expiresBefore" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "expiresBefore", ctx.QueryParams(), &params.ExpiresBefore)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter expiresBefore: %s", err))
+	}
+
+	// ------------- Optional query parameter "state" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "state", ctx.QueryParams(), &params.State)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter state: %s", err))
+	}
+
+	// ------------- Optional query parameter "tag" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "tag", ctx.QueryParams(), &params.Tag)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter tag: %s", err))
+	}
+
+	// ------------- Optional query parameter "createdBy" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "createdBy", ctx.QueryParams(), &params.CreatedBy)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter createdBy: %s", err))
+	}
+
+	// ------------- Optional query parameter "externalID" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "externalID", ctx.QueryParams(), &params.ExternalID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter externalID: %s", err))
+	}
+
+	// ------------- Optional query parameter "q" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "q", ctx.QueryParams(), &params.Q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter q: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ListURLs(ctx, params)
+	return err
+}
+
 // DeleteURL converts echo context to params.
 func (w *ServerInterfaceWrapper) DeleteURL(ctx echo.Context) error {
 	var err error
@@ -49,8 +294,19 @@ func (w *ServerInterfaceWrapper) DeleteURL(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
 	}
 
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteURLParams
+	// ------------- Optional query parameter "permanent" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "permanent", ctx.QueryParams(), &params.Permanent)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter permanent: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.DeleteURL(ctx, urlKey)
+	err = w.Handler.DeleteURL(ctx, urlKey, params)
 	return err
 }
 
@@ -65,8 +321,247 @@ func (w *ServerInterfaceWrapper) GetURL(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetURLParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Link-Password" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Link-Password")]; found {
+		var XLinkPassword LinkPassword
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Link-Password, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Link-Password", runtime.ParamLocationHeader, valueList[0], &XLinkPassword)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Link-Password: %s", err))
+		}
+
+		params.XLinkPassword = &XLinkPassword
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetURL(ctx, urlKey, params)
+	return err
+}
+
+// UpdateURL converts echo context to params.
+func (w *ServerInterfaceWrapper) UpdateURL(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UpdateURL(ctx, urlKey)
+	return err
+}
+
+// GetURLInfo converts echo context to params.
+func (w *ServerInterfaceWrapper) GetURLInfo(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetURLInfoParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Link-Password" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Link-Password")]; found {
+		var XLinkPassword LinkPassword
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Link-Password, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Link-Password", runtime.ParamLocationHeader, valueList[0], &XLinkPassword)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Link-Password: %s", err))
+		}
+
+		params.XLinkPassword = &XLinkPassword
+	}
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "If-None-Match", runtime.ParamLocationHeader, valueList[0], &IfNoneMatch)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetURLInfo(ctx, urlKey, params)
+	return err
+}
+
+// GetURLQR converts echo context to params.
+func (w *ServerInterfaceWrapper) GetURLQR(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetURLQRParams
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// ------------- Optional query parameter "size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "size", ctx.QueryParams(), &params.Size)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter size: %s", err))
+	}
+
+	// ------------- Optional query parameter "margin" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "margin", ctx.QueryParams(), &params.Margin)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter margin: %s", err))
+	}
+
+	// ------------- Optional query parameter "level" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "level", ctx.QueryParams(), &params.Level)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter level: %s", err))
+	}
+
+	// ------------- Optional query parameter "foreground" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "foreground", ctx.QueryParams(), &params.Foreground)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter foreground: %s", err))
+	}
+
+	// ------------- Optional query parameter "background" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "background", ctx.QueryParams(), &params.Background)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter background: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetURLQR(ctx, urlKey, params)
+	return err
+}
+
+// RestoreURL converts echo context to params.
+func (w *ServerInterfaceWrapper) RestoreURL(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.RestoreURL(ctx, urlKey)
+	return err
+}
+
+// GetURLStats converts echo context to params.
+func (w *ServerInterfaceWrapper) GetURLStats(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetURLStats(ctx, urlKey)
+	return err
+}
+
+// SuspendURL converts echo context to params.
+func (w *ServerInterfaceWrapper) SuspendURL(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.SuspendURL(ctx, urlKey)
+	return err
+}
+
+// UnlockURL converts echo context to params.
+func (w *ServerInterfaceWrapper) UnlockURL(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.UnlockURL(ctx, urlKey)
+	return err
+}
+
+// UnsuspendURL converts echo context to params.
+func (w *ServerInterfaceWrapper) UnsuspendURL(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "urlKey" -------------
+	var urlKey string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "urlKey", runtime.ParamLocationPath, ctx.Param("urlKey"), &urlKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter urlKey: %s", err))
+	}
+
+	ctx.Set(ApiKeyAuthScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetURL(ctx, urlKey)
+	err = w.Handler.UnsuspendURL(ctx, urlKey)
 	return err
 }
 
@@ -98,8 +593,23 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/admin/keys", wrapper.ListAPIKeys)
+	router.POST(baseURL+"/admin/keys", wrapper.CreateAPIKey)
+	router.DELETE(baseURL+"/admin/keys/:keyID", wrapper.RevokeAPIKey)
+	router.POST(baseURL+"/admin/keys/:keyID/rotate", wrapper.RotateAPIKey)
 	router.POST(baseURL+"/generate", wrapper.GenerateURL)
+	router.POST(baseURL+"/generate/batch", wrapper.GenerateURLBatch)
+	router.POST(baseURL+"/qr/batch", wrapper.ExportQRCodes)
+	router.GET(baseURL+"/urls", wrapper.ListURLs)
 	router.DELETE(baseURL+"/:urlKey", wrapper.DeleteURL)
 	router.GET(baseURL+"/:urlKey", wrapper.GetURL)
+	router.PATCH(baseURL+"/:urlKey", wrapper.UpdateURL)
+	router.GET(baseURL+"/:urlKey/info", wrapper.GetURLInfo)
+	router.GET(baseURL+"/:urlKey/qr", wrapper.GetURLQR)
+	router.POST(baseURL+"/:urlKey/restore", wrapper.RestoreURL)
+	router.GET(baseURL+"/:urlKey/stats", wrapper.GetURLStats)
+	router.POST(baseURL+"/:urlKey/suspend", wrapper.SuspendURL)
+	router.POST(baseURL+"/:urlKey/unlock", wrapper.UnlockURL)
+	router.POST(baseURL+"/:urlKey/unsuspend", wrapper.UnsuspendURL)
 
 }
@@ -18,19 +18,131 @@ import (
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xVwU7kOBD9Fat2j1HSS3PKaRuxu0KLVoiFwwhxMEklMTi2KTuBFup/H9nudCedSH2Z",
-	"keZESLv8Xr33qvIFhW6NVqichfwLbNFgy8Pj5u7mLyJN/tmQNkhOYPil0CX6v25rEHIQymGNBLsEWrSW",
-	"1+MfrSOhatjtEiB87wRhCflTvOJ4/jkZzuuXVyycv+sfVEjc4eP97T2+d2jdnIkUPf6tCXsMNEuseCcd",
-	"5BWXFhMo0RYkjBNaQQ4vWkvkiglVioI7oWr20aBrkJhrkNV7vJJ1JNmHkJIp7Rh+GkGYsv+0Y0MHjNtQ",
-	"srm7iQf3wMxpFqBTSAA/eWskHsjsG9yz8B12JD3rw0FonDM2z7Ja61piWujWayTULaraNZCvkzOy+huT",
-	"iSpnlbVGK4tzaWPfD6JdcjOBg1oPQm0f72/PWz6rmFPzJUJVOoZMOV4Ez7HlQkIOPRe24W+mFApt82ft",
-	"XweRdqdWe2MqTcw2mhwqbzVnHjMBJ1zQ2rPwr9j/SL0ofBp7JBvr+1W68rdqg4obATms01W6hgQMd03Q",
-	"Jxv6CdLpmM4pi0FnyzhzHs4HqyLdhvAIZToHAYO4r7gpRzWRLMXgX+lyO2iCKiBxY2RIsVbZq/Vww/D6",
-	"p98JK8jht+w43dl+tLOFuQrCT7nH00FEfhgNtucDY2cddRisjkkK4lys/vg5fPdpXSLcFQVaW3VSbkez",
-	"zL3oaQij7dqW03Yk8siXcCL76kj+i9td9FJidHcKdB3eTzw9wkW9pFY1i7M4dTfWDt5OBLucI01aKmew",
-	"PqCXse6HCH1Y+Avq+i79Nqx0p8oTNa8XmdW4MBGEpSAsXFyfhRSowtL0/w2ipQsz4RYkW68uzkg2QjuF",
-	"+EWkmzCcsDOceIsOyUL+dNrlG25PMjfuDvwShTysKkhAcb/CISZ7NrnJqMPjh+hi823TXMH8c/Mc+CP1",
-	"A7HwDYPMO9+RtH0Bu+fd9wAAAP//QlK7B1YIAAA=",
+	"H4sIAAAAAAAC/+x9DXMbN5LoX0FNbuuSd0OK+ojj+NXVnewkG1XknCPbe3dr+r2DZpocRENgAmBEMS79",
+	"91fdAOZ7SEr+UvZla6siczBAo9Hf3eh5FyVqVSgJ0proybuo4JqvwIKmf/0Em7Pv8I8UTKJFYYWS0ZNI",
+	"pEwtmM2Anb44Y1ewieJI4IOC2yyKI8lXED2JrujtONLwWyk0pNETq0uII5NksOI4rd0UONBYLeQyur2N",
+	"o3Mhr15wY9ZKp/2FC/8El+es+lehlYXEQsqskBtW6jwAlAFPQdcg/dcEF5hUK2wH5peLpzy5WmpVygFg",
+	"EpUrHRBxWQ/0S/9Wgt7UK7cG1Iv+k4ZF9CT64qA+hgP31Bz8cvEMl/Cg/KA07AXKSqVlDmYEjkU9zb3h",
+	"WHGL74xMj0/3n9pP5+Y+h2vIx6bO6eH+M7vJ3MTPuV4KOTbzyj3df2o/nZv7pfgdxmY2+Gz/eWmqW5xW",
+	"gymUNEBs+IPSlyJNgTaQKGlB0gHwoshFwpEIDn41ih7vt9Tpi7PvtaYzvY07xNTgayYMk8oynudqjeyl",
+	"GE8SMIYITYNRpU5gGt3G0V+VhA8GHk62Fb7A5wxuhLGGXZaWScVyJZegmYZUaEisidklJLw0wIRlcFOg",
+	"FIrZmhtmSlOATCFlStMPC+X3yA1bcblhVqzAMG7mUlj86eZZLpIr43BhpuwVYYAbJZmFPDdsnYkkm7L/",
+	"zECyJowxwwksSC4trobPDOhrkQDLuGGcLXieo3yg0XPJ2fHsCHEtLB6ABltqCSkT0ljgKaH7TFrQxgor",
+	"eP7B0N6aFBFv4cYeZHaVt+foSsrtR2QytTaEgxSMFZIgY1ymbM3x14XHybUwwuLfiuFuhCwhbBlFWzhU",
+	"IZdT9lSrtQFt2BIsIqzgS2C5kFdCLnECnK+xGqHsZ2X/G+xpYsU1fAxGej90cYTLoWYtZKrWQZxXeMy4",
+	"Y0ZVAFLDBmwbD4yzTOUpYgDR0aBET21zmSi5EMtSI2HTnIR7vwAyNbeMa3A8T4jCZWK2kyJjr2lJXF2A",
+	"1ZvJ6cKC7qsqA4mSqWGltCJvb/ASEkU8545oQDcLaWEJJBVu4yho8YvKvnhoh1ptTZghawV5AIdVj4Rh",
+	"K2EMHiGKJa3kkkj3lVLPudycWgurwpqPsdE+/Eo5QUhgVDAatgYNzGoBacW6YZ9TRmfPcm5BE+SvJS9t",
+	"BtIigJB+GsgzYP81OX1xNvkJNswRZge1yCiGcclKeSXVWuJvGq7VFaSo96Z08n4tBKVaDk1krQrQVoA/",
+	"hxSGKDSOVmAMX8KwaVnbxG/cFPX4t3EYry5/hcTiXKcvzn6CTX91nq6cWePfuFQqBy7xlUQDYvyUEO3N",
+	"sidRyi1MULVFcReqOBJ0PnDDV0WOT75dPH6Uzh4fPn58knyTPvp66B1n6LzrP/DovMv6Wtm7gdxBpEiD",
+	"5RV7zDTRMI7Xc2FsH7fCwqr9xw6CxBO6rRbhWvNNH0SaaxyUl5BoGADmCjb7Q2CqScYtO6uYAYkivOaV",
+	"KTuzLOESxf8lMA3I5dcoqJZcyOlO/DtP0K8+tMdndBgOUJTaMIT3iqZTWPAyt9GTBc8NdLmchuFWDIKM",
+	"ooovq+0Zkq1JxuUSGFyD3jRdwz6zBCquaX/F9RWgsYGcyW/OQS5tFj15dBJHKyHDPw93oYQmHkdF+nTT",
+	"P6Z1ppgn244hacokY2QylgYqhy/JBUjLliBBcwSZCRvFjb38yiX8u//nNFGr9pYOjx4P8OL3Nxa05Pl2",
+	"/79WcN7S2BgLqzZgLVASviq4WMrJyTeHh/vA8Ve3K3h9cf6U2yQbJZuKS/s074wbFTAEDZNnobQz5YNF",
+	"nosVmesZMFmuLh2WaW5WgGaXCANywl5CoQF8gPuW6OfMvX14b2nRR4vzF/t40WDK3O4vyAZmRibcJdjC",
+	"MnsCS3w9dFZuHhfgIWQT8qfse2Ez0NURpq+E3Ly+OEfFDaiZUcEbsHg07f1D0Nv7mRNIrMIQ4/fh0yWw",
+	"dQaSpZCWBdSGMJcsvNbkicp5afCmkjAdlEHOP30lVtBf+eKHZ8fHx9+SU8q4db4mEWmFEO8O4xwGF9hP",
+	"5XbxOajKhUzhpg9UoYzAPwO740EFQaA9ucd9071D4jT3DqoZ1xXkKvyg1aoPnqI/eM5ayLsEjIA1EFgd",
+	"V6rAOVjB05y2JNfR7Hg2mR1OZoevZrMn9P+/741mB+dr9Hn2BXSh1WoIzIEwx5Q9Lw2pbI5OF6uxMrSF",
+	"o3tuIRfcbAE+KY1VK7ItSlP7ho6TaypF8mcXgOIWUuY8iqDW/icM+x/yQjX8Sn5SexOmQIgmhufQ1h/H",
+	"Ry3VfBxHBbcWNEL5f96cTv7OJ7/PJt/+38nbf/mnoQ0mPMngmZJWq4FDeoZPJ/5xcCoMSMvWwmae5j3d",
+	"sO+c5WJCKCIol7VI8R+WghitXRUavX+IMdA04Uv4129n7d0dff1oCOamFbFNwNXmBrlLKL36e3TibFiY",
+	"eR43fAVbhBtnEtbujF+jesVjRGi4htQR51xKJLdc/E7T45QIIsQsU8bGzBt9rFDakhFHEVVW5SWY0ino",
+	"KTtbSoWTkjjmkhF5BiUwl3c+AZeaGJPKp/b9ZTJ7VlnWiVpdCtQbjnZs/tJHRZRmObKu0mi0fkABBC1z",
+	"bhulNAy/2zgKoUmvGrqGKmgftzNsqZiSCbBOePZeMVfWCrnOpYu5xm2is57iTg5nU/afwmaqtExYH/tz",
+	"VNSMrFY262AkVhgSW/FcigXjcjNlryojkU6pHaZ1YTI8JGcL5C6+0T6xzNrCPDk4aJjeB2qxAD0hNAy6",
+	"4J24bhvhGErtxjarSOq9AqlN0zcEanGiuSyl1aVBIk7VigtpGDgTbM0HkCNZE/J9sDPGcA363+0E+veY",
+	"kCkugQSxzoDA7DPiWuQ5KXhHlVP2Myl7Z4sg1QX/mAYGErKK0dIt4D0wfegrot2iKWu/otLgXnEPqHtj",
+	"VWFQT17ReR2yFb+i8K2Sjtsp7N04kLmkE2kkLHafxWFDjAhpH51EpErFqlw1nZRGcEsqCztdiZ9p0G1M",
+	"eVubaVUus+Gkrg+D2szLhKAb0JoIUVKnCNQiCBwlg3CnyOOrMIMwjBde0NQDcJq5rOepE93ViMoANODC",
+	"4Dz1cwgbs1LmYOrBuAqzsCpyboGVxkM8l+8QiNuYvaN1bgl89/d0Xs5mx4nkK6C/4JYVOU8Ag5GgAw3g",
+	"1pwq9GbRXAY5YjJVTP2hHbh1/k3D4l/99BoWt1P2osZ1w899D4YsRtPxFUWHITU3WeVPshMf/g+ZbzBR",
+	"wU3mHGtS2RZV+YDEDfPO5Tj8DRvpm7YFeNKVr3G01sICwuC2S76IY8FXmyGL6MdXr14wY7ktnXrAfYU3",
+	"AnGhNe42t7fRIZGv3hzPDuPj2VF8PPsmPp49fts4C/x5gOs0LEBr0C9ULpKdNt9FezS+T4UBO157xfWS",
+	"Il8XNBodf77c462lGytsDjsH0yAcXZk+fdzXYjLkjPoiHZWFD+KMWVfBgNtmWz1+dDKb3VkGYjCxFTMM",
+	"fLpUallH2louSU/hX3MtuC++2Yayv4VxXQfahTQbO9vtTI+Fih5qBKKz494bgxuuSghGwkyUuedb/eq4",
+	"CmtmvADyZCWrQkXxB8gExZEDBB95qeBPgcpzgsFMtJpxMscau90vn1StMYSms632ZtfUVIsmxoYsvw+F",
+	"ssaqe/BYD6v3zb21Fx5C2M/B8GljaqEBJkjwjCwjMk5aiu/7VWE3+HNZIDswDSuFsstmsOqosaPZbFat",
+	"XO/plwsfPB2JhV22atX2KuiKm+Vgd3rJl4DtV9sVY2bItM7xTXR0+t+n2dMobgV03jYC6/1D5Tc+bv71",
+	"bLYtjB5HpRS/leAfe02fh+KyvcrG4lAVtncxWOyqvfYt8uqlzoZj5wHtPYrL4IZd/PUpo9q/2DEj+tXe",
+	"ESbLBDiVg/zzF//c8ku/mNH/olaE7It/ezObfHs6+YFPFm/fPbodjJE1y/8qzywqyLZqgydWmI5zlBI8",
+	"718umOe3IOvcm+Z6Gb0dXK0qCKwXe95bysX/E6XJq1WS0UnHTEOirkFTlOAScfLNX9iX51/F7PDrv7Av",
+	"n38VsyP845evEG/Hs7+wL3/8KoDagfM8imnhX6I4+nEE1rrGsAL2pAvqWqQ2C2v8Vgqw7HclgXFiwSaS",
+	"SJDWpZwrfuMtkUcNs2Q2ZJbUFYkVHBQ8HIIEnZMMxDLrnhGuXogbyFuLH81OHjeWf3QytP5Fz0jtWBL+",
+	"+cQN+GCxVH9UUk2CmRzFzX9NMFQ4SdVaLjWn01VauLpP94cbkGhlzKR6ZPgKGv+yWiR25N+D75fS8AVM",
+	"0EobIpuXTsVvy3HUFkLHPlYWUKGssw3rFhu1LYfA9xoKpa2LdRSZMFkvvf31gOa5HZBKr7xH0IYI/YRu",
+	"ZjhmGOLTLOHGVzw52Txlp5IBKcVcGLtFMzZ0hlMV+NuKi7ylLVqb2JGjb6qSo1k3sRlHLQ+ov80VqmEH",
+	"YIhF0E8kaPKcPPhECwta8Ck7DX8rycKbPmi9cS6wYdc8L8FQACImHHE7lzlwwgs0JqDiO6c1pgPWVCmt",
+	"FkPmydnL/2DHh48eTQ4Zz4uMT46YG70hbvcbCJUexnoZ6EZ4Bq2KB+u6L8+LnVN6/bJ1Mv0szNt3R8Pq",
+	"pXsQO61AXhRmilVkLrjKi+JApIdHx7v9rpzLZcmXQ8gKjxjR8xBmCi9TUlaNVQt2miRQ2Mm5/2nK8JBz",
+	"o6pjBzl5/bKDLJB4jnby9GIn0g7jx7dfTupMlvvlq/+1FyoxToXaeGC/1aNOQQllIJE8XxvQk9MlSDtl",
+	"K3UpciAiTcFcWVW47TG0Iguaopqvs1WhTGuPQWLj73HEZaoVVXC5+leneBJ6lgtZ3uC/aXFnAODS0dud",
+	"O+/YWbvM+07wo4criqAwQIYll1dIl5AilnCFRiQSXDxyIbSxDN+pJUQlNaj+iuLtVnXLo+eyEbCvQpwv",
+	"Q85jt+SkRbFKnfYD6VAocC67sbS9CkTa0nG3MA0BoY6uwJ+7ymLcTRKdbPxL0gQMnQZMOZocrCWF3/aj",
+	"Bsjj9cX5mVyoXbUE98rr7/dSMpIgqANeLg/WUurBIMJkmsIgalV74u0itZagnfLAyjgzZecuh+tVis1A",
+	"aJb4jJpRGmPaBgLDJ1chDsu49x5Q/Szy0mQNuZBcObUAOvYhoBXfUGWtf8eCU1rtCFAIqPWtxXuUqd43",
+	"6Z1DtVCXFledvCVmJ5HwKuxazU3mMW+AmK7mKCHrMXfIyDaDbQ86izsYGiMbRtiBKyzBqlFrefcUZzN9",
+	"VwHAzZW7J+KEJaVBuxdKfGFPI7sZ7ZFevFMGbyhx14bT56kJN1RJSBb4HmzwAfNpTQzeMaXGNVDOhep0",
+	"GsmzaFte6EW4zzAOShuCmq4AUtNI8wwuc8+cReUB7c/w1SttPq9+rkabvZncvWuEkhdbvTgnX11JM5b/",
+	"NtZsQDm0wt1yM+M1fnfL2zjtPI5btahViBsb0w8kugUJzxUM3QESxo9P98bxfmmYodf8lYr6zSom+VHS",
+	"ND9ReXw4hHjPzM3ri/MPcT8hmD0DroGEG/us1GYoypjQ7+E8cSRdLovryzSyPunChdB3XNIYrWl+fXH+",
+	"0nJr+lt9T4tpPxF8f4LoJ03CU5cxMUUubAVYlSJxjoPNYOPuVBmw+9rgnswcunb5PBXpeTQO4l7mKrna",
+	"FoZqlgH0sIF6ZhgTIxrIo4D0jsscxg3Nc7lhxVAZw07iqoAc3CMJlvuWE2+vIv6sBcTb6oY/Z8nw/Qz1",
+	"+1Rc7lVn2ZC0pNutYlaX8Mevshw83yq86sAb9aQffpHivaoG71AlOFgbGGItoq4ADKFXU9WU7FcT+OBq",
+	"5fZB6IMrFfp81TY9ReKf9vXHKIwLpYixci4xIzu53A3qmtJyQ+zFdWVg++PVkHO6re8P2b1bxXMVkXq1",
+	"91adWTO1OPNp9dGap8HSIw/o23FE3duq89uj1KDfmt8FMyKIwbAvZ0SRIeosqf1svkF7pkb+XVCw1cL6",
+	"26jB2JCTraQSnrMzHTklFdEscivFDKbLKftmRgx/PJuyvzlxatgVQNFCVGVfziU3RiyrkKGzrThLlLoS",
+	"EDOlG4WZLlp49oLktcsHmKq2wL3he6B4r4085tLZb+5g5rI6GXebhwJqIWw4FsNOVR22JrVFAfc6v+aQ",
+	"wyy/ojRMAinIBKZzeWqZy5jZtWKdlX3GjL1sGeLbqmLvYoW3Y+CHswFz3EBSamE3L/FNR/2nhfgJNqel",
+	"M5xHulX5i9k17XJ6y3U9ED6MjfqTJ0StLi/6JLrmwmT8qkiFBJP9+xJ/JgnZa5aA9e54xiZT2oJ0OHb+",
+	"qZfPEZXl4l3Lly7dF8XRNWjj3r+eTWc4qypA8kJET6Lj6Wx67GpLMtrpAcU1DkIN0HLoavo5tfFxCRR/",
+	"hxv9pCQvU2e8uN4MFFhm7pJ88/hCMByPDkUMMdNZ6id2F85N1GmrdDSbfch+FKGRwPaWSiZmWO5trEsO",
+	"UZOMk9nh2PwVwAfdThr03vHu9+rmUU1CjJ68aZPgm7e3bzFetVpxvakOJACNqxXKDBycM92NL/PDweFS",
+	"CZ5RJRkaLWOYzSiN7QDsn1izSYDvHgfGPlXp5oOd1lAfgtu2aK/Kw1sEc/iBCcZR8hjJhCYAHrEk7J0+",
+	"xLc86cw+SU8VIa95LvD4itI+VJrt0yK935A/B++oKeGto+Mc7FClM8ma5iwkIJdKta4oCNuofCati4ra",
+	"eM1J2bg+bbu5G7Tdoq6T7S07MEbtBeEnFRv4xsknoTKpqs1SLyoR4ilO+mcOvUykd6OL/ok6R6tudflm",
+	"GOZ6yIFrhXn7dpieDlzrmm4Hzf2nHROvF0BXhIwvtyGZqhaNnfiLTxquhSqDaGjfGmNitYJUcAv5ZoAk",
+	"CfIxkpx9UoHXFXQS1m1h9yfB70fwdKZ9QRgEFtHpIL2FeyKmWehPIUWCA6W/o7hGh8hwDex/NyzrWjT6",
+	"q1x0rNqVKaToyQgbz2XlnRqW8WtgUjXqGMhwIF5zLX58O59QF9gm48YFl49kNAw1c+mfqBtNGqNue9Bo",
+	"h7HLwph9HHjdGkMA+1YmTrkQlL5Oc1sbgJAyre3u2/iDmkd7Am9Kake6KPN806A57sJcn8s+Ilea2mW8",
+	"l9D69pN1y2scfGijwHMNPN2Qo903w27j7QIoHF9DirQl0AH19rmrHKpq7ESjyxTNhK3LYGXYgosc47xA",
+	"6XNpc4qCAk8yJ2e6wmsug/SKGTeebTGxmTeq0rYJG7qT8/ElTuvqzy6xc+kKMh+I8Gn3pxqlwND2qT7g",
+	"TqLUH7ffzefl7orukFVyDFbhb9SGhDpa3J/x78torWo0TwKO5X7Tu5jtgqQ4strfz14wrpMMY8mhmWzz",
+	"Vkh1PhSfkXwV2rqEDESfV76/KZS2eI0pBfORGKVzNW6ExqgvoFWYvQnNZShVpZgG6VPxQoe9mvfkl99F",
+	"0d5AFY6+FJLrzUAuuwd1+0DaN3TMZ+EAwqHSHmNUAEJD3lPVfRprm1dutFSYiSylW/5wthts6m9+JyPc",
+	"Eb5pnRmdYeBUx57011hYtObMwl8yqN5uV5QvRG5BU/I7z0Ebn/NxMsmZ0GROU7S9mTwm+DZzWRlPTkPW",
+	"NUHhWwtLCF2M3QUiXxlEbRVtuzqovsZF7aI8bDEznu3m0jdxOqerQZd4ECZU8TCNVr4L7lIA2IhriFlZ",
+	"FPVQuPG/D8d7X1+cm6jn4Hcv8VDWizVLiDr1vL6cafBbBFjb2epQXd+166TUdmXUuoC1MW+bzv0WgNxp",
+	"bP+excgnCpQe2UmjNru+Xtf8rZft2rUWHfvIYoiFxjqc/kU/Ds+/45Mkzp+uXYIWzYc+JLVvOcAXauFd",
+	"0+kIzslT3YXyNpiZMtVNy9A3ZGR213WpNX2dV95W2jiG/HByC9uBer9Oy1snfUr1UB9sVl/K82FB9ZO+",
+	"B6jts6zkqem20PehjOqTE2wDln3pSqy+it3j8OhL/wddQnYyr75MwL6k/0D6VSvy3ByBr+SwsEyVFWU5",
+	"2W8oBjRGvPS0TV2B80L7/boRhYcientftGT8OtyOJND50mAXyAL8XZNKVLtcJNqYPHfDdt0AhZsipzYS",
+	"rpJlaK+WL1s73afFwNBVJmM3efjITbSLIzb3EcaNArI7yZVBpPv+HJtAFq7ZpqMeoRmld+PQr0ITtkeI",
+	"5bcWNDuuWKEV9NH8ylANPWjd9Wykz2IiO3MHMeqV8oPO8Has0XeuWHhrguy5um7HZZp3pKiHvEs/aDAq",
+	"d3wvU9Ky1FTe3zJzxf+uAVhR6mWjcsTNxDTggQklWQFaKPo8hsLWTU5715feus3IUZJ6s5eWdRvxlY9t",
+	"c/E7euSCxlvtRTdHp8Q85AWblYsrdV3XDTbQ0rE4xuRyAXrFpWubPmAmjRQZDvDcQDKxFS0NG2pG6P4R",
+	"UyyuQLZy9+7CId8NomjQUWtfCPMXqHsN4l6Mf7HO0G2ozudgJOt8sG46l9Unf5qdXvwVffoKkGQ/vnp+",
+	"Tq1XnDlSKGNNe2arWMXqByXV/k/nckdTz1DYK3SrtHestehII8LcqLnkdFUeEau5yN0ni2wWN4AKbRTr",
+	"Gm9TQBK+TuGQfwnuaitui7Lv7epXyayay9YJDERy7SDz70jbtj5TOKbwtjND+2NbcXTsOK9T7xukQb6p",
+	"kGu6dOWuG/aus3VScM1Gh+x4djh1ix7tEBOjq07ZawNpsy1mU/42O7U0vzolnW0aZp1LlGRN45Yotl/Q",
+	"7j5W4ojPl0c6eIRu3z316Ub8XhU3bA157jf6TX+jFlaF0lyLrdgtNHUrD5EWH79kK7CZSv3kj+97dLsn",
+	"30si9z6F9fkE7J3iaXF0cvTt7sHd71/dxtHX+yic1iffbm+bsr11JLV8uN1hBmBkoWascJmh68q3v4Na",
+	"3b8a/xDq7htnVCISwvhtmNy9JjN454J8uE27cV/DfloIyFPjqFDazscbXPd2Srqn7p253N/o8vW8Q0ZX",
+	"dRHrI6UDehe9diTMKmAfcJq+JZQduO2g1v8f5YB/CLsxMGQ3+V0ZN6Fye2vMf192rgzBXmP34Rqdqt9H",
+	"jN9isGBicv1jVkUu/FIhFOHqdsZ7jnQlQaMHyYi9RTeR38/m6oVAvn/Flz5XEeLlVY0xO2XHs5PWpysr",
+	"mykFy0Vu6qBdkHhj35A+W0x+xubrz33mfzxM85EDIe4+93CuM+yq22Cn/aVOxFmfAH1xf3jXTxXjoV+C",
+	"u4xD5paQbH9c3JKddDLWY3b4DBq3fOh4uyv+aSCNGUh3LCNoy5yKdNqO70M1jFqi9Te9RbDKNPT7r4UV",
+	"immfnt3hoKeKjrHnp49JuV8u7izjGj1z9xjrOsnuM7LqUbvH2NAAdz9gQ/fgvcY3Pqe/h3CkFrIHhfsS",
+	"213qKGL/qrle/svNvT7c26h3+ew21kipxcOXXbdjEqaB3T+ghPER7IFa/4fkKg4WeoXIve+F1nDe6uRh",
+	"M1y9v7fnkTLo7l24Z8HfexAGUZWG6LD2P7gX82nLiptdnaSqwgtEYXe9wUPHNXwroZ6vZlITrpzvdLF8",
+	"78O2S0XXD0LdcOjR664U35U15lIDT0dvLdiq69HH5Q23xshBEQ6oYMCMqLw/vftBTdZB3B9Qm/lOc39E",
+	"bfaSssxdidCIglSREdJ3LisbE+xCGstlQh2ccvBZaCGvwVixrHbIL0sDvreCcck1/NpgK3VdyqpXX7+q",
+	"zD9qFiR0JUDdAP4jBUP7HeZv/VXnB6GI+90V/wxkPhhR52lnSyTT5Y7/iNLjWQao91tpcRzpbifiz3Ua",
+	"3ZdAj8YGyF4Yz5T2EyCh297ePH8zWa/X9IEh/JQESPSf0jvwZLe93whzVnvsWAH7ZkTumvI+Hmsa6MEQ",
+	"JnzXJd6G3z+jgB8rTeoIx2wn/55M+AMbFedgcbd9tVTRn/sSRF/ZV9vepu5fh0EPyhv+0wn+dE5wRVl3",
+	"zCkGwukoY5pDXwf+wh+fRAc4AAnwOsGWFf9vACwY2ETllQAA",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
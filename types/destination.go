@@ -0,0 +1,151 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	pathPlaceholder  = "path"
+	queryPlaceholder = "query"
+	// queryParamPrefix starts the placeholders of a single query parameter, such as {query.ref}
+	queryParamPrefix = "query."
+)
+
+var (
+	// placeholderPattern matches anything in braces, so that unknown placeholders are rejected
+	placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+	queryParamName     = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// IsDestinationTemplate reports whether the long url has placeholders
+func IsDestinationTemplate(longURL string) bool {
+	return placeholderPattern.MatchString(longURL)
+}
+
+// ValidateDestinationTemplate checks the placeholders of the long url are {path}, {query} or {query.<name>} and all
+// follow the host, so that visits can never change where the tiny url points to
+func ValidateDestinationTemplate(longURL string) error {
+	hostEnd := authorityEnd(longURL)
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(longURL, -1) {
+		name := longURL[m[2]:m[3]]
+		param, isParam := strings.CutPrefix(name, queryParamPrefix)
+		if name != pathPlaceholder && name != queryPlaceholder && !(isParam && queryParamName.MatchString(param)) {
+			return fmt.Errorf("%w: unknown placeholder {%s}", ErrInvalidTemplate, name)
+		}
+		if m[0] < hostEnd {
+			return fmt.Errorf("%w: placeholders must follow the host", ErrInvalidTemplate)
+		}
+	}
+	return nil
+}
+
+// Destination returns where a visit of the tiny url with the path following the key and the query goes. Passthrough
+// tiny urls render the long url when it is a destination template, and otherwise append the path to the long url and
+// add the query parameters it does not set. Values of the visit are escaped for the part of the url they end up in.
+func (u URLDocument) Destination(path string, query url.Values) string {
+	if !u.Passthrough {
+		return u.LongURL
+	}
+	segments := pathSegments(path)
+	if IsDestinationTemplate(u.LongURL) {
+		return renderTemplate(u.LongURL, segments, query)
+	}
+	return appendVisit(u.LongURL, segments, query)
+}
+
+// authorityEnd returns the index at which the host of the url ends, or the length of the url when it has no host
+func authorityEnd(rawURL string) int {
+	i := strings.Index(rawURL, "://")
+	if i < 0 {
+		return len(rawURL)
+	}
+	j := strings.IndexAny(rawURL[i+3:], "/?#")
+	if j < 0 {
+		return len(rawURL)
+	}
+	return i + 3 + j
+}
+
+// pathSegments splits the path into its segments, dropping the empty and dot segments that could climb above the
+// path of the long url
+func pathSegments(path string) []string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" && s != "." && s != ".." {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+func renderTemplate(tmpl string, segments []string, query url.Values) string {
+	queryStart, fragmentStart := strings.IndexByte(tmpl, '?'), strings.IndexByte(tmpl, '#')
+	b := new(strings.Builder)
+	last := 0
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(tmpl, -1) {
+		b.WriteString(tmpl[last:m[0]])
+		inQuery := queryStart >= 0 && m[0] > queryStart && (fragmentStart < 0 || m[0] < fragmentStart)
+		b.WriteString(placeholderValue(tmpl[m[2]:m[3]], segments, query, inQuery))
+		last = m[1]
+	}
+	b.WriteString(tmpl[last:])
+	return b.String()
+}
+
+// placeholderValue returns the escaped value of the placeholder, for the query of the url when inQuery is set and for
+// its path otherwise
+func placeholderValue(name string, segments []string, query url.Values, inQuery bool) string {
+	switch name {
+	case pathPlaceholder:
+		if inQuery {
+			return url.QueryEscape(strings.Join(segments, "/"))
+		}
+		escaped := make([]string, len(segments))
+		for i, s := range segments {
+			escaped[i] = url.PathEscape(s)
+		}
+		return strings.Join(escaped, "/")
+	case queryPlaceholder:
+		if inQuery {
+			return query.Encode()
+		}
+		return url.PathEscape(query.Encode())
+	default:
+		v := query.Get(strings.TrimPrefix(name, queryParamPrefix))
+		if inQuery {
+			return url.QueryEscape(v)
+		}
+		return url.PathEscape(v)
+	}
+}
+
+func appendVisit(longURL string, segments []string, query url.Values) string {
+	dest, err := url.Parse(longURL)
+	if err != nil {
+		return longURL
+	}
+	if len(segments) > 0 {
+		escaped := make([]string, len(segments))
+		for i, s := range segments {
+			escaped[i] = url.PathEscape(s)
+		}
+		dest = dest.JoinPath(escaped...)
+	}
+	// the parameters of the long url win over the ones of the visit
+	own, extra := dest.Query(), url.Values{}
+	for name, values := range query {
+		if _, ok := own[name]; !ok {
+			extra[name] = values
+		}
+	}
+	if encoded := extra.Encode(); encoded != "" {
+		if dest.RawQuery != "" {
+			dest.RawQuery += "&"
+		}
+		dest.RawQuery += encoded
+	}
+	return dest.String()
+}
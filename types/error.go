@@ -1,17 +1,120 @@
 package types
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
-	InternalServerError = 99
-	InputError          = 100
-	ValidationError     = 101
-	NoRouteError        = 102
-	NotFoundError       = 103
+	InternalServerError  = 99
+	InputError           = 100
+	ValidationError      = 101
+	NoRouteError         = 102
+	NotFoundError        = 103
+	ConflictError        = 104
+	UnauthorizedError    = 105
+	TooManyRequestsError = 106
+	GoneError            = 107
+	NotYetActiveError    = 108
+	ForbiddenError       = 109
+	InterstitialError    = 110
 
 	ErrNoPath           = errors.New("no route to the path. Check the URI")
 	ErrDocumentNotFound = errors.New("no entry found for the key")
 	ErrCacheNotFound    = errors.New("no cache found for the key")
 	ErrInvalidScheme    = errors.New("unsupported scheme")
 	ErrInvalidInput     = errors.New("invalid input")
+	ErrDuplicateKey     = errors.New("an entry already exists for the key")
+	ErrDuplicateURL     = errors.New("an entry already exists for the url")
+	ErrInvalidAlias     = errors.New("alias must be 3-32 characters of letters, digits, '-' or '_'")
+	ErrReservedAlias    = errors.New("alias is a reserved word")
+	ErrAliasTaken       = errors.New("alias is already taken")
+	ErrConflictExpiry   = errors.New("only one of liveForever, ttlSeconds and expireAt can be set")
+	ErrMissingExpiry    = errors.New("expireAt is required to turn off liveForever")
+	ErrExpiryOutOfRange = errors.New("expiry is out of the allowed range")
+	ErrEmptyUpdate      = errors.New("update does not change any field")
+	ErrInvalidRedirect  = errors.New("invalid redirect settings")
+	ErrBatchTooLarge    = errors.New("batch has more items than allowed")
+	ErrInvalidPassword  = errors.New("password must be 4-72 bytes")
+	ErrPasswordRequired = errors.New("the tiny url is password protected")
+	ErrWrongPassword    = errors.New("wrong password")
+	ErrTooManyAttempts  = errors.New("too many wrong passwords, retry later")
+	ErrClicksExhausted  = errors.New("the tiny url reached its click limit")
+	ErrInvalidMaxClicks = errors.New("maxClicks must be positive")
+	ErrLinkNotYetActive = errors.New("the tiny url is not active yet")
+	ErrLinkExpired      = errors.New("the tiny url has expired")
+	ErrLinkInactive     = fmt.Errorf("%w: the tiny url is no longer active", ErrLinkExpired)
+	ErrLinkSuspended    = errors.New("the tiny url is suspended")
+	ErrInvalidWindow    = errors.New("activeUntil must be after activeFrom")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidListLimit = errors.New("limit is out of the allowed range")
+	ErrUnauthenticated  = errors.New("missing or invalid api key")
+	ErrForbidden        = errors.New("the api key is not allowed to access the resource")
+	ErrInvalidKeyName   = errors.New("key name must be 1-64 characters")
+	ErrInvalidTenant    = errors.New("invalid tenant")
+	ErrInvalidQROptions = errors.New("invalid qr code options")
+	ErrInterstitial     = errors.New("the tiny url shows its destination before redirecting")
+	ErrInvalidTemplate  = errors.New("invalid destination template")
+	ErrInvalidRules     = errors.New("invalid targeting rules")
+	ErrInvalidVariants  = errors.New("invalid variants")
+	ErrInvalidMetadata  = errors.New("invalid metadata")
+	ErrNotInTrash       = errors.New("the tiny url is not in the trash")
+	ErrNotSuspended     = errors.New("the tiny url is not suspended")
+	ErrInvalidReason    = errors.New("suspension reason must be at most 500 characters")
+	ErrInvalidFallback  = errors.New("fallbackURL must be an http or https url")
+)
+
+// GoneReason tells why a tiny url that exists no longer redirects
+type GoneReason string
+
+const (
+	GoneExpired   GoneReason = "expired"
+	GoneSuspended GoneReason = "suspended"
+	GoneExhausted GoneReason = "exhausted"
 )
+
+// LinkGoneError is returned for a visit of a tiny url that no longer redirects and has a fallback url to send it to
+type LinkGoneError struct {
+	// Err tells why the tiny url no longer redirects, see GoneReasonOf
+	Err         error
+	FallbackURL string
+}
+
+func (e *LinkGoneError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap lets errors.Is match the error with the reason the tiny url no longer redirects
+func (e *LinkGoneError) Unwrap() error {
+	return e.Err
+}
+
+// GoneReasonOf returns the reason of an error returned for a tiny url that no longer redirects. False is returned for
+// other errors.
+func GoneReasonOf(err error) (GoneReason, bool) {
+	switch {
+	case errors.Is(err, ErrLinkExpired):
+		return GoneExpired, true
+	case errors.Is(err, ErrLinkSuspended):
+		return GoneSuspended, true
+	case errors.Is(err, ErrClicksExhausted):
+		return GoneExhausted, true
+	default:
+		return "", false
+	}
+}
+
+// LinkNotYetActiveError is returned for a visit of a tiny url before its activation window opens
+type LinkNotYetActiveError struct {
+	ActiveFrom time.Time
+}
+
+func (e *LinkNotYetActiveError) Error() string {
+	return fmt.Sprintf("%s, it becomes active at %s", ErrLinkNotYetActive, e.ActiveFrom.UTC().Format(time.RFC3339))
+}
+
+// Unwrap lets errors.Is match the error with ErrLinkNotYetActive
+func (e *LinkNotYetActiveError) Unwrap() error {
+	return ErrLinkNotYetActive
+}
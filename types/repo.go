@@ -1,10 +1,84 @@
 package types
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
-// URLRepo abstraction for the repository to store tiny urls
+// URLRepo abstraction for the repository to store tiny urls. Documents are addressed by their url key within a
+// tenant, an empty tenant being the default tenant.
 type URLRepo interface {
 	Put(ctx context.Context, document any) error
-	GetDocument(ctx context.Context, urlKey string) (URLDocument, error)
-	Delete(ctx context.Context, urlKey string) error
+	// PutMany stores all the documents without stopping at the first failure. The returned slice holds the error of
+	// every document that was not stored at its index, the returned error is set when the whole write failed.
+	PutMany(ctx context.Context, documents []URLDocument) ([]error, error)
+	GetDocument(ctx context.Context, tenant, urlKey string) (URLDocument, error)
+	// GetDocumentByDedupeHash retrieves the document generated with dedupe for the hash of a long url
+	GetDocumentByDedupeHash(ctx context.Context, dedupeHash string) (URLDocument, error)
+	// ClearDedupeHash removes the dedupe hash of the document so that a new document can be stored for the hash
+	ClearDedupeHash(ctx context.Context, tenant, urlKey, dedupeHash string) error
+	Delete(ctx context.Context, tenant, urlKey string) error
+	// Trash marks the document as deleted at the given time and clears its dedupe hash, unless it already is in the
+	// trash
+	Trash(ctx context.Context, tenant, urlKey string, deletedAt time.Time) error
+	// Restore moves the document out of the trash and returns it
+	Restore(ctx context.Context, tenant, urlKey string) (URLDocument, error)
+	// PurgeTrash deletes the documents of every tenant moved to the trash before the given time, and returns how many
+	// were deleted
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+	// SetSuspension sets the suspension of the document, or lifts it when nil, and returns the updated document
+	SetSuspension(ctx context.Context, tenant, urlKey string, suspension *Suspension) (URLDocument, error)
+	// Update applies the non nil fields of the update and returns the updated document. Changing the long url clears
+	// the dedupe hash.
+	Update(ctx context.Context, tenant, urlKey string, update URLUpdate) (URLDocument, error)
+	// AddClicks adds the clicks of every count to the click count of its document, or of the variant of its document,
+	// with a single write. Counts of documents or variants that do not exist are ignored.
+	AddClicks(ctx context.Context, counts []ClickCount) error
+	// ConsumeClick adds one to the click count of the document unless it reached the max clicks, and returns the clicks
+	// left. ok is false when no click was left.
+	ConsumeClick(ctx context.Context, tenant, urlKey string) (remaining int64, ok bool, err error)
+	// List returns up to query.Limit documents matching the filter of the query in the order it asks for, starting
+	// after query.After when it is set. Documents with the same sort value are ordered by url key.
+	List(ctx context.Context, query ListQuery) ([]URLDocument, error)
+}
+
+// APIKeyRepo abstraction for the repository to store API keys
+type APIKeyRepo interface {
+	Put(ctx context.Context, key APIKey) error
+	// GetByHash retrieves the key with the hash of a secret
+	GetByHash(ctx context.Context, hash string) (APIKey, error)
+	List(ctx context.Context) ([]APIKey, error)
+	// Rotate replaces the hash of the key unless it was revoked, and returns the updated key
+	Rotate(ctx context.Context, keyID, hash string, rotatedAt time.Time) (APIKey, error)
+	// Revoke marks the key as revoked unless it already is
+	Revoke(ctx context.Context, keyID string, revokedAt time.Time) error
+}
+
+// ListQuery is a query listing tiny urls of a tenant from the repo
+type ListQuery struct {
+	Tenant    string
+	Filter    URLFilter
+	Sort      ListSort
+	Ascending bool
+	Limit     int
+	After     *ListCursor
+}
+
+// ListCursor is the position of the last listed document. Only the value of the sort field is set.
+type ListCursor struct {
+	CreatedAt time.Time `json:"c,omitempty"`
+	Clicks    int64     `json:"n,omitempty"`
+	URLKey    string    `json:"k"`
+}
+
+// ClickRepo abstraction for the repository to store click events
+type ClickRepo interface {
+	// PutMany stores the events with a single write
+	PutMany(ctx context.Context, events []ClickEvent) error
+}
+
+// SequenceRepo abstraction for a store handing out ranges of monotonic ids
+type SequenceRepo interface {
+	// NextRange reserves size ids for the named sequence and returns the last id of the reserved range
+	NextRange(ctx context.Context, name string, size int64) (int64, error)
 }
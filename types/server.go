@@ -23,6 +23,11 @@ type (
 		Register(*Server)
 	}
 
+	// Worker is a background job running alongside the server until its context is done
+	Worker interface {
+		Run(ctx context.Context, wg *sync.WaitGroup)
+	}
+
 	// Handler represents the V0 API
 	Handler interface {
 		Registerer
@@ -33,6 +38,27 @@ type (
 	OpenAPISchema interface {
 		ValidateRequest(req *http.Request) error
 		ValidationMiddleware() MiddlewareFunc
+		// AuthenticationMiddleware authenticates the API key of requests to operations declaring a security
+		// requirement. It must be registered before ValidationMiddleware.
+		AuthenticationMiddleware(auth Authenticator) MiddlewareFunc
+	}
+
+	// HandlerConfig holds the settings of the rest handlers
+	HandlerConfig struct {
+		// NotYetActiveURL is where visits of tiny urls that are not active yet are redirected. The holding page is
+		// served when it is empty.
+		NotYetActiveURL string
+		// HoldingPage optionally replaces the built-in holding page. It is an html/template executed with the
+		// ActiveFrom time of the tiny url.
+		HoldingPage string
+		// Tenants are resolved from the host of requests. Requests for other hosts belong to the default tenant.
+		Tenants []Tenant
+		// CountryHeader is the request header carrying the country of the client, set by a proxy or a CDN. Targeting
+		// rules on countries never match when it is empty.
+		CountryHeader string
+		// IPHashKey keys the hashes of client IPs in click events and visitor ids, so that they cannot be reversed. A
+		// random key is used when it is empty, which changes the hashes on every restart and between instances.
+		IPHashKey string
 	}
 
 	// Server represents an HTTP server
@@ -46,13 +72,62 @@ type (
 		Message string
 	}
 
+	// GoneResponse is returned for visits of tiny urls that exist but no longer redirect. It has the shape of an
+	// APIError with the reason the tiny url is gone.
+	GoneResponse struct {
+		Code    int
+		Message string
+		Reason  GoneReason
+	}
+
+	// InterstitialResponse is returned to API clients following a tiny url with an interstitial instead of the
+	// redirect. It has the shape of an APIError with the destination of the tiny url.
+	InterstitialResponse struct {
+		Code        int
+		Message     string
+		Destination string
+	}
+
 	openAPISchema3 struct {
 		router routers.Router
 	}
 )
 
+const (
+	// APIKeyHeader carries the API key of a request
+	APIKeyHeader = "X-API-Key"
+)
+
+// principalKey is the request context key of the authenticated principal
+type principalKey struct{}
+
+// tenantKey is the request context key of the id of the tenant
+type tenantKey struct{}
+
 var emptySpecError = errors.New("empty oas spec")
 
+// WithPrincipal returns a copy of the context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal authenticated for the request. ok is false for public operations.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// WithTenant returns a copy of the context scoped to the tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the id of the tenant the request was resolved to, which is empty for the default tenant
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
+
 func (ae *APIError) Error() string {
 	return ae.Message
 }
@@ -81,6 +156,15 @@ func (o *openAPISchema3) ValidateRequest(req *http.Request) error {
 		Request:    req,
 		PathParams: pathParam,
 		Route:      route,
+		Options: &openapi3filter.Options{
+			// the API key was authenticated by the authentication middleware
+			AuthenticationFunc: func(_ context.Context, input *openapi3filter.AuthenticationInput) error {
+				if _, ok := PrincipalFromContext(input.RequestValidationInput.Request.Context()); !ok {
+					return ErrUnauthenticated
+				}
+				return nil
+			},
+		},
 	}
 	ctx, cancel := context.WithCancel(req.Context())
 	defer cancel()
@@ -113,6 +197,56 @@ func (o *openAPISchema3) ValidationMiddleware() MiddlewareFunc {
 	}
 }
 
+// AuthenticationMiddleware authenticates the API key of requests to operations that declare a security requirement
+// and adds the principal to the request context. Requests without a valid key get a 401, unless the key is optional
+// for the operation and the request does not send one.
+func (o *openAPISchema3) AuthenticationMiddleware(auth Authenticator) MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route, _, err := o.router.FindRoute(c.Request())
+			if err != nil {
+				// unknown routes are answered by the validation middleware
+				return next(c)
+			}
+			apiKey := c.Request().Header.Get(APIKeyHeader)
+			required, optional := authentication(route)
+			if !required || (optional && apiKey == "") {
+				return next(c)
+			}
+			p, err := auth.Authenticate(c.Request().Context(), apiKey)
+			if err != nil {
+				if errors.Is(err, ErrUnauthenticated) {
+					return c.JSON(http.StatusUnauthorized, &APIError{
+						Code:    UnauthorizedError,
+						Message: err.Error(),
+					})
+				}
+				return c.JSON(http.StatusInternalServerError, &APIError{
+					Code:    InternalServerError,
+					Message: err.Error(),
+				})
+			}
+			c.SetRequest(c.Request().WithContext(WithPrincipal(c.Request().Context(), p)))
+			return next(c)
+		}
+	}
+}
+
+// authentication reports whether the operation of the route, or the spec when the operation does not say, declares a
+// security requirement, and whether one of its requirements is empty, which makes the API key optional
+func authentication(route *routers.Route) (required, optional bool) {
+	security := route.Operation.Security
+	if security == nil {
+		security = &route.Spec.Security
+	}
+	for _, requirement := range *security {
+		if len(requirement) == 0 {
+			optional = true
+		}
+	}
+	return len(*security) > 0, optional
+}
+
 // Run starts and runs the server
 func (s *Server) Run(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
@@ -3,6 +3,8 @@ package types
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -10,6 +12,11 @@ import (
 
 const (
 	urlFormat = "%s://%s/tinyurlsvc/%s"
+	// tenantURLFormat is the format of the tiny urls of tenants, which are served from the root of their domain
+	tenantURLFormat = "%s://%s/%s"
+	// ClickCountsPrefix prefixes the cache key of the clicks counted and not added to the db yet. Its ':' keeps it
+	// apart from the keys of the default tenant, and the tenant id it names is reserved.
+	ClickCountsPrefix = "click-counts:"
 )
 
 // Metrics represents the abstraction for a service to be able to push metrics
@@ -20,32 +27,501 @@ type Metrics interface {
 // URLService represents domain service abstraction where biz logic resides.
 type URLService interface {
 	Metrics
-	GenerateTinyURL(ctx context.Context, longURL string, liveForever bool) (URLDocument, error)
-	GetTinyURL(ctx context.Context, urlKey string) (URLDocument, error)
-	DeleteTinyURL(ctx context.Context, urlKey string) error
+	// GenerateTinyURL generates a tiny url. The returned bool is true when dedupe returned a tiny url that was already
+	// stored for the long url instead of generating one.
+	GenerateTinyURL(ctx context.Context, req GenerateRequest) (URLDocument, bool, error)
+	// GenerateTinyURLs generates a tiny url for every request. The results are in the order of the requests and
+	// fail independently of each other.
+	GenerateTinyURLs(ctx context.Context, reqs []GenerateRequest) ([]GenerateResult, error)
+	// GetTinyURL resolves a tiny url for a visit and counts the click. Password protected tiny urls fail with
+	// ErrPasswordRequired or ErrWrongPassword unless the visit carries the password. The long url of the returned
+	// document is the destination of the first targeting rule matching the visit, if any, or else of the variant the
+	// visitor is assigned to. Tiny urls that exist but no longer redirect fail with an error GoneReasonOf knows.
+	GetTinyURL(ctx context.Context, urlKey string, visit Visit) (URLDocument, error)
+	// GetTinyURLStats returns the stored document of a tiny url owned by the caller, which holds the clicks of the tiny
+	// url and of its variants. Admins can read the stats of any tiny url.
+	GetTinyURLStats(ctx context.Context, urlKey string, caller Principal) (URLDocument, error)
+	// GetTinyURLInfo returns the stored document of a tiny url without counting a click. Password protected tiny
+	// urls need the password, and expired or suspended tiny urls fail like they do in GetTinyURL.
+	GetTinyURLInfo(ctx context.Context, urlKey, password string) (URLDocument, error)
+	// DeleteTinyURL moves a tiny url owned by the caller to the trash, where it stops resolving until it is restored
+	// or purged once the retention period passes. Admins can delete any tiny url.
+	DeleteTinyURL(ctx context.Context, urlKey string, caller Principal) error
+	// RestoreTinyURL moves a tiny url owned by the caller out of the trash. ErrNotInTrash is returned for tiny urls
+	// that are not in the trash. Admins can restore any tiny url.
+	RestoreTinyURL(ctx context.Context, urlKey string, caller Principal) (URLDocument, error)
+	// PurgeTinyURL deletes a tiny url for good, whether or not it is in the trash. Only admins can purge tiny urls.
+	PurgeTinyURL(ctx context.Context, urlKey string, caller Principal) error
+	// SuspendTinyURL stops a tiny url from redirecting until it is unsuspended. Visits fail with ErrLinkSuspended.
+	// Only admins can suspend tiny urls.
+	SuspendTinyURL(ctx context.Context, urlKey, reason string, caller Principal) (URLDocument, error)
+	// UnsuspendTinyURL lets a suspended tiny url redirect again. ErrNotSuspended is returned for tiny urls that are
+	// not suspended. Only admins can unsuspend tiny urls.
+	UnsuspendTinyURL(ctx context.Context, urlKey string, caller Principal) (URLDocument, error)
+	// UpdateTinyURL updates a tiny url owned by the caller. Admins can update any tiny url.
+	UpdateTinyURL(ctx context.Context, urlKey string, update URLUpdate, caller Principal) (URLDocument, error)
+	// ListTinyURLs returns a page of the tiny urls matching the filter of the request, continuing after its cursor.
+	// Only admins can list the tiny urls of other owners.
+	ListTinyURLs(ctx context.Context, req ListRequest, caller Principal) (URLPage, error)
+}
+
+// Authenticator resolves the principal an API key belongs to
+type Authenticator interface {
+	// Authenticate returns the principal of the API key. ErrUnauthenticated is returned for unknown and revoked keys.
+	Authenticate(ctx context.Context, apiKey string) (Principal, error)
+}
+
+// ClickRecorder records the click events of the visits of tiny urls. Recording never blocks the visit.
+type ClickRecorder interface {
+	Record(event ClickEvent)
+}
+
+// ClickCounter counts the clicks of tiny urls in the cache and adds them to the db in batches in the background
+type ClickCounter interface {
+	Worker
+	// Count adds a click to the tiny url of the key of the tenant
+	Count(ctx context.Context, tenant, urlKey string)
+	// CountVariant adds a click to the variant at the index of the tiny url of the key of the tenant
+	CountVariant(ctx context.Context, tenant, urlKey string, variant int)
+	// Pending returns the clicks counted for the tiny url that were not added to the db yet
+	Pending(ctx context.Context, tenant, urlKey string) (int64, error)
+	// PendingVariants returns the clicks counted for each of the first n variants of the tiny url that were not added
+	// to the db yet
+	PendingVariants(ctx context.Context, tenant, urlKey string, n int) ([]int64, error)
+	// Flush adds the clicks counted for the tiny url that were not added to the db yet to the db
+	Flush(ctx context.Context, tenant, urlKey string) error
+}
+
+// ClickPipeline queues the click events it records and writes them to the db in batches in the background
+type ClickPipeline interface {
+	Metrics
+	ClickRecorder
+	Worker
+}
+
+// KeyService manages the API keys used to call the API. Secrets are only returned when a key is created or rotated.
+type KeyService interface {
+	Authenticator
+	CreateKey(ctx context.Context, name string, admin bool) (APIKey, string, error)
+	ListKeys(ctx context.Context) ([]APIKey, error)
+	// RotateKey replaces the secret of the key. The previous secret stops working immediately.
+	RotateKey(ctx context.Context, keyID string) (APIKey, string, error)
+	// RevokeKey disables the key for good. Revoked keys are still listed.
+	RevokeKey(ctx context.Context, keyID string) error
+}
+
+// Principal is the caller authenticated by an API key
+type Principal struct {
+	// KeyID identifies the API key. It is recorded as the owner of the tiny urls the key generates.
+	KeyID string
+	Admin bool
+}
+
+// Manages reports whether the principal may change the tiny url. Admins may change any tiny url, other principals
+// only the tiny urls they own.
+func (p Principal) Manages(tinyURL URLDocument) bool {
+	return p.Admin || (tinyURL.Owner != "" && tinyURL.Owner == p.KeyID)
+}
+
+// APIKey represents an API key stored in the db. Only the hash of its secret is stored.
+type APIKey struct {
+	ID        string    `bson:"key_id"`
+	Name      string    `bson:"name"`
+	Admin     bool      `bson:"admin"`
+	Hash      string    `bson:"key_hash"`
+	CreatedAt time.Time `bson:"created_at"`
+	RotatedAt time.Time `bson:"rotated_at,omitempty"`
+	RevokedAt time.Time `bson:"revoked_at,omitempty"`
+}
+
+// Tenant is a workspace with its own short domain and key namespace, so that the same key can be used by several
+// tenants. Requests are resolved to the tenant whose domain matches their host; every other host belongs to the default
+// tenant, which has an empty id.
+type Tenant struct {
+	ID     string
+	Domain string
+}
+
+// KeyGenerator represents a strategy for generating the key of a tiny url
+type KeyGenerator interface {
+	// Generate returns the base10 id and the key for the long url. attempt starts at 0 and is incremented
+	// every time a previously generated key collided with an existing one.
+	Generate(ctx context.Context, longURL string, attempt int) (int64, string, error)
+}
+
+// GenerateRequest holds the inputs used to generate a tiny url
+type GenerateRequest struct {
+	LongURL     string
+	LiveForever bool
+	// Alias is an optional custom key used instead of a generated one
+	Alias string
+	// TTL is an optional duration the tiny url lives for
+	TTL time.Duration
+	// ExpireAt is an optional time at which the tiny url expires
+	ExpireAt time.Time
+	// RedirectStatus, CacheControl and ReferrerPolicy optionally override the service wide redirect settings
+	RedirectStatus int
+	CacheControl   string
+	ReferrerPolicy string
+	// Dedupe optionally overrides the service wide dedupe setting. It is ignored for aliases, passwords, click
+	// limits, activation windows, interstitials, passthrough, targeting rules, variants and fallback urls.
+	Dedupe *bool
+	// Password optionally protects the tiny url. Only its hash is stored.
+	Password string
+	// MaxClicks optionally limits the number of redirects of the tiny url
+	MaxClicks int64
+	// ActiveFrom and ActiveUntil optionally bound the window in which the tiny url redirects
+	ActiveFrom  time.Time
+	ActiveUntil time.Time
+	// Interstitial shows the destination and waits for the visitor to continue instead of redirecting
+	Interstitial bool
+	// Passthrough passes the path following the key and the query of visits on to the long url, which can be a
+	// destination template
+	Passthrough bool
+	// Rules optionally send matching visits elsewhere than the long url
+	Rules []TargetingRule
+	// Variants optionally split the visits across several destinations by weight
+	Variants []Variant
+	// FallbackURL optionally receives the visits once the tiny url expired, was suspended or reached its click limit
+	FallbackURL string
+	// Metadata describes the tiny url for the people managing it
+	Metadata
+	// Owner is the id of the API key generating the tiny url
+	Owner string
+}
+
+// Metadata holds the free-form details of a tiny url, which do not change where it points to
+type Metadata struct {
+	Title string `bson:"title,omitempty"`
+	Notes string `bson:"notes,omitempty"`
+	// Tags are lower cased and unique
+	Tags []string `bson:"tags,omitempty"`
+	// CreatedBy names who created the tiny url, such as a user of the client generating it
+	CreatedBy string `bson:"created_by,omitempty"`
+	// ExternalID is the id of the tiny url in the system of the client
+	ExternalID string `bson:"external_id,omitempty"`
+}
+
+// Visit holds the details of a request to follow a tiny url
+type Visit struct {
+	// Password is the password given for a password protected tiny url
+	Password string
+	// Path is the path following the key in the visited url. Only passthrough tiny urls accept one.
+	Path string
+	// Platform, Language and Country describe the client for targeting rules. They are empty when unknown.
+	Platform string
+	Language string
+	Country  string
+	// VisitorID identifies the visitor so that it keeps the variant it was assigned to. Visitors without one get a
+	// random variant.
+	VisitorID string
+}
+
+// ClickEvent is a visit of a tiny url stored for analytics
+type ClickEvent struct {
+	// Tenant is the id of the tenant of the tiny url. It is empty for the default tenant.
+	Tenant    string    `bson:"tenant,omitempty"`
+	URLKey    string    `bson:"url_key"`
+	Time      time.Time `bson:"time"`
+	Referrer  string    `bson:"referrer,omitempty"`
+	UserAgent string    `bson:"user_agent,omitempty"`
+	// IPHash is a hash of the IP of the client, which is never stored as is
+	IPHash string `bson:"ip_hash,omitempty"`
+	// Destination is the url the visit was sent to, after targeting rules, variants and passthrough
+	Destination string `bson:"destination"`
+}
+
+// ClickCount is a number of clicks of the tiny url of the key of a tenant, or of one of its variants
+type ClickCount struct {
+	Tenant string
+	URLKey string
+	// Variant is the index of the variant the clicks are counted for, nil for the clicks of the tiny url
+	Variant *int
+	Clicks  int64
+}
+
+// ClickCounterConfig holds the settings of the click counter
+type ClickCounterConfig struct {
+	// FlushInterval is how often the counted clicks are added to the db
+	FlushInterval time.Duration
+}
+
+// ClickPipelineConfig holds the settings of the click event pipeline
+type ClickPipelineConfig struct {
+	// QueueSize bounds the events waiting to be written. Events recorded while it is full are dropped.
+	QueueSize int
+	// Workers write the queued events in batches of up to BatchSize, at least every FlushInterval
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Platforms of the clients visiting tiny urls
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	// PlatformMobile and PlatformDesktop match groups of platforms in targeting rules
+	PlatformMobile  = "mobile"
+	PlatformDesktop = "desktop"
+)
+
+// TargetingRule sends the visits it matches to its destination instead of the long url. A rule matches the visits
+// matching all its criteria, and a criterion matches when any of its values does. Empty criteria match every visit.
+type TargetingRule struct {
+	// Platforms are the platforms of the clients, see PlatformIOS
+	Platforms []string `bson:"platforms,omitempty"`
+	// Languages are language tags such as en or pt-br matched against the preferred language of the client. A tag
+	// also matches its more specific tags.
+	Languages []string `bson:"languages,omitempty"`
+	// Countries are ISO 3166-1 alpha-2 codes
+	Countries   []string `bson:"countries,omitempty"`
+	Destination string   `bson:"destination"`
+}
+
+// Variant is one of the destinations the visits of a split tiny url are sent to
+type Variant struct {
+	URL string `bson:"url"`
+	// Weight is the share of the visits relative to the weights of the other variants
+	Weight int `bson:"weight"`
+	// Clicks is the number of visits sent to the variant. Like the clicks of the tiny url, it is only accurate when
+	// read from the db.
+	Clicks int64 `bson:"clicks" json:"-"`
+}
+
+// Suspension stops a tiny url from redirecting without deleting it, for instance while it is investigated for abuse
+type Suspension struct {
+	At time.Time `bson:"at"`
+	// Reason is an optional note of the admin suspending the tiny url
+	Reason string `bson:"reason,omitempty"`
+}
+
+// GenerateResult holds the outcome of a request of a batch. Err is set when the request failed.
+type GenerateResult struct {
+	Document URLDocument
+	// Existing is true when dedupe returned a tiny url that was already stored for the long url
+	Existing bool
+	Err      error
+}
+
+// URLUpdate holds a partial update of a tiny url. Nil fields are left unchanged.
+type URLUpdate struct {
+	LongURL      *string
+	ExpireAt     *time.Time
+	LiveForever  *bool
+	ActiveFrom   *time.Time
+	ActiveUntil  *time.Time
+	Interstitial *bool
+	Passthrough  *bool
+	// Rules replaces the targeting rules, an empty slice removes them
+	Rules *[]TargetingRule
+	// Variants replaces the variants and resets their clicks, an empty slice removes them
+	Variants *[]Variant
+	// FallbackURL replaces the fallback url, an empty url removes it
+	FallbackURL *string
+	// Title, Notes, Tags, CreatedBy and ExternalID replace the metadata, empty values remove it
+	Title      *string
+	Notes      *string
+	Tags       *[]string
+	CreatedBy  *string
+	ExternalID *string
+}
+
+// ListSort is the field tiny urls are listed by
+type ListSort string
+
+const (
+	SortCreatedAt ListSort = "createdAt"
+	SortClicks    ListSort = "clicks"
+)
+
+// URLState is the expiry state tiny urls are listed by
+type URLState string
+
+const (
+	StateActive  URLState = "active"
+	StateExpired URLState = "expired"
+	// StateTrashed lists the tiny urls in the trash, which the other states leave out
+	StateTrashed URLState = "trashed"
+)
+
+// URLFilter narrows the listed tiny urls. Zero fields do not filter.
+type URLFilter struct {
+	// Owner matches the id of the API key that generated the tiny url
+	Owner string
+	// Domain matches the host of the long url
+	Domain string
+	// CreatedAfter and CreatedBefore bound the creation time, ExpiresAfter and ExpiresBefore the expire time
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	State         URLState
+	// Tags matches the tiny urls having all the tags
+	Tags       []string
+	CreatedBy  string
+	ExternalID string
+	// Search matches the tiny urls with any of its words in their title, notes or tags
+	Search string
+}
+
+// ListRequest asks for a page of tiny urls
+type ListRequest struct {
+	Filter URLFilter
+	// Sort defaults to the creation time, newest first unless Ascending is set
+	Sort      ListSort
+	Ascending bool
+	// Limit bounds the size of the page, zero uses the default page size
+	Limit int
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor string
+}
+
+// URLPage is a page of listed tiny urls. NextCursor is empty on the last page.
+type URLPage struct {
+	Documents  []URLDocument
+	NextCursor string
+}
+
+// URLServiceConfig holds the settings of the url service
+type URLServiceConfig struct {
+	// DefaultExpiry is used when a request does not ask for an expiry
+	DefaultExpiry time.Duration
+	// MinExpiry and MaxExpiry bound the expiry a request may ask for
+	MinExpiry time.Duration
+	MaxExpiry time.Duration
+	// Redirect holds the redirect settings used for tiny urls that do not set their own
+	Redirect RedirectConfig
+	// MaxBatchSize limits the number of tiny urls generated by one batch
+	MaxBatchSize int
+	// Dedupe returns the existing tiny url of a long url for requests that do not set dedupe
+	Dedupe bool
+	// PasswordAttempts limits the wrong passwords tried for a tiny url within PasswordAttemptWindow
+	PasswordAttempts      int
+	PasswordAttemptWindow time.Duration
+	// TrustedDomains are the destinations tiny urls redirect to right away, along with their subdomains. Tiny urls
+	// pointing elsewhere always show the interstitial. Every domain is trusted when it is empty.
+	TrustedDomains []string
+	// TrashRetention is how long deleted tiny urls stay in the trash before they are purged every PurgeInterval
+	TrashRetention time.Duration
+	PurgeInterval  time.Duration
+	// FallbackURL receives the visits of tiny urls that no longer redirect and have no fallback url of their own.
+	// TenantFallbackURLs replace it for the tenants they have an entry for.
+	FallbackURL        string
+	TenantFallbackURLs map[string]string
+	// ExpiredRetention is how long expired tiny urls are kept, to send visits to their fallback url or answer them
+	// with a 410, before they are removed
+	ExpiredRetention time.Duration
+}
+
+// RedirectConfig holds the HTTP status and headers used to redirect to a long url
+type RedirectConfig struct {
+	Status         int
+	CacheControl   string
+	ReferrerPolicy string
 }
 
 // URLDocument represents a data stored in the db for a tiny url which is generated
 type URLDocument struct {
-	Base10ID    int64     `bson:"base_10_id"`
+	Base10ID int64 `bson:"base_10_id"`
+	// Tenant is the id of the tenant the key belongs to. It is empty for the default tenant.
+	Tenant      string    `bson:"tenant,omitempty"`
 	URLKey      string    `bson:"url_key"`
 	LongURL     string    `bson:"long_url"`
 	ExpireTime  time.Time `bson:"expire_time"`
 	LiveForever bool      `bson:"live_forever"`
+	CreatedAt   time.Time `bson:"created_at"`
+	// UpdatedAt is the time of the last update, the creation time until the tiny url is updated
+	UpdatedAt time.Time `bson:"updated_at,omitempty"`
+	// DeletedAt is the time the tiny url was moved to the trash, zero unless it is in the trash
+	DeletedAt time.Time `bson:"deleted_at,omitempty"`
+	// Suspension is set while the tiny url is suspended
+	Suspension *Suspension `bson:"suspension,omitempty"`
+	// RedirectStatus, CacheControl and ReferrerPolicy are empty when the service wide settings apply
+	RedirectStatus int    `bson:"redirect_status,omitempty"`
+	CacheControl   string `bson:"cache_control,omitempty"`
+	ReferrerPolicy string `bson:"referrer_policy,omitempty"`
+	// DedupeHash is the hash of the normalized long url. It is only set for tiny urls generated with dedupe.
+	DedupeHash string `bson:"dedupe_hash,omitempty"`
+	// PasswordHash is the bcrypt hash of the password protecting the tiny url
+	PasswordHash string `bson:"password_hash,omitempty"`
+	// MaxClicks is the number of redirects the tiny url allows. Zero means unlimited.
+	MaxClicks int64 `bson:"max_clicks,omitempty"`
+	// ActiveFrom and ActiveUntil bound the window in which the tiny url redirects. Zero times leave it open.
+	ActiveFrom  time.Time `bson:"active_from,omitempty"`
+	ActiveUntil time.Time `bson:"active_until,omitempty"`
+	// Interstitial shows the destination and waits for the visitor to continue instead of redirecting
+	Interstitial bool `bson:"interstitial,omitempty"`
+	// Passthrough passes the path following the key and the query of visits on to the long url, see Destination
+	Passthrough bool `bson:"passthrough,omitempty"`
+	// FallbackURL receives the visits once the tiny url expired, was suspended or reached its click limit
+	FallbackURL string `bson:"fallback_url,omitempty"`
+	// Rules are evaluated in order for every visit, the long url is the destination when none matches
+	Rules []TargetingRule `bson:"rules,omitempty"`
+	// Variants split the visits that no rule matches by weight. The long url is not visited while there are variants.
+	Variants []Variant `bson:"variants,omitempty"`
+	// Domain is the host of the long url, stored so that tiny urls can be listed by destination
+	Domain string `bson:"domain,omitempty"`
+	// Owner is the id of the API key that generated the tiny url. Tiny urls without an owner can only be changed by
+	// admins.
+	Owner    string `bson:"owner,omitempty"`
+	Metadata `bson:",inline"`
+	// Clicks is only accurate when read from the db, it is not kept up to date in the cache
+	Clicks int64 `bson:"clicks" json:"-"`
 }
 
-// ToURL returns the tiny url for a given URLDocument
-func (u URLDocument) ToURL(ctx echo.Context) string {
+// ToURL returns the tiny url for a given URLDocument. Tiny urls of tenants are served from the root of tenantDomain,
+// the configured domain of their tenant, and the others from the host the request came in on.
+func (u URLDocument) ToURL(ctx echo.Context, tenantDomain string) string {
 	scheme := "http"
 	if ctx.Request().TLS != nil {
 		scheme = "https"
 	}
+	if u.Tenant != "" {
+		return fmt.Sprintf(tenantURLFormat, scheme, tenantDomain, u.URLKey)
+	}
 	return fmt.Sprintf(urlFormat, scheme, ctx.Request().Host, u.URLKey)
 }
 
+// URLDomain returns the lower cased host of the long url without its port, or an empty string when it has none
+func URLDomain(longURL string) string {
+	parsed, err := url.Parse(strings.TrimSpace(longURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
 // CacheService represents domain service abstraction for caching
 type CacheService interface {
-	Cache(ctx context.Context, key string, val any) error
+	Cache(ctx context.Context, key string, val any, ttl time.Duration) error
+	CacheIfAbsent(ctx context.Context, key string, val any, ttl time.Duration) error
+	// CacheMany stores all the entries in one round trip
+	CacheMany(ctx context.Context, entries []CacheEntry) error
+	// Increment adds one to the counter of the key and returns the new value. A new counter expires after ttl.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// SetCounter stores a counter that expires after ttl. A ttl of zero keeps the counter until it is deleted.
+	SetCounter(ctx context.Context, key string, val int64, ttl time.Duration) error
+	// DecrementIfPresent subtracts one from the counter of the key and returns the new value. ok is false when there
+	// is no counter for the key, which is left unset.
+	DecrementIfPresent(ctx context.Context, key string) (val int64, ok bool, err error)
+	// IncrementFields adds the counts to the counters of the fields of the hash of the key
+	IncrementFields(ctx context.Context, key string, counts map[string]int64) error
+	// GetFieldCounter returns the counter of the field of the hash of the key, zero when there is none
+	GetFieldCounter(ctx context.Context, key, field string) (int64, error)
+	// TakeFieldCounters deletes the hash of the key and returns the counters of its fields
+	TakeFieldCounters(ctx context.Context, key string) (map[string]int64, error)
+	// TakeFieldCounter deletes the field of the hash of the key and returns its counter, zero when there is none
+	TakeFieldCounter(ctx context.Context, key, field string) (int64, error)
 	Delete(ctx context.Context, key string) error
 	GetCachedValue(ctx context.Context, key string) (string, error)
 }
+
+// CacheEntry is a key:value pair stored in the cache until ttl passes
+type CacheEntry struct {
+	Key   string
+	Value any
+	TTL   time.Duration
+}
@@ -1,18 +1,48 @@
 package types
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type (
 	// MockRepo mocks the db
 	MockRepo struct {
 		Data map[string]URLDocument
+		mu   sync.RWMutex
 	}
-	// MockCache mocks the cache
+	// MockCache mocks the cache. Fields holds the counters of the fields of hashes.
 	MockCache struct {
-		Data map[string]string
+		Data   map[string]string
+		Fields map[string]map[string]int64
+		mu     sync.RWMutex
+	}
+	// MockSequenceRepo mocks the sequence store
+	MockSequenceRepo struct {
+		Data map[string]int64
+		mu   sync.Mutex
+	}
+	// MockKeyRepo mocks the api key store
+	MockKeyRepo struct {
+		Data map[string]APIKey
+		mu   sync.RWMutex
+	}
+	// MockClickRepo mocks the click event store. Writes fail while Fail is set.
+	MockClickRepo struct {
+		Events []ClickEvent
+		Fail   bool
+		mu     sync.Mutex
+	}
+	// MockClickRecorder keeps the click events it records
+	MockClickRecorder struct {
+		Events []ClickEvent
+		mu     sync.Mutex
 	}
 )
 
@@ -25,6 +55,8 @@ const (
 )
 
 func (mr *MockRepo) Put(_ context.Context, document any) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
 	switch o := document.(type) {
 	case URLDocument:
 		switch {
@@ -35,37 +67,497 @@ func (mr *MockRepo) Put(_ context.Context, document any) error {
 		case o.LongURL == Empty:
 			return errorCondition(Empty)
 		}
-		mr.Data[o.URLKey] = o
+		if _, ok := mr.Data[MockKey(o.Tenant, o.URLKey)]; ok {
+			return ErrDuplicateKey
+		}
+		if _, ok := mr.byDedupeHash(o.DedupeHash); ok {
+			return ErrDuplicateURL
+		}
+		mr.Data[MockKey(o.Tenant, o.URLKey)] = o
 	}
 	return nil
 }
 
-func (mr *MockRepo) GetDocument(_ context.Context, urlKey string) (URLDocument, error) {
-	doc, ok := mr.Data[urlKey]
+func (mr *MockRepo) PutMany(ctx context.Context, documents []URLDocument) ([]error, error) {
+	errs := make([]error, len(documents))
+	for i, d := range documents {
+		errs[i] = mr.Put(ctx, d)
+	}
+	return errs, nil
+}
+
+func (mr *MockRepo) GetDocument(_ context.Context, tenant, urlKey string) (URLDocument, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
 	if !ok {
 		return URLDocument{}, ErrDocumentNotFound
 	}
 	return doc, nil
 }
 
-func (mr *MockRepo) Delete(_ context.Context, urlKey string) error {
-	doc, ok := mr.Data[urlKey]
+func (mr *MockRepo) GetDocumentByDedupeHash(_ context.Context, dedupeHash string) (URLDocument, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	doc, ok := mr.byDedupeHash(dedupeHash)
 	if !ok {
+		return URLDocument{}, ErrDocumentNotFound
+	}
+	return doc, nil
+}
+
+func (mr *MockRepo) ClearDedupeHash(_ context.Context, tenant, urlKey, dedupeHash string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
+	if ok && doc.DedupeHash == dedupeHash {
+		doc.DedupeHash = ""
+		mr.Data[MockKey(tenant, urlKey)] = doc
+	}
+	return nil
+}
+
+// byDedupeHash must be called with the lock held
+func (mr *MockRepo) byDedupeHash(dedupeHash string) (URLDocument, bool) {
+	if dedupeHash == "" {
+		return URLDocument{}, false
+	}
+	for _, doc := range mr.Data {
+		if doc.DedupeHash == dedupeHash {
+			return doc, true
+		}
+	}
+	return URLDocument{}, false
+}
+
+func (mr *MockRepo) Delete(_ context.Context, tenant, urlKey string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if _, ok := mr.Data[MockKey(tenant, urlKey)]; !ok {
+		return ErrDocumentNotFound
+	}
+	delete(mr.Data, MockKey(tenant, urlKey))
+	return nil
+}
+
+func (mr *MockRepo) Trash(_ context.Context, tenant, urlKey string, deletedAt time.Time) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
+	if !ok || !doc.DeletedAt.IsZero() {
+		return ErrDocumentNotFound
+	}
+	doc.DeletedAt = deletedAt
+	doc.DedupeHash = ""
+	mr.Data[MockKey(tenant, urlKey)] = doc
+	return nil
+}
+
+func (mr *MockRepo) Restore(_ context.Context, tenant, urlKey string) (URLDocument, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
+	if !ok || doc.DeletedAt.IsZero() {
+		return URLDocument{}, ErrDocumentNotFound
+	}
+	doc.DeletedAt = time.Time{}
+	mr.Data[MockKey(tenant, urlKey)] = doc
+	return doc, nil
+}
+
+func (mr *MockRepo) PurgeTrash(_ context.Context, before time.Time) (int64, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	var purged int64
+	for key, doc := range mr.Data {
+		if !doc.DeletedAt.IsZero() && !doc.DeletedAt.After(before) {
+			delete(mr.Data, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (mr *MockRepo) SetSuspension(_ context.Context, tenant, urlKey string,
+	suspension *Suspension) (URLDocument, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
+	if !ok {
+		return URLDocument{}, ErrDocumentNotFound
+	}
+	doc.Suspension = suspension
+	mr.Data[MockKey(tenant, urlKey)] = doc
+	return doc, nil
+}
+
+func (mr *MockRepo) Update(_ context.Context, tenant, urlKey string, update URLUpdate) (URLDocument, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
+	if !ok {
+		return URLDocument{}, ErrDocumentNotFound
+	}
+	if update.LongURL != nil {
+		doc.LongURL = *update.LongURL
+		doc.Domain = URLDomain(*update.LongURL)
+		doc.DedupeHash = ""
+	}
+	if update.ExpireAt != nil {
+		doc.ExpireTime = *update.ExpireAt
+	}
+	if update.LiveForever != nil {
+		doc.LiveForever = *update.LiveForever
+	}
+	if update.ActiveFrom != nil {
+		doc.ActiveFrom = *update.ActiveFrom
+	}
+	if update.ActiveUntil != nil {
+		doc.ActiveUntil = *update.ActiveUntil
+	}
+	if update.Interstitial != nil {
+		doc.Interstitial = *update.Interstitial
+	}
+	if update.Passthrough != nil {
+		doc.Passthrough = *update.Passthrough
+	}
+	if update.Rules != nil {
+		doc.Rules = *update.Rules
+	}
+	if update.Variants != nil {
+		doc.Variants = *update.Variants
+	}
+	if update.Title != nil {
+		doc.Title = *update.Title
+	}
+	if update.Notes != nil {
+		doc.Notes = *update.Notes
+	}
+	if update.Tags != nil {
+		doc.Tags = *update.Tags
+	}
+	if update.CreatedBy != nil {
+		doc.CreatedBy = *update.CreatedBy
+	}
+	if update.ExternalID != nil {
+		doc.ExternalID = *update.ExternalID
+	}
+	if update.FallbackURL != nil {
+		doc.FallbackURL = *update.FallbackURL
+	}
+	doc.UpdatedAt = time.Now()
+	mr.Data[MockKey(tenant, urlKey)] = doc
+	return doc, nil
+}
+
+func (mr *MockRepo) AddClicks(_ context.Context, counts []ClickCount) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for _, c := range counts {
+		doc, ok := mr.Data[MockKey(c.Tenant, c.URLKey)]
+		if !ok {
+			continue
+		}
+		if c.Variant == nil {
+			doc.Clicks += c.Clicks
+		} else if *c.Variant < len(doc.Variants) {
+			// the variants are shared with the copies handed out, so they are copied before counting
+			doc.Variants = append([]Variant(nil), doc.Variants...)
+			doc.Variants[*c.Variant].Clicks += c.Clicks
+		}
+		mr.Data[MockKey(c.Tenant, c.URLKey)] = doc
+	}
+	return nil
+}
+
+func (mr *MockRepo) ConsumeClick(_ context.Context, tenant, urlKey string) (int64, bool, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	doc, ok := mr.Data[MockKey(tenant, urlKey)]
+	if !ok || doc.Clicks >= doc.MaxClicks {
+		return 0, false, nil
+	}
+	doc.Clicks++
+	mr.Data[MockKey(tenant, urlKey)] = doc
+	return doc.MaxClicks - doc.Clicks, true, nil
+}
+
+func (mr *MockRepo) List(_ context.Context, query ListQuery) ([]URLDocument, error) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	now := time.Now()
+	docs := make([]URLDocument, 0, len(mr.Data))
+	for _, doc := range mr.Data {
+		if doc.Tenant == query.Tenant && matchesFilter(query.Filter, doc, now) {
+			docs = append(docs, doc)
+		}
+	}
+	slices.SortFunc(docs, func(a, b URLDocument) int {
+		return compareListed(query, a, b)
+	})
+	if query.After != nil {
+		after := URLDocument{URLKey: query.After.URLKey, CreatedAt: query.After.CreatedAt, Clicks: query.After.Clicks}
+		for len(docs) > 0 && compareListed(query, docs[0], after) <= 0 {
+			docs = docs[1:]
+		}
+	}
+	if len(docs) > query.Limit {
+		docs = docs[:query.Limit]
+	}
+	return docs, nil
+}
+
+// MockKey is the key of the document of the url key of a tenant in MockRepo.Data. Documents of the default tenant are
+// stored under their url key.
+func MockKey(tenant, urlKey string) string {
+	if tenant == "" {
+		return urlKey
+	}
+	return tenant + ":" + urlKey
+}
+
+func matchesFilter(f URLFilter, doc URLDocument, now time.Time) bool {
+	switch {
+	case f.Owner != "" && doc.Owner != f.Owner,
+		f.Domain != "" && doc.Domain != f.Domain,
+		!f.CreatedAfter.IsZero() && doc.CreatedAt.Before(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !doc.CreatedAt.Before(f.CreatedBefore),
+		!f.ExpiresAfter.IsZero() && doc.ExpireTime.Before(f.ExpiresAfter),
+		!f.ExpiresBefore.IsZero() && !doc.ExpireTime.Before(f.ExpiresBefore),
+		f.State == StateActive && !doc.ExpireTime.After(now),
+		f.State == StateExpired && doc.ExpireTime.After(now),
+		(f.State == StateTrashed) == doc.DeletedAt.IsZero(),
+		f.CreatedBy != "" && doc.CreatedBy != f.CreatedBy,
+		f.ExternalID != "" && doc.ExternalID != f.ExternalID,
+		f.Search != "" && !matchesSearch(f.Search, doc):
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(doc.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSearch reports whether any word of the search is a word of the title, notes or tags of the document, like a
+// text search does without stemming
+func matchesSearch(search string, doc URLDocument) bool {
+	words := strings.Fields(strings.ToLower(doc.Title + " " + doc.Notes + " " + strings.Join(doc.Tags, " ")))
+	for _, w := range strings.Fields(strings.ToLower(search)) {
+		if slices.Contains(words, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareListed(query ListQuery, a, b URLDocument) int {
+	c := a.CreatedAt.Compare(b.CreatedAt)
+	if query.Sort == SortClicks {
+		c = cmp.Compare(a.Clicks, b.Clicks)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.URLKey, b.URLKey)
+	}
+	if !query.Ascending {
+		c = -c
+	}
+	return c
+}
+
+func (ms *MockSequenceRepo) NextRange(_ context.Context, name string, size int64) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.Data[name] += size
+	return ms.Data[name], nil
+}
+
+func (mk *MockKeyRepo) Put(_ context.Context, key APIKey) error {
+	mk.mu.Lock()
+	defer mk.mu.Unlock()
+	if _, ok := mk.Data[key.ID]; ok {
+		return ErrDuplicateKey
+	}
+	mk.Data[key.ID] = key
+	return nil
+}
+
+func (mk *MockKeyRepo) GetByHash(_ context.Context, hash string) (APIKey, error) {
+	mk.mu.RLock()
+	defer mk.mu.RUnlock()
+	for _, key := range mk.Data {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return APIKey{}, ErrDocumentNotFound
+}
+
+func (mk *MockKeyRepo) List(_ context.Context) ([]APIKey, error) {
+	mk.mu.RLock()
+	defer mk.mu.RUnlock()
+	keys := make([]APIKey, 0, len(mk.Data))
+	for _, key := range mk.Data {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b APIKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return keys, nil
+}
+
+func (mk *MockKeyRepo) Rotate(_ context.Context, keyID, hash string, rotatedAt time.Time) (APIKey, error) {
+	mk.mu.Lock()
+	defer mk.mu.Unlock()
+	key, ok := mk.Data[keyID]
+	if !ok || !key.RevokedAt.IsZero() {
+		return APIKey{}, ErrDocumentNotFound
+	}
+	key.Hash = hash
+	key.RotatedAt = rotatedAt
+	mk.Data[keyID] = key
+	return key, nil
+}
+
+func (mk *MockKeyRepo) Revoke(_ context.Context, keyID string, revokedAt time.Time) error {
+	mk.mu.Lock()
+	defer mk.mu.Unlock()
+	key, ok := mk.Data[keyID]
+	if !ok || !key.RevokedAt.IsZero() {
 		return ErrDocumentNotFound
 	}
-	delete(mr.Data, doc.URLKey)
+	key.RevokedAt = revokedAt
+	mk.Data[keyID] = key
 	return nil
 }
 
-func (mc *MockCache) Cache(_ context.Context, key string, val any) error {
+func (mr *MockClickRepo) PutMany(_ context.Context, events []ClickEvent) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if mr.Fail {
+		return errors.New(StoreFail)
+	}
+	mr.Events = append(mr.Events, events...)
+	return nil
+}
+
+// Len returns the number of stored events
+func (mr *MockClickRepo) Len() int {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	return len(mr.Events)
+}
+
+func (mr *MockClickRecorder) Record(event ClickEvent) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.Events = append(mr.Events, event)
+}
+
+func (mc *MockCache) Cache(_ context.Context, key string, val any, _ time.Duration) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 	switch o := val.(type) {
 	case string:
 		mc.Data[key] = o
+	case []byte:
+		mc.Data[key] = string(o)
+	}
+	return nil
+}
+
+func (mc *MockCache) CacheIfAbsent(ctx context.Context, key string, val any, ttl time.Duration) error {
+	mc.mu.RLock()
+	_, ok := mc.Data[key]
+	mc.mu.RUnlock()
+	if ok {
+		return nil
+	}
+	return mc.Cache(ctx, key, val, ttl)
+}
+
+func (mc *MockCache) CacheMany(ctx context.Context, entries []CacheEntry) error {
+	for _, e := range entries {
+		if err := mc.Cache(ctx, e.Key, e.Value, e.TTL); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func (mc *MockCache) Increment(_ context.Context, key string, _ time.Duration) (int64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	n, _ := strconv.ParseInt(mc.Data[key], 10, 64)
+	n++
+	mc.Data[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (mc *MockCache) SetCounter(_ context.Context, key string, val int64, _ time.Duration) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.Data[key] = strconv.FormatInt(val, 10)
+	return nil
+}
+
+func (mc *MockCache) DecrementIfPresent(_ context.Context, key string) (int64, bool, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	val, ok := mc.Data[key]
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	n--
+	mc.Data[key] = strconv.FormatInt(n, 10)
+	return n, true, nil
+}
+
+func (mc *MockCache) IncrementFields(_ context.Context, key string, counts map[string]int64) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.Fields == nil {
+		mc.Fields = make(map[string]map[string]int64)
+	}
+	if mc.Fields[key] == nil {
+		mc.Fields[key] = make(map[string]int64)
+	}
+	for field, n := range counts {
+		mc.Fields[key][field] += n
+	}
+	return nil
+}
+
+func (mc *MockCache) GetFieldCounter(_ context.Context, key, field string) (int64, error) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.Fields[key][field], nil
+}
+
+func (mc *MockCache) TakeFieldCounters(_ context.Context, key string) (map[string]int64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	counts := mc.Fields[key]
+	delete(mc.Fields, key)
+	return counts, nil
+}
+
+func (mc *MockCache) TakeFieldCounter(_ context.Context, key, field string) (int64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	n := mc.Fields[key][field]
+	delete(mc.Fields[key], field)
+	return n, nil
+}
+
 func (mc *MockCache) Delete(_ context.Context, key string) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 	switch key {
 	case CacheStoreFail:
 		return errorCondition(StoreFail)
@@ -84,6 +576,8 @@ func (mc *MockCache) Delete(_ context.Context, key string) error {
 }
 
 func (mc *MockCache) GetCachedValue(_ context.Context, key string) (string, error) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
 	switch key {
 	case CacheGetFail:
 		return "", errorCondition(StoreFail)